@@ -0,0 +1,342 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mindmorass/yippity-clippity/internal/app"
+	"github.com/mindmorass/yippity-clippity/internal/audit"
+	"github.com/mindmorass/yippity-clippity/internal/backend"
+	"github.com/mindmorass/yippity-clippity/internal/identity"
+	"github.com/mindmorass/yippity-clippity/internal/ui"
+	qrcode "github.com/skip2/go-qrcode"
+	"golang.org/x/term"
+)
+
+// isCommand returns true if the arguments name a CLI subcommand rather than
+// launching the menubar app. macOS may pass a -psn_* argument when the app
+// is started from Finder, which must be ignored.
+func isCommand(args []string) bool {
+	return len(args) > 0 && !strings.HasPrefix(args[0], "-psn")
+}
+
+// runCommand executes a CLI subcommand and returns the process exit code
+func runCommand(args []string) int {
+	switch args[0] {
+	case "login":
+		return runLogin(args[1:])
+	case "key":
+		return runKey()
+	case "trust":
+		return runTrust(args[1:])
+	case "pair":
+		return runPair(args[1:])
+	case "audit":
+		return runAudit(args[1:])
+	case "help", "-h", "--help":
+		printUsage()
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n\n", args[0])
+		printUsage()
+		return 2
+	}
+}
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: yippity-clippity [command]")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "Without a command, the menubar app is started.")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "Commands:")
+	fmt.Fprintln(os.Stderr, "  login dropbox    Authorize access to Dropbox in the browser")
+	fmt.Fprintln(os.Stderr, "  login s3         Save static AWS keys in the secret store")
+	fmt.Fprintln(os.Stderr, "  pair             Show a one-time code for pairing a new device")
+	fmt.Fprintln(os.Stderr, "  pair <code>      Pair this device using a code shown on another device")
+	fmt.Fprintln(os.Stderr, "  key              Show this device's public signing key")
+	fmt.Fprintln(os.Stderr, "  trust list       List trusted devices")
+	fmt.Fprintln(os.Stderr, "  trust add <name> <public-key>")
+	fmt.Fprintln(os.Stderr, "                   Accept clips signed by another device")
+	fmt.Fprintln(os.Stderr, "  trust remove <name|public-key>")
+	fmt.Fprintln(os.Stderr, "                   Stop accepting clips from a device")
+	fmt.Fprintln(os.Stderr, "  audit [options]  Show or export clips sent, received and blocked")
+	fmt.Fprintln(os.Stderr, "                   (run \"audit -h\" for options)")
+}
+
+// runLogin handles "login <backend>"
+func runLogin(args []string) int {
+	if len(args) == 1 {
+		switch args[0] {
+		case "dropbox":
+			return runLoginDropbox()
+		case "s3":
+			return runLoginS3()
+		}
+	}
+
+	fmt.Fprintln(os.Stderr, "usage: yippity-clippity login dropbox|s3")
+	return 2
+}
+
+func runLoginDropbox() int {
+	open := func(url string) {
+		fmt.Printf("Opening your browser to authorize Yippity-Clippity.\nIf it does not open, visit:\n\n  %s\n\n", url)
+		ui.OpenBrowser(url)
+	}
+
+	if err := app.LoginDropbox(context.Background(), open); err != nil {
+		fmt.Fprintf(os.Stderr, "Dropbox login failed: %v\n", err)
+		return 1
+	}
+
+	fmt.Println("Dropbox login successful")
+	return 0
+}
+
+func runLoginS3() int {
+	in := bufio.NewReader(os.Stdin)
+	prompt := func(label string) string {
+		fmt.Print(label)
+		line, _ := in.ReadString('\n')
+		return strings.TrimSpace(line)
+	}
+
+	// Secrets typed at a terminal aren't echoed
+	promptSecret := func(label string) string {
+		fd := int(os.Stdin.Fd())
+		if !term.IsTerminal(fd) {
+			return prompt(label)
+		}
+		fmt.Print(label)
+		secret, _ := term.ReadPassword(fd)
+		fmt.Println()
+		return strings.TrimSpace(string(secret))
+	}
+
+	creds := backend.S3Credentials{
+		AccessKeyID:     prompt("AWS access key ID: "),
+		SecretAccessKey: promptSecret("AWS secret access key: "),
+		SessionToken:    promptSecret("AWS session token (optional): "),
+	}
+
+	if err := app.SaveS3Credentials(creds); err != nil {
+		fmt.Fprintf(os.Stderr, "Saving S3 credentials failed: %v\n", err)
+		return 1
+	}
+
+	fmt.Println("S3 credentials saved")
+	return 0
+}
+
+func runKey() int {
+	id, err := app.DeviceIdentity()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Loading device key failed: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Device:      %s\n", id.Name)
+	fmt.Printf("Public key:  %s\n", id.KeyID())
+	fmt.Printf("Fingerprint: %s\n", id.Fingerprint())
+	fmt.Println("")
+	fmt.Println("On each other device, run:")
+	fmt.Printf("  yippity-clippity trust add %s %s\n", id.Name, id.KeyID())
+	return 0
+}
+
+// runTrust handles "trust list|add|remove"
+func runTrust(args []string) int {
+	trust, err := app.OpenTrustStore()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Loading trusted devices failed: %v\n", err)
+		return 1
+	}
+
+	switch {
+	case len(args) == 1 && args[0] == "list":
+		peers := trust.Peers()
+		if len(peers) == 0 {
+			fmt.Println("No trusted devices")
+			return 0
+		}
+		for _, p := range peers {
+			fmt.Printf("%-24s %s  added %s\n", p.Name, identity.Fingerprint(p.PublicKey), p.AddedAt.Format("2006-01-02"))
+		}
+		return 0
+
+	case len(args) == 3 && args[0] == "add":
+		if err := trust.Add(args[1], args[2]); err != nil {
+			fmt.Fprintf(os.Stderr, "Adding trusted device failed: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Trusting %s (%s)\n", args[1], identity.Fingerprint(strings.TrimSpace(args[2])))
+		return 0
+
+	case len(args) == 2 && args[0] == "remove":
+		removed, err := trust.Remove(args[1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Removing trusted device failed: %v\n", err)
+			return 1
+		}
+		if removed == 0 {
+			fmt.Fprintf(os.Stderr, "No trusted device matches %s\n", args[1])
+			return 1
+		}
+		fmt.Printf("Removed %d trusted device(s)\n", removed)
+		return 0
+	}
+
+	fmt.Fprintln(os.Stderr, "usage: yippity-clippity trust list|add <name> <public-key>|remove <name|public-key>")
+	return 2
+}
+
+// runPair handles "pair" and "pair <code>"
+func runPair(args []string) int {
+	ctx := context.Background()
+
+	switch len(args) {
+	case 0:
+		peer, err := app.HostPairing(ctx, func(code, uri string) {
+			fmt.Printf("On the new device, run:\n\n  yippity-clippity pair %s\n\n", code)
+			if qr, err := qrcode.New(uri, qrcode.Medium); err == nil {
+				fmt.Println(qr.ToSmallString(false))
+			}
+			fmt.Println("Waiting for the new device...")
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Pairing failed: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Paired with %s (%s)\n", peer.Name, identity.Fingerprint(peer.PublicKey))
+		return 0
+
+	case 1:
+		peer, err := app.JoinPairing(ctx, args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Pairing failed: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Paired with %s (%s)\n", peer.Name, identity.Fingerprint(peer.PublicKey))
+		return 0
+	}
+
+	fmt.Fprintln(os.Stderr, "usage: yippity-clippity pair [code]")
+	return 2
+}
+
+// runAudit handles "audit", which queries and exports the audit log
+func runAudit(args []string) int {
+	flags := flag.NewFlagSet("audit", flag.ContinueOnError)
+	since := flags.String("since", "", "only records after this time: a duration such as 24h, or a date such as 2025-01-31")
+	until := flags.String("until", "", "only records before this time, in the same forms as -since")
+	direction := flags.String("direction", "", "outgoing or incoming")
+	decision := flags.String("decision", "", "sent, received or blocked")
+	peer := flags.String("peer", "", "only clips from this device")
+	format := flags.String("format", "table", "table, csv or json")
+	output := flags.String("output", "", "write to this file instead of stdout")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintln(os.Stderr, "usage: yippity-clippity audit [options]")
+		return 2
+	}
+
+	filter := audit.Filter{
+		Direction: *direction,
+		Decision:  *decision,
+		Peer:      *peer,
+	}
+	var err error
+	if filter.Since, err = parseAuditTime(*since); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid -since: %v\n", err)
+		return 2
+	}
+	if filter.Until, err = parseAuditTime(*until); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid -until: %v\n", err)
+		return 2
+	}
+
+	var write func(io.Writer, []audit.Record) error
+	switch *format {
+	case "table":
+		write = writeAuditTable
+	case "csv":
+		write = audit.WriteCSV
+	case "json":
+		write = audit.WriteJSON
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown format %q, use table, csv or json\n", *format)
+		return 2
+	}
+
+	records, err := audit.Read(app.AuditLogPath(), filter)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Reading audit log failed: %v\n", err)
+		return 1
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.OpenFile(*output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Creating %s failed: %v\n", *output, err)
+			return 1
+		}
+		defer file.Close()
+		w = file
+	}
+
+	if err := write(w, records); err != nil {
+		fmt.Fprintf(os.Stderr, "Writing audit records failed: %v\n", err)
+		return 1
+	}
+	if *output != "" {
+		fmt.Printf("Exported %d audit record(s) to %s\n", len(records), *output)
+	}
+	return 0
+}
+
+// parseAuditTime parses a time as a duration before now or a date
+func parseAuditTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is neither a duration nor a date", s)
+}
+
+// writeAuditTable prints records as an aligned table in local time
+func writeAuditTable(w io.Writer, records []audit.Record) error {
+	if len(records) == 0 {
+		_, err := fmt.Fprintln(w, "No audit records")
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tDIRECTION\tDECISION\tTYPE\tSIZE\tPEER\tCLIP\tRULE")
+	for _, r := range records {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\n",
+			r.Time.Local().Format("2006-01-02 15:04:05"), r.Direction, r.Decision,
+			r.ContentType, r.Size, r.PeerDevice, r.ClipID, r.Rule)
+	}
+	return tw.Flush()
+}
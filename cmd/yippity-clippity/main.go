@@ -1,7 +1,7 @@
 package main
 
 import (
-	"log"
+	"log/slog"
 	"os"
 
 	"github.com/mindmorass/yippity-clippity/internal/app"
@@ -11,18 +11,20 @@ import (
 var Version = "dev"
 
 func main() {
-	// Set up logging
-	log.SetFlags(log.Ldate | log.Ltime | log.Lshortfile)
+	// Run a CLI subcommand instead of the menubar app if one was given
+	if isCommand(os.Args[1:]) {
+		os.Exit(runCommand(os.Args[1:]))
+	}
 
 	// Create and run application
 	application, err := app.New(Version)
 	if err != nil {
-		log.Fatalf("Failed to create application: %v", err)
+		slog.Error("Failed to create application", "error", err)
 		os.Exit(1)
 	}
 
 	if err := application.Run(); err != nil {
-		log.Fatalf("Application error: %v", err)
+		slog.Error("Application error", "error", err)
 		os.Exit(1)
 	}
 }
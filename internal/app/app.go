@@ -2,9 +2,28 @@ package app
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/mindmorass/yippity-clippity/internal/audit"
 	"github.com/mindmorass/yippity-clippity/internal/backend"
+	"github.com/mindmorass/yippity-clippity/internal/clipboard"
+	"github.com/mindmorass/yippity-clippity/internal/identity"
+	"github.com/mindmorass/yippity-clippity/internal/imaging"
+	"github.com/mindmorass/yippity-clippity/internal/logging"
+	"github.com/mindmorass/yippity-clippity/internal/metrics"
+	"github.com/mindmorass/yippity-clippity/internal/notify"
+	"github.com/mindmorass/yippity-clippity/internal/pairing"
+	"github.com/mindmorass/yippity-clippity/internal/secrets"
+	"github.com/mindmorass/yippity-clippity/internal/sensitive"
+	"github.com/mindmorass/yippity-clippity/internal/storage"
 	"github.com/mindmorass/yippity-clippity/internal/sync"
 	"github.com/mindmorass/yippity-clippity/internal/ui"
 	"github.com/mindmorass/yippity-clippity/internal/update"
@@ -13,9 +32,17 @@ import (
 // App is the main application
 type App struct {
 	config        *Config
+	secrets       secrets.Store
+	backend       backend.Backend
+	identity      *identity.Identity
+	trust         *identity.TrustStore
 	syncEngine    *sync.Engine
 	menubar       *ui.Menubar
 	updateChecker *update.Checker
+	metricsServer *http.Server
+	logFile       io.Closer
+	auditLog      *audit.Log
+	notifier      *notify.Service
 	version       string
 	quitChan      chan struct{}
 }
@@ -25,49 +52,93 @@ func New(version string) (*App, error) {
 	// Load configuration
 	config, err := LoadConfig()
 	if err != nil {
-		log.Printf("Warning: failed to load config: %v", err)
+		slog.Warn("Failed to load config", "error", err)
 		config = DefaultConfig()
 	}
 
-	// Create backend based on configuration
-	backendCfg := &backend.Config{
-		Type:             backend.BackendType(config.BackendType),
-		Location:         config.SharedLocation,
-		S3Bucket:         config.S3Bucket,
-		S3Prefix:         config.S3Prefix,
-		S3Region:         config.S3Region,
-		DropboxAppKey:    config.DropboxAppKey,
-		DropboxAppSecret: config.DropboxAppSecret,
+	// Log to a rotated file as well as stderr
+	logFile, err := setupLogging(config)
+	if err != nil {
+		slog.Warn("Failed to set up log file", "error", err)
 	}
 
-	// Default to local backend if not specified
-	if backendCfg.Type == "" {
-		backendCfg.Type = backend.BackendLocal
+	// Open the secret store for backend credentials
+	store, err := openSecretStore(config)
+	if err != nil {
+		slog.Warn("Failed to open secret store", "error", err)
 	}
 
-	b, err := backend.New(backendCfg)
+	// Create backend based on configuration
+	b, err := backend.New(backendConfig(config, store))
 	if err != nil {
-		log.Printf("Warning: failed to create backend: %v, falling back to local", err)
+		slog.Warn("Failed to create backend, falling back to local", "error", err)
 		b = backend.NewDefault()
 	}
 
 	// Initialize backend
 	ctx := context.Background()
 	if err := b.Init(ctx); err != nil {
-		log.Printf("Warning: failed to initialize backend: %v", err)
+		slog.Warn("Failed to initialize backend", "error", err)
 		// For local backend, this might just mean the directory doesn't exist yet
 	}
 
 	// Create sync engine with the backend
 	engine := sync.NewEngineWithBackend(b)
 
+	// Sign outgoing clips and only accept clips from trusted devices
+	id, trust := loadIdentity(store)
+	if id != nil {
+		engine.SetIdentity(id)
+	}
+	if config.RequireSignatures {
+		engine.SetTrustStore(trust)
+	}
+
+	// Check outgoing clips for secrets
+	engine.SetSensitivePipeline(sensitivePipeline(config))
+	engine.SetConfirmHandler(confirmSensitive)
+	engine.SetAppFilter(sync.NewAppFilter(config.SyncAllowApps, config.SyncDenyApps))
+
+	// Let shared clips expire
+	engine.SetClipTTL(clipTTL(config))
+
+	// Limit what is synced by content type and size
+	engine.SetPolicy(syncPolicy(config))
+	engine.SetImageOptions(imageOptions(config))
+	engine.SetAcceptLossyImages(config.AcceptLossyImages)
+
+	// Decide conflicts between local and remote clips
+	engine.SetConflictResolver(conflictResolver(config))
+
+	// Tell the user about received clips and sync failures
+	notifier := notify.NewService(notify.Default(), notificationEvents(config))
+	notifier.Attach(engine)
+
+	// Record what leaves and reaches this device
+	var auditLog *audit.Log
+	if config.AuditLog {
+		auditLog, err = audit.Open(AuditLogPath())
+		if err != nil {
+			slog.Warn("Failed to open audit log", "error", err)
+		} else {
+			audit.Attach(engine, auditLog)
+		}
+	}
+
 	// Create update checker
 	checker := update.NewChecker(version)
 
 	app := &App{
 		config:        config,
+		secrets:       store,
+		backend:       b,
+		identity:      id,
+		trust:         trust,
 		syncEngine:    engine,
 		updateChecker: checker,
+		logFile:       logFile,
+		auditLog:      auditLog,
+		notifier:      notifier,
 		version:       version,
 		quitChan:      make(chan struct{}),
 	}
@@ -80,9 +151,20 @@ func New(version string) (*App, error) {
 
 // Run starts the application
 func (a *App) Run() error {
+	// Serve metrics if enabled
+	if a.config.MetricsAddr != "" {
+		server, err := metrics.Serve(a.config.MetricsAddr)
+		if err != nil {
+			slog.Warn("Failed to serve metrics", "error", err)
+		} else {
+			a.metricsServer = server
+			slog.Info("Serving metrics", "url", "http://"+a.config.MetricsAddr+metrics.Path)
+		}
+	}
+
 	// Start sync engine
 	if err := a.syncEngine.Start(); err != nil {
-		log.Printf("Warning: failed to start sync engine: %v", err)
+		slog.Warn("Failed to start sync engine", "error", err)
 	}
 
 	// Run menubar (blocking)
@@ -103,9 +185,14 @@ func (a *App) SetSharedLocation(path string) error {
 	}
 
 	// Update config
-	a.config.SharedLocation = path
+	switch a.backend.Type() {
+	case backend.BackendDropbox:
+		a.config.DropboxPath = path
+	default:
+		a.config.SharedLocation = path
+	}
 	if err := SaveConfig(a.config); err != nil {
-		log.Printf("Warning: failed to save config: %v", err)
+		slog.Warn("Failed to save config", "error", err)
 	}
 
 	return nil
@@ -119,8 +206,17 @@ func (a *App) GetSharedLocation() string {
 // Quit stops the application
 func (a *App) Quit() {
 	a.syncEngine.Stop()
+	if a.metricsServer != nil {
+		a.metricsServer.Close()
+	}
 	a.menubar.Quit()
 	close(a.quitChan)
+	if a.auditLog != nil {
+		a.auditLog.Close()
+	}
+	if a.logFile != nil {
+		a.logFile.Close()
+	}
 }
 
 // GetVersion returns the application version
@@ -128,6 +224,16 @@ func (a *App) GetVersion() string {
 	return a.version
 }
 
+// GetLogDir returns the directory holding the log files
+func (a *App) GetLogDir() string {
+	return logDir()
+}
+
+// GetNotifier returns the notification service
+func (a *App) GetNotifier() *notify.Service {
+	return a.notifier
+}
+
 // GetUpdateChecker returns the update checker
 func (a *App) GetUpdateChecker() *update.Checker {
 	return a.updateChecker
@@ -142,8 +248,574 @@ func (a *App) GetBackendType() string {
 func (a *App) SetBackendType(backendType string) error {
 	a.config.BackendType = backendType
 	if err := SaveConfig(a.config); err != nil {
-		log.Printf("Warning: failed to save config: %v", err)
+		slog.Warn("Failed to save config", "error", err)
+		return err
+	}
+	return nil
+}
+
+// ConnectDropbox runs the Dropbox login flow in the browser and, if Dropbox
+// is the active backend, reconnects the sync engine with the new tokens
+func (a *App) ConnectDropbox() error {
+	db, active := backend.Unwrap(a.backend).(*backend.DropboxBackend)
+	if !active {
+		db = backend.NewDropboxBackend(a.config.DropboxAppKey, a.config.DropboxAppSecret)
+		db.SetSecretStore(a.secrets)
+	}
+
+	if err := db.Login(context.Background(), ui.OpenBrowser); err != nil {
+		return err
+	}
+
+	if active {
+		return a.syncEngine.Reconnect()
+	}
+	return nil
+}
+
+// SetAppExcluded adds an app to or removes it from the "never sync"
+// list and saves the config
+func (a *App) SetAppExcluded(bundleID string, excluded bool) error {
+	filter := a.syncEngine.GetAppFilter()
+	filter.SetDenied(bundleID, excluded)
+
+	a.config.SyncDenyApps = filter.DenyList()
+	if err := SaveConfig(a.config); err != nil {
+		slog.Warn("Failed to save config", "error", err)
 		return err
 	}
+
+	if excluded {
+		slog.Info("Clips copied from app will no longer be synced", "app", bundleID)
+	} else {
+		slog.Info("Clips copied from app will be synced again", "app", bundleID)
+	}
 	return nil
 }
+
+// IsAppExcluded returns true if clips from the app are never synced
+func (a *App) IsAppExcluded(bundleID string) bool {
+	return a.syncEngine.GetAppFilter().Denied(bundleID)
+}
+
+// PairDevice starts a pairing session on the shared backend, reports the
+// one-time code through onCode and waits for the new device to join. The
+// new device is added to the trusted peers and its name returned.
+func (a *App) PairDevice(onCode func(code, uri string)) (string, error) {
+	peer, err := hostPairing(context.Background(), a.backend, a.identity, a.trust, onCode)
+	if err != nil {
+		return "", err
+	}
+	return peer.Name, nil
+}
+
+// HostPairing shows a pairing code and waits for a new device to join.
+// It is used by the "pair" CLI command.
+func HostPairing(ctx context.Context, onCode func(code, uri string)) (identity.Peer, error) {
+	b, id, trust, err := openPairing(ctx)
+	if err != nil {
+		return identity.Peer{}, err
+	}
+	defer b.Close()
+
+	return hostPairing(ctx, b, id, trust, onCode)
+}
+
+// JoinPairing pairs with the device showing code.
+// It is used by the "pair <code>" CLI command.
+func JoinPairing(ctx context.Context, code string) (identity.Peer, error) {
+	b, id, trust, err := openPairing(ctx)
+	if err != nil {
+		return identity.Peer{}, err
+	}
+	defer b.Close()
+
+	store, ok := backend.Unwrap(b).(backend.ObjectStore)
+	if !ok {
+		return identity.Peer{}, fmt.Errorf("%s backend does not support pairing", b.Type())
+	}
+
+	peer, err := pairing.Join(ctx, store, id, code)
+	if err != nil {
+		return identity.Peer{}, err
+	}
+	if err := trust.Add(peer.Name, peer.PublicKey); err != nil {
+		return identity.Peer{}, err
+	}
+	return peer, nil
+}
+
+func hostPairing(ctx context.Context, b backend.Backend, id *identity.Identity, trust *identity.TrustStore, onCode func(code, uri string)) (identity.Peer, error) {
+	store, ok := backend.Unwrap(b).(backend.ObjectStore)
+	if !ok {
+		return identity.Peer{}, fmt.Errorf("%s backend does not support pairing", b.Type())
+	}
+	if id == nil {
+		return identity.Peer{}, fmt.Errorf("no device key available")
+	}
+
+	session, err := pairing.Start(ctx, store, id)
+	if err != nil {
+		return identity.Peer{}, err
+	}
+	onCode(session.Code(), session.URI())
+
+	peer, err := session.Wait(ctx)
+	if err != nil {
+		return identity.Peer{}, err
+	}
+	if err := trust.Add(peer.Name, peer.PublicKey); err != nil {
+		return identity.Peer{}, err
+	}
+
+	slog.Info("Paired with device", "peer", peer.Name, "fingerprint", identity.Fingerprint(peer.PublicKey))
+	return peer, nil
+}
+
+// openPairing opens the configured backend, device key and trusted peers
+// for the pairing CLI commands
+func openPairing(ctx context.Context) (backend.Backend, *identity.Identity, *identity.TrustStore, error) {
+	config, err := LoadConfig()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	store, err := openSecretStore(config)
+	if err != nil {
+		slog.Warn("Failed to open secret store", "error", err)
+	}
+
+	b, err := backend.New(backendConfig(config, store))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err := b.Init(ctx); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to initialize backend: %w", err)
+	}
+
+	id, err := identity.Load(store, getConfigDir())
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	trust, err := identity.LoadTrustStore(filepath.Join(getConfigDir(), identity.PeersFileName), id)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return b, id, trust, nil
+}
+
+// LoginDropbox runs the Dropbox login flow using the saved configuration.
+// It is used by the "login dropbox" CLI command.
+func LoginDropbox(ctx context.Context, open backend.URLOpener) error {
+	config, err := LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	store, err := openSecretStore(config)
+	if err != nil {
+		return err
+	}
+
+	db := backend.NewDropboxBackend(config.DropboxAppKey, config.DropboxAppSecret)
+	db.SetSecretStore(store)
+	return db.Login(ctx, open)
+}
+
+// SaveS3Credentials stores static AWS keys in the configured secret store.
+// It is used by the "login s3" CLI command.
+func SaveS3Credentials(creds backend.S3Credentials) error {
+	config, err := LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	store, err := openSecretStore(config)
+	if err != nil {
+		return err
+	}
+
+	return backend.SaveS3Credentials(store, creds)
+}
+
+// DeviceIdentity returns this device's signing identity.
+// It is used by the "key" CLI command.
+func DeviceIdentity() (*identity.Identity, error) {
+	config, err := LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := openSecretStore(config)
+	if err != nil {
+		slog.Warn("Failed to open secret store", "error", err)
+	}
+
+	return identity.Load(store, getConfigDir())
+}
+
+// AuditLogPath returns where the audit log is kept.
+// It is used by the "audit" CLI command.
+func AuditLogPath() string {
+	return filepath.Join(getConfigDir(), audit.FileName)
+}
+
+// OpenTrustStore returns the trusted peer list.
+// It is used by the "trust" CLI command.
+func OpenTrustStore() (*identity.TrustStore, error) {
+	return identity.LoadTrustStore(filepath.Join(getConfigDir(), identity.PeersFileName), nil)
+}
+
+// loadIdentity loads the device key and trusted peers. If the peer list
+// can't be read, only this device is trusted.
+func loadIdentity(store secrets.Store) (*identity.Identity, *identity.TrustStore) {
+	id, err := identity.Load(store, getConfigDir())
+	if err != nil {
+		slog.Warn("Failed to load device key, clips will be unsigned", "error", err)
+	}
+
+	trust, err := identity.LoadTrustStore(filepath.Join(getConfigDir(), identity.PeersFileName), id)
+	if err != nil {
+		slog.Warn("Failed to load trusted peers", "error", err)
+	}
+
+	if id != nil {
+		slog.Info("Loaded device key", "fingerprint", id.Fingerprint())
+	}
+	return id, trust
+}
+
+// openSecretStore opens the configured secret store and moves any
+// plaintext credentials left in the config file into it
+func openSecretStore(config *Config) (secrets.Store, error) {
+	store, err := secrets.Open(secrets.Options{
+		Type: secrets.StoreType(config.SecretStore),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if config.DropboxAppSecret != "" {
+		err := store.Set(backend.DropboxSecretService, backend.DropboxAppSecretAccount, []byte(config.DropboxAppSecret))
+		if err != nil {
+			slog.Warn("Failed to migrate Dropbox app secret", "error", err)
+			return store, nil
+		}
+
+		config.DropboxAppSecret = ""
+		if err := SaveConfig(config); err != nil {
+			slog.Warn("Failed to save config", "error", err)
+		}
+		slog.Info("Moved Dropbox app secret from config file to secret store", "store", store.Type())
+	}
+
+	return store, nil
+}
+
+// backendConfig converts application config to backend config
+func backendConfig(config *Config, store secrets.Store) *backend.Config {
+	backendCfg := &backend.Config{
+		Type:             backend.BackendType(config.BackendType),
+		Secrets:          store,
+		Location:         config.SharedLocation,
+		StateDir:         getConfigDir(),
+		S3Bucket:         config.S3Bucket,
+		S3Prefix:         config.S3Prefix,
+		S3Region:         config.S3Region,
+		S3TagExpiring:    config.S3TagExpiring,
+		DropboxAppKey:    config.DropboxAppKey,
+		DropboxAppSecret: config.DropboxAppSecret,
+		DropboxPath:      config.DropboxPath,
+		DropboxPathRoot:  config.DropboxPathRoot,
+	}
+
+	// Default to local backend if not specified
+	if backendCfg.Type == "" {
+		backendCfg.Type = backend.BackendLocal
+	}
+
+	backendCfg.Format = formatOptions(config)
+	backendCfg.Faults = backendFaults(config)
+
+	return backendCfg
+}
+
+// logDir returns the directory holding the log files
+func logDir() string {
+	return filepath.Join(getConfigDir(), "logs")
+}
+
+// setupLogging sets up the default logger from the config. Invalid
+// levels and formats fall back to info and text.
+func setupLogging(config *Config) (io.Closer, error) {
+	level, levelErr := logging.ParseLevel(config.LogLevel)
+	format, formatErr := logging.ParseFormat(config.LogFormat)
+	if formatErr != nil {
+		format = logging.FormatText
+	}
+
+	closer, err := logging.Setup(logging.Options{
+		Level:  level,
+		Format: format,
+		Dir:    logDir(),
+	})
+
+	if levelErr != nil {
+		slog.Warn("Invalid log_level, using info", "error", levelErr)
+	}
+	if formatErr != nil {
+		slog.Warn("Invalid log_format, using text", "error", formatErr)
+	}
+	return closer, err
+}
+
+// backendFaults parses the debug fault injection settings. Invalid
+// durations are ignored and rates are clamped to 0..1.
+func backendFaults(config *Config) backend.Faults {
+	cfg := config.Debug.Faults
+	faults := backend.Faults{
+		ErrorRate:          clampRate(cfg.ErrorRate),
+		TornReadRate:       clampRate(cfg.TornReadRate),
+		StaleModTimeRate:   clampRate(cfg.StaleModTimeRate),
+		LockContentionRate: clampRate(cfg.LockContentionRate),
+		Seed:               cfg.Seed,
+	}
+
+	for _, d := range []struct {
+		name  string
+		value string
+		out   *time.Duration
+	}{
+		{"latency", cfg.Latency, &faults.Latency},
+		{"jitter", cfg.Jitter, &faults.Jitter},
+	} {
+		if d.value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(d.value)
+		if err != nil || parsed < 0 {
+			slog.Warn("Invalid debug fault setting, ignoring it", "setting", "debug.faults."+d.name, "value", d.value)
+			continue
+		}
+		*d.out = parsed
+	}
+
+	if faults.Enabled() {
+		slog.Warn("Injecting backend faults from the debug config")
+	}
+	return faults
+}
+
+// clampRate limits a probability to 0..1
+func clampRate(rate float64) float64 {
+	return min(max(rate, 0), 1)
+}
+
+// formatOptions converts clip format settings to storage options
+func formatOptions(config *Config) storage.Options {
+	opts := storage.DefaultOptions()
+
+	if config.ClipFormatVersion > 0 {
+		opts.Version = uint32(config.ClipFormatVersion)
+	}
+
+	compression, err := storage.ParseCompression(config.Compression)
+	if err != nil {
+		slog.Warn("Invalid compression, using the default", "error", err, "compression", opts.Compression)
+	} else {
+		opts.Compression = compression
+	}
+
+	return opts
+}
+
+// clipTTL parses the clip TTL setting. An invalid value disables expiry.
+func clipTTL(config *Config) time.Duration {
+	if config.ClipTTL == "" {
+		return 0
+	}
+	ttl, err := time.ParseDuration(config.ClipTTL)
+	if err != nil || ttl < 0 {
+		slog.Warn("Invalid clip_ttl, clips will not expire", "clip_ttl", config.ClipTTL)
+		return 0
+	}
+	return ttl
+}
+
+// syncPolicy builds the content type and size policy from config.
+// Invalid entries are skipped with a warning.
+func syncPolicy(config *Config) *sync.Policy {
+	policy := &sync.Policy{
+		MaxSize: make(map[clipboard.ContentType]int64),
+		Exclude: make(map[clipboard.ContentType]bool),
+	}
+
+	for contentType, size := range config.MaxSize {
+		limit, err := parseSize(size)
+		if err != nil {
+			slog.Warn("Invalid max_size", "type", contentType, "error", err)
+			continue
+		}
+		policy.MaxSize[clipboard.ContentType(contentType)] = limit
+	}
+
+	for _, contentType := range config.ExcludeTypes {
+		policy.Exclude[clipboard.ContentType(strings.ToLower(contentType))] = true
+	}
+
+	action, err := sync.ParseLargeItemAction(config.LargeItem)
+	if err != nil {
+		slog.Warn("Invalid large_item, skipping large items", "error", err)
+		action = sync.LargeItemSkip
+	}
+	policy.LargeItem = action
+
+	return policy
+}
+
+// conflictResolver builds the conflict strategy from config. An invalid
+// strategy falls back to last-write-wins.
+func conflictResolver(config *Config) sync.ConflictResolver {
+	var window time.Duration
+	if config.ConflictWindow != "" {
+		d, err := time.ParseDuration(config.ConflictWindow)
+		if err != nil || d < 0 {
+			slog.Warn("Invalid conflict_window, using the default", "conflict_window", config.ConflictWindow, "default", sync.DefaultConflictWindow)
+		} else {
+			window = d
+		}
+	}
+
+	resolver, err := sync.NewConflictResolver(config.ConflictStrategy, window, config.PreferDevice)
+	if err != nil {
+		slog.Warn("Invalid conflict_strategy, using the default", "error", err, "default", sync.StrategyLastWriteWins)
+		return sync.LastWriteWins{}
+	}
+	return resolver
+}
+
+// notificationEvents returns the events the user wants notifications for
+func notificationEvents(config *Config) notify.Events {
+	n := config.Notifications
+	return notify.Events{
+		ClipReceived:    n.ClipReceived,
+		WriteFailed:     n.WriteFailed,
+		AuthExpired:     n.AuthExpired,
+		UpdateAvailable: n.UpdateAvailable,
+		Conflicts:       n.Conflicts,
+	}
+}
+
+// imageOptions builds the image pipeline settings from config.
+// Invalid values fall back to sending PNG.
+func imageOptions(config *Config) *imaging.Options {
+	opts := &imaging.Options{
+		MaxDimension: config.ImageMaxDimension,
+		JPEGQuality:  config.ImageJPEGQuality,
+	}
+
+	format, err := imaging.ParseFormat(config.ImageFormat)
+	if err != nil {
+		slog.Warn("Invalid image_format, sending images as PNG", "error", err)
+		format = imaging.FormatPNG
+	}
+	opts.Format = format
+
+	if config.ImageReencodeThreshold != "" {
+		threshold, err := parseSize(config.ImageReencodeThreshold)
+		if err != nil {
+			slog.Warn("Invalid image_reencode_threshold, re-encoding every image", "error", err)
+		}
+		opts.Threshold = threshold
+	}
+
+	return opts
+}
+
+// parseSize parses a size such as "512KB" or "5MB" into bytes.
+// Units are powers of 1024; a bare number is bytes.
+func parseSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{
+		{"GB", 1 << 30},
+		{"MB", 1 << 20},
+		{"KB", 1 << 10},
+		{"B", 1},
+	} {
+		if strings.HasSuffix(s, unit.suffix) {
+			s = strings.TrimSpace(strings.TrimSuffix(s, unit.suffix))
+			multiplier = unit.size
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+// sensitivePipeline builds the sensitive content rules from config.
+// Built-in rules missing from the config use their default action.
+func sensitivePipeline(config *Config) *sensitive.Pipeline {
+	pipeline := sensitive.NewPipeline()
+
+	names := make([]string, 0, len(sensitive.DefaultActions))
+	for name := range sensitive.DefaultActions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		def := sensitive.DefaultActions[name]
+		action := def
+		if s, ok := config.SensitiveRules[name]; ok {
+			parsed, err := sensitive.ParseAction(s)
+			if err != nil {
+				slog.Warn("Invalid sensitive content rule action, using the default", "rule", name, "default", def, "error", err)
+			} else {
+				action = parsed
+			}
+		}
+		pipeline.Add(sensitive.Rule{Detector: sensitive.Builtin(name), Action: action})
+	}
+
+	for name := range config.SensitiveRules {
+		if _, ok := sensitive.DefaultActions[name]; !ok {
+			slog.Warn("Unknown sensitive content rule", "rule", name)
+		}
+	}
+
+	for _, p := range config.SensitivePatterns {
+		detector, err := sensitive.NewRegexDetector(p.Name, p.Pattern)
+		if err != nil {
+			slog.Warn("Invalid sensitive content pattern", "error", err)
+			continue
+		}
+		action, err := sensitive.ParseAction(p.Action)
+		if err != nil {
+			slog.Warn("Invalid sensitive content pattern action, blocking matches", "pattern", p.Name, "error", err)
+			action = sensitive.ActionBlock
+		} else if p.Action == "" {
+			action = sensitive.ActionBlock
+		}
+		pipeline.Add(sensitive.Rule{Detector: detector, Action: action})
+	}
+
+	return pipeline
+}
+
+// confirmSensitive asks the user whether to sync a clip that matched
+// sensitive content rules
+func confirmSensitive(content *clipboard.Content, rules []string) bool {
+	message := fmt.Sprintf("The copied text looks like it contains %s. Sync it to your other devices anyway?", strings.Join(rules, ", "))
+	return ui.ShowConfirmDialog("Sync sensitive content?", message, "Sync", "Don't Sync")
+}
@@ -4,6 +4,10 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/mindmorass/yippity-clippity/internal/imaging"
+	"github.com/mindmorass/yippity-clippity/internal/logging"
+	"github.com/mindmorass/yippity-clippity/internal/sensitive"
+	"github.com/mindmorass/yippity-clippity/internal/sync"
 	"github.com/spf13/viper"
 )
 
@@ -28,22 +32,175 @@ type Config struct {
 	S3Prefix string `mapstructure:"s3_prefix"`
 	S3Region string `mapstructure:"s3_region"`
 
-	// Dropbox-specific settings (app credentials stored via environment or keychain)
-	DropboxAppKey    string `mapstructure:"dropbox_app_key"`
+	// S3TagExpiring tags expiring clips so a lifecycle rule can remove
+	// them. It needs the s3:PutObjectTagging permission.
+	S3TagExpiring bool `mapstructure:"s3_tag_expiring"`
+
+	// Dropbox-specific settings
+	DropboxAppKey   string `mapstructure:"dropbox_app_key"`
+	DropboxPath     string `mapstructure:"dropbox_path"`      // Sync folder within Dropbox
+	DropboxPathRoot string `mapstructure:"dropbox_path_root"` // "", "team", or a namespace ID
+
+	// DropboxAppSecret is only read to migrate old configs; the secret is
+	// moved to the secret store on startup and cleared here
 	DropboxAppSecret string `mapstructure:"dropbox_app_secret"`
+
+	// Clip format settings. clip_format_version defaults to 1, which
+	// every release can read; raise it only once all devices understand
+	// the newer version. Version 2 adds compression, version 3 stores
+	// payloads as content-addressed blobs.
+	ClipFormatVersion int    `mapstructure:"clip_format_version"`
+	Compression       string `mapstructure:"compression"` // "zstd", "gzip", or "none"
+
+	// Secret store for backend credentials: "auto", "keychain",
+	// "secret-service", or "file" (passphrase from the environment)
+	SecretStore string `mapstructure:"secret_store"`
+
+	// ClipTTL is how long a shared clip stays valid, e.g. "10m". Expired
+	// clips are ignored by readers and removed by the device that wrote
+	// them. Empty means clips never expire.
+	ClipTTL string `mapstructure:"clip_ttl"`
+
+	// Size and type policy. MaxSize maps a content type ("text" or
+	// "image") to a size such as "5MB". LargeItem is what happens to clips
+	// over their limit: "skip", "downscale" (images) or "placeholder"
+	// (fetched on demand, needs clip format version 3).
+	MaxSize      map[string]string `mapstructure:"max_size"`
+	ExcludeTypes []string          `mapstructure:"exclude_types"`
+	LargeItem    string            `mapstructure:"large_item"`
+
+	// Image pipeline. Images are always stripped of metadata.
+	// ImageMaxDimension caps the longer side in pixels (0 keeps the size).
+	// Images larger than ImageReencodeThreshold are re-encoded as
+	// ImageFormat ("png", "jpeg" or "webp") if that makes them smaller.
+	// AcceptLossyImages applies images a peer re-encoded as JPEG.
+	ImageMaxDimension      int    `mapstructure:"image_max_dimension"`
+	ImageFormat            string `mapstructure:"image_format"`
+	ImageReencodeThreshold string `mapstructure:"image_reencode_threshold"`
+	ImageJPEGQuality       int    `mapstructure:"image_jpeg_quality"`
+	AcceptLossyImages      bool   `mapstructure:"accept_lossy_images"`
+
+	// ConflictStrategy decides between a remote clip and a local copy:
+	// "last-write-wins", "prefer-local", "prefer-device" or "keep-both".
+	// ConflictWindow is how long after a local copy prefer-local and
+	// keep-both apply, e.g. "10s". PreferDevice names the device that wins
+	// with prefer-device.
+	ConflictStrategy string `mapstructure:"conflict_strategy"`
+	ConflictWindow   string `mapstructure:"conflict_window"`
+	PreferDevice     string `mapstructure:"prefer_device"`
+
+	// RequireSignatures refuses remote clips that aren't signed by this
+	// device or a trusted peer
+	RequireSignatures bool `mapstructure:"require_signatures"`
+
+	// SensitiveRules sets the action ("block", "redact", "confirm" or
+	// "off") for each built-in sensitive content rule: private_key, aws,
+	// github, slack, jwt and credit_card
+	SensitiveRules map[string]string `mapstructure:"sensitive_rules"`
+
+	// SensitivePatterns are user-defined sensitive content rules
+	SensitivePatterns []SensitivePattern `mapstructure:"sensitive_patterns"`
+
+	// Bundle IDs of apps whose clips are never synced. If SyncAllowApps
+	// is set, only clips from those apps are synced.
+	SyncAllowApps []string `mapstructure:"sync_allow_apps"`
+	SyncDenyApps  []string `mapstructure:"sync_deny_apps"`
+
+	// MetricsAddr serves Prometheus metrics at /metrics on this loopback
+	// address, e.g. "127.0.0.1:9464". Empty disables the endpoint.
+	MetricsAddr string `mapstructure:"metrics_addr"`
+
+	// LogLevel is "debug", "info", "warn" or "error". LogFormat is "text"
+	// or "json". Logs are written to ~/.yippity-clippity/logs.
+	LogLevel  string `mapstructure:"log_level"`
+	LogFormat string `mapstructure:"log_format"`
+
+	// AuditLog records every clip sent, received or blocked by a policy
+	// rule in ~/.yippity-clippity/audit.jsonl. Clip contents are never
+	// written.
+	AuditLog bool `mapstructure:"audit_log"`
+
+	// Notifications turns desktop notifications on or off per event
+	Notifications NotificationsConfig `mapstructure:"notifications"`
+
+	// Debug holds settings for testing the app against a misbehaving
+	// backend. Leave it unset in normal use.
+	Debug DebugConfig `mapstructure:"debug"`
+}
+
+// NotificationsConfig selects the events that show a desktop
+// notification
+type NotificationsConfig struct {
+	ClipReceived    bool `mapstructure:"clip_received" yaml:"clip_received"`
+	WriteFailed     bool `mapstructure:"write_failed" yaml:"write_failed"`
+	AuthExpired     bool `mapstructure:"auth_expired" yaml:"auth_expired"`
+	UpdateAvailable bool `mapstructure:"update_available" yaml:"update_available"`
+	Conflicts       bool `mapstructure:"conflicts" yaml:"conflicts"`
+}
+
+// defaultNotifications shows everything except received clips, which
+// would notify on every copy
+func defaultNotifications() NotificationsConfig {
+	return NotificationsConfig{
+		WriteFailed:     true,
+		AuthExpired:     true,
+		UpdateAvailable: true,
+		Conflicts:       true,
+	}
+}
+
+// DebugConfig holds debugging settings
+type DebugConfig struct {
+	Faults FaultsConfig `mapstructure:"faults" yaml:"faults"`
+}
+
+// FaultsConfig sets the faults injected into every backend call.
+// Latency and Jitter are durations such as "200ms"; rates are
+// probabilities from 0 to 1. A non-zero Seed makes runs repeatable.
+type FaultsConfig struct {
+	Latency            string  `mapstructure:"latency" yaml:"latency,omitempty"`
+	Jitter             string  `mapstructure:"jitter" yaml:"jitter,omitempty"`
+	ErrorRate          float64 `mapstructure:"error_rate" yaml:"error_rate,omitempty"`
+	TornReadRate       float64 `mapstructure:"torn_read_rate" yaml:"torn_read_rate,omitempty"`
+	StaleModTimeRate   float64 `mapstructure:"stale_mod_time_rate" yaml:"stale_mod_time_rate,omitempty"`
+	LockContentionRate float64 `mapstructure:"lock_contention_rate" yaml:"lock_contention_rate,omitempty"`
+	Seed               uint64  `mapstructure:"seed" yaml:"seed,omitempty"`
+}
+
+// SensitivePattern is a user-defined sensitive content rule
+type SensitivePattern struct {
+	Name    string `mapstructure:"name" yaml:"name"`
+	Pattern string `mapstructure:"pattern" yaml:"pattern"`
+	Action  string `mapstructure:"action" yaml:"action"`
 }
 
 // DefaultConfig returns the default configuration
 func DefaultConfig() *Config {
 	return &Config{
-		SharedLocation:   "",
-		LaunchAtLogin:    false,
-		BackendType:      "local",
-		S3Bucket:         "",
-		S3Prefix:         "",
-		S3Region:         "",
-		DropboxAppKey:    "",
-		DropboxAppSecret: "",
+		SharedLocation:    "",
+		LaunchAtLogin:     false,
+		BackendType:       "local",
+		S3Bucket:          "",
+		S3Prefix:          "",
+		S3Region:          "",
+		DropboxAppKey:     "",
+		DropboxAppSecret:  "",
+		DropboxPath:       "",
+		DropboxPathRoot:   "",
+		ClipFormatVersion: 1,
+		Compression:       "zstd",
+		SecretStore:       "auto",
+		RequireSignatures: true,
+		SensitiveRules:    defaultSensitiveRules(),
+		LargeItem:         "skip",
+		ImageFormat:       "png",
+		ImageJPEGQuality:  imaging.DefaultJPEGQuality,
+		AcceptLossyImages: true,
+		ConflictStrategy:  sync.StrategyLastWriteWins,
+		LogLevel:          "info",
+		LogFormat:         string(logging.FormatText),
+		AuditLog:          true,
+		Notifications:     defaultNotifications(),
 	}
 }
 
@@ -67,8 +224,36 @@ func LoadConfig() (*Config, error) {
 	viper.SetDefault("s3_bucket", "")
 	viper.SetDefault("s3_prefix", "")
 	viper.SetDefault("s3_region", "")
+	viper.SetDefault("s3_tag_expiring", false)
 	viper.SetDefault("dropbox_app_key", "")
 	viper.SetDefault("dropbox_app_secret", "")
+	viper.SetDefault("dropbox_path", "")
+	viper.SetDefault("dropbox_path_root", "")
+	viper.SetDefault("clip_format_version", 1)
+	viper.SetDefault("compression", "zstd")
+	viper.SetDefault("secret_store", "auto")
+	viper.SetDefault("require_signatures", true)
+	viper.SetDefault("clip_ttl", "")
+	viper.SetDefault("large_item", "skip")
+	viper.SetDefault("image_max_dimension", 0)
+	viper.SetDefault("image_format", "png")
+	viper.SetDefault("image_reencode_threshold", "")
+	viper.SetDefault("image_jpeg_quality", imaging.DefaultJPEGQuality)
+	viper.SetDefault("accept_lossy_images", true)
+	viper.SetDefault("conflict_strategy", sync.StrategyLastWriteWins)
+	viper.SetDefault("conflict_window", "")
+	viper.SetDefault("prefer_device", "")
+	viper.SetDefault("sensitive_rules", defaultSensitiveRules())
+	viper.SetDefault("metrics_addr", "")
+	viper.SetDefault("log_level", "info")
+	viper.SetDefault("log_format", string(logging.FormatText))
+	viper.SetDefault("audit_log", true)
+	notifications := defaultNotifications()
+	viper.SetDefault("notifications.clip_received", notifications.ClipReceived)
+	viper.SetDefault("notifications.write_failed", notifications.WriteFailed)
+	viper.SetDefault("notifications.auth_expired", notifications.AuthExpired)
+	viper.SetDefault("notifications.update_available", notifications.UpdateAvailable)
+	viper.SetDefault("notifications.conflicts", notifications.Conflicts)
 
 	// Try to read config file
 	if err := viper.ReadInConfig(); err != nil {
@@ -102,13 +287,55 @@ func SaveConfig(config *Config) error {
 	viper.Set("s3_bucket", config.S3Bucket)
 	viper.Set("s3_prefix", config.S3Prefix)
 	viper.Set("s3_region", config.S3Region)
+	viper.Set("s3_tag_expiring", config.S3TagExpiring)
 	viper.Set("dropbox_app_key", config.DropboxAppKey)
 	viper.Set("dropbox_app_secret", config.DropboxAppSecret)
+	viper.Set("dropbox_path", config.DropboxPath)
+	viper.Set("dropbox_path_root", config.DropboxPathRoot)
+	viper.Set("clip_format_version", config.ClipFormatVersion)
+	viper.Set("compression", config.Compression)
+	viper.Set("secret_store", config.SecretStore)
+	viper.Set("require_signatures", config.RequireSignatures)
+	viper.Set("clip_ttl", config.ClipTTL)
+	viper.Set("max_size", config.MaxSize)
+	viper.Set("exclude_types", config.ExcludeTypes)
+	viper.Set("large_item", config.LargeItem)
+	viper.Set("image_max_dimension", config.ImageMaxDimension)
+	viper.Set("image_format", config.ImageFormat)
+	viper.Set("image_reencode_threshold", config.ImageReencodeThreshold)
+	viper.Set("image_jpeg_quality", config.ImageJPEGQuality)
+	viper.Set("accept_lossy_images", config.AcceptLossyImages)
+	viper.Set("conflict_strategy", config.ConflictStrategy)
+	viper.Set("conflict_window", config.ConflictWindow)
+	viper.Set("prefer_device", config.PreferDevice)
+	viper.Set("sensitive_rules", config.SensitiveRules)
+	viper.Set("sensitive_patterns", config.SensitivePatterns)
+	viper.Set("sync_allow_apps", config.SyncAllowApps)
+	viper.Set("sync_deny_apps", config.SyncDenyApps)
+	viper.Set("metrics_addr", config.MetricsAddr)
+	viper.Set("log_level", config.LogLevel)
+	viper.Set("log_format", config.LogFormat)
+	viper.Set("audit_log", config.AuditLog)
+	viper.Set("notifications", config.Notifications)
+
+	// Keep debug settings out of config files that don't use them
+	if config.Debug != (DebugConfig{}) {
+		viper.Set("debug", config.Debug)
+	}
 
 	configPath := filepath.Join(configDir, ConfigFileName+".yaml")
 	return viper.WriteConfigAs(configPath)
 }
 
+// defaultSensitiveRules returns the built-in sensitive content actions
+func defaultSensitiveRules() map[string]string {
+	rules := make(map[string]string, len(sensitive.DefaultActions))
+	for name, action := range sensitive.DefaultActions {
+		rules[name] = string(action)
+	}
+	return rules
+}
+
 func getConfigDir() string {
 	home, err := os.UserHomeDir()
 	if err != nil {
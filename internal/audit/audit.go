@@ -0,0 +1,167 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileName is the audit log's file name
+const FileName = "audit.jsonl"
+
+// Directions
+const (
+	Outgoing = "outgoing"
+	Incoming = "incoming"
+)
+
+// Decisions
+const (
+	Sent     = "sent"
+	Received = "received"
+	Blocked  = "blocked"
+)
+
+// Record is one audit log entry. It describes a clip but never holds its
+// contents.
+type Record struct {
+	Time        time.Time `json:"timestamp"`
+	Direction   string    `json:"direction"`
+	ClipID      string    `json:"clip_id"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	Checksum    string    `json:"checksum"`
+	Device      string    `json:"device"`
+	PeerDevice  string    `json:"peer_device,omitempty"`
+	Decision    string    `json:"decision"`
+
+	// Rule is the policy rule that blocked the clip, e.g.
+	// "sensitive: aws"
+	Rule string `json:"rule,omitempty"`
+}
+
+// Log is an append-only audit log of JSON lines. Existing records are
+// never rewritten.
+type Log struct {
+	file *os.File
+	mu   sync.Mutex
+}
+
+// Open opens the audit log at path for appending, creating it if needed
+func Open(path string) (*Log, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	if err := endLine(file); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return &Log{file: file}, nil
+}
+
+// endLine ends a last line cut short by a crash, so the next record
+// starts on a line of its own
+func endLine(file *os.File) error {
+	info, err := file.Stat()
+	if err != nil || info.Size() == 0 {
+		return err
+	}
+	last := make([]byte, 1)
+	if _, err := file.ReadAt(last, info.Size()-1); err != nil {
+		return err
+	}
+	if last[0] == '\n' {
+		return nil
+	}
+	_, err = file.Write([]byte{'\n'})
+	return err
+}
+
+// Append adds a record and flushes it to disk
+func (l *Log) Append(r Record) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, err := l.file.Write(line); err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+	return l.file.Sync()
+}
+
+// Close closes the log
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// Filter selects records. Zero fields match everything.
+type Filter struct {
+	Since     time.Time
+	Until     time.Time
+	Direction string
+	Decision  string
+	Peer      string
+}
+
+// Match returns true if r passes the filter
+func (f Filter) Match(r Record) bool {
+	switch {
+	case !f.Since.IsZero() && r.Time.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !r.Time.Before(f.Until):
+		return false
+	case f.Direction != "" && r.Direction != f.Direction:
+		return false
+	case f.Decision != "" && r.Decision != f.Decision:
+		return false
+	case f.Peer != "" && r.PeerDevice != f.Peer:
+		return false
+	}
+	return true
+}
+
+// Read returns the records in the audit log at path that match the
+// filter, oldest first. A missing log has no records. A line cut short
+// by a crash is skipped.
+func Read(path string, filter Filter) ([]Record, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			continue
+		}
+		if filter.Match(r) {
+			records = append(records, r)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return records, nil
+}
@@ -0,0 +1,216 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+var (
+	sent = Record{
+		Time:        time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC),
+		Direction:   Outgoing,
+		ClipID:      "laptop-1",
+		ContentType: "text",
+		Size:        5,
+		Checksum:    "2cf24dba",
+		Device:      "laptop",
+		Decision:    Sent,
+	}
+	received = Record{
+		Time:        time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC),
+		Direction:   Incoming,
+		ClipID:      "desktop-1",
+		ContentType: "image",
+		Size:        2048,
+		Checksum:    "486ea462",
+		Device:      "laptop",
+		PeerDevice:  "desktop",
+		Decision:    Received,
+	}
+	blocked = Record{
+		Time:        time.Date(2026, 3, 1, 11, 0, 0, 0, time.UTC),
+		Direction:   Incoming,
+		ClipID:      "phone-1",
+		ContentType: "text",
+		Size:        40,
+		Checksum:    "9f86d081",
+		Device:      "laptop",
+		PeerDevice:  "phone",
+		Decision:    Blocked,
+		Rule:        "sensitive: aws",
+	}
+)
+
+// writeLog appends records to a new audit log and returns its path
+func writeLog(t *testing.T, records ...Record) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "audit", FileName)
+	log, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	for _, r := range records {
+		if err := log.Append(r); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+	if err := log.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	return path
+}
+
+func TestAppendRead(t *testing.T) {
+	path := writeLog(t, sent, received, blocked)
+
+	got, err := Read(path, Filter{})
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if want := []Record{sent, received, blocked}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Read = %+v, want %+v", got, want)
+	}
+
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("audit log mode = %v, %v, want 0600", info.Mode().Perm(), err)
+	}
+}
+
+func TestReadMissingLog(t *testing.T) {
+	got, err := Read(filepath.Join(t.TempDir(), FileName), Filter{})
+	if err != nil || got != nil {
+		t.Errorf("Read = %v, %v, want no records", got, err)
+	}
+}
+
+func TestReadFilter(t *testing.T) {
+	path := writeLog(t, sent, received, blocked)
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []Record
+	}{
+		{"since", Filter{Since: received.Time}, []Record{received, blocked}},
+		{"until", Filter{Until: received.Time}, []Record{sent}},
+		{"since and until", Filter{Since: sent.Time.Add(time.Minute), Until: blocked.Time}, []Record{received}},
+		{"direction", Filter{Direction: Outgoing}, []Record{sent}},
+		{"decision", Filter{Decision: Blocked}, []Record{blocked}},
+		{"peer", Filter{Peer: "desktop"}, []Record{received}},
+		{"combined", Filter{Direction: Incoming, Peer: "desktop", Decision: Blocked}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Read(path, tt.filter)
+			if err != nil {
+				t.Fatalf("Read: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Read = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadSkipsTruncatedLine(t *testing.T) {
+	path := writeLog(t, sent)
+
+	// A crash cut the next record short
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.WriteString(`{"timestamp":"2026-03-01T10:00:00Z","direc`); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	got, err := Read(path, Filter{})
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if want := []Record{sent}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Read = %+v, want %+v", got, want)
+	}
+
+	// Records appended after the crash are kept
+	log, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := log.Append(blocked); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	log.Close()
+
+	got, err = Read(path, Filter{})
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if want := []Record{sent, blocked}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Read after reopening = %+v, want %+v", got, want)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, []Record{sent, blocked}); err != nil {
+		t.Fatalf("WriteCSV: %v", err)
+	}
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("exported CSV doesn't parse: %v", err)
+	}
+	want := [][]string{
+		{"timestamp", "direction", "clip_id", "content_type", "size", "checksum", "device", "peer_device", "decision", "rule"},
+		{"2026-03-01T09:00:00Z", "outgoing", "laptop-1", "text", "5", "2cf24dba", "laptop", "", "sent", ""},
+		{"2026-03-01T11:00:00Z", "incoming", "phone-1", "text", "40", "9f86d081", "laptop", "phone", "blocked", "sensitive: aws"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("CSV rows = %q, want %q", rows, want)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, []Record{blocked}); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+
+	var got []map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("exported JSON doesn't parse: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("exported %d records, want 1", len(got))
+	}
+
+	// The JSON fields are the CSV columns
+	var fields []string
+	for name := range got[0] {
+		fields = append(fields, name)
+	}
+	sort.Strings(fields)
+	columns := append([]string(nil), csvHeader...)
+	sort.Strings(columns)
+	if !reflect.DeepEqual(fields, columns) {
+		t.Errorf("JSON fields = %q, want %q", fields, columns)
+	}
+
+	buf.Reset()
+	if err := WriteJSON(&buf, nil); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	if got := buf.String(); got != "[]\n" {
+		t.Errorf("WriteJSON with no records = %q, want []", got)
+	}
+}
@@ -0,0 +1,52 @@
+package audit
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+)
+
+// csvHeader names the CSV columns, matching the JSON field names
+var csvHeader = []string{
+	"timestamp", "direction", "clip_id", "content_type", "size",
+	"checksum", "device", "peer_device", "decision", "rule",
+}
+
+// WriteCSV writes records as CSV with a header row
+func WriteCSV(w io.Writer, records []Record) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, r := range records {
+		err := cw.Write([]string{
+			r.Time.Format(time.RFC3339Nano),
+			r.Direction,
+			r.ClipID,
+			r.ContentType,
+			strconv.FormatInt(r.Size, 10),
+			r.Checksum,
+			r.Device,
+			r.PeerDevice,
+			r.Decision,
+			r.Rule,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes records as a JSON array
+func WriteJSON(w io.Writer, records []Record) error {
+	if records == nil {
+		records = []Record{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(records)
+}
@@ -0,0 +1,73 @@
+package audit
+
+import (
+	"log/slog"
+
+	"github.com/mindmorass/yippity-clippity/internal/logging"
+	"github.com/mindmorass/yippity-clippity/internal/sync"
+)
+
+// policyReasons are the skip reasons that count as a policy rule
+// blocking a clip. Expired clips, lost conflicts and deferred downloads
+// aren't policy decisions and aren't recorded.
+var policyReasons = map[sync.SkipReason]bool{
+	sync.SkipExcludedApp:  true,
+	sync.SkipSensitive:    true,
+	sync.SkipTooLarge:     true,
+	sync.SkipExcludedType: true,
+	sync.SkipUntrusted:    true,
+	sync.SkipLossyImage:   true,
+	sync.SkipBadImage:     true,
+}
+
+// Attach records an engine's sent, received and blocked clips in log.
+// Call the returned function to stop.
+func Attach(engine *sync.Engine, log *Log) (detach func()) {
+	device := engine.DeviceName()
+
+	return engine.Subscribe(func(event sync.Event) {
+		var r Record
+		switch event.Type {
+		case sync.EventPublished:
+			r = newRecord(event, device, Sent)
+		case sync.EventApplied:
+			r = newRecord(event, device, Received)
+		case sync.EventSkipped:
+			if !policyReasons[event.Reason] {
+				return
+			}
+			r = newRecord(event, device, Blocked)
+			r.Rule = string(event.Reason)
+			if event.Detail != "" {
+				r.Rule += ": " + event.Detail
+			}
+		default:
+			return
+		}
+
+		if err := log.Append(r); err != nil {
+			slog.Error("Failed to write audit record", logging.KeyClip, r.ClipID, logging.KeyError, err)
+		}
+	})
+}
+
+// newRecord describes the event's clip. Clips from this device are
+// outgoing, everything else incoming.
+func newRecord(event sync.Event, device, decision string) Record {
+	clip := event.Clip
+	r := Record{
+		Time:        event.Time.UTC(),
+		Direction:   Outgoing,
+		ClipID:      clip.ID,
+		ContentType: string(clip.ContentType),
+		Size:        clip.Size,
+		Checksum:    clip.Checksum,
+		Device:      device,
+		Decision:    decision,
+	}
+	if clip.SourceMachine != device {
+		r.Direction = Incoming
+		r.PeerDevice = clip.SourceMachine
+	}
+	return r
+}
@@ -0,0 +1,99 @@
+package audit_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mindmorass/yippity-clippity/internal/audit"
+	"github.com/mindmorass/yippity-clippity/internal/clipboard"
+	"github.com/mindmorass/yippity-clippity/internal/sim"
+	"github.com/mindmorass/yippity-clippity/internal/sync"
+)
+
+// attach records a device's clips in a new audit log and returns its path
+func attach(t *testing.T, d *sim.Device) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), audit.FileName)
+	log, err := audit.Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	detach := audit.Attach(d.Engine, log)
+	t.Cleanup(func() {
+		detach()
+		log.Close()
+	})
+	return path
+}
+
+func TestAttachRecordsDecisions(t *testing.T) {
+	s := sim.New("a", "b")
+	a, b := s.Device("a"), s.Device("b")
+	aLog, bLog := attach(t, a), attach(t, b)
+
+	a.Clipboard.Copy("first clip")
+	s.Run(10)
+
+	// b blocks text by policy
+	b.Engine.SetPolicy(&sync.Policy{
+		Exclude: map[clipboard.ContentType]bool{clipboard.ContentTypeText: true},
+	})
+	a.Clipboard.Copy("second clip")
+	s.Run(10)
+
+	// Deferring a large clip isn't a policy decision
+	b.Engine.SetPolicy(&sync.Policy{
+		MaxSize: map[clipboard.ContentType]int64{clipboard.ContentTypeText: 4},
+	})
+	a.Clipboard.Copy("third clip")
+	s.Run(10)
+
+	type want struct {
+		direction, decision, peer, rule string
+	}
+	check := func(path, device string, wants []want) {
+		t.Helper()
+		records, err := audit.Read(path, audit.Filter{})
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+		if len(records) != len(wants) {
+			t.Fatalf("%s has %d records, want %d: %+v", path, len(records), len(wants), records)
+		}
+		for i, r := range records {
+			w := wants[i]
+			if r.Direction != w.direction || r.Decision != w.decision || r.PeerDevice != w.peer || r.Rule != w.rule {
+				t.Errorf("record %d = %s %s from %q rule %q, want %s %s from %q rule %q",
+					i, r.Direction, r.Decision, r.PeerDevice, r.Rule, w.direction, w.decision, w.peer, w.rule)
+			}
+			if r.ClipID == "" || r.Checksum == "" || r.Size == 0 || r.ContentType != string(clipboard.ContentTypeText) {
+				t.Errorf("record %d doesn't describe the clip: %+v", i, r)
+			}
+			if r.Device != device {
+				t.Errorf("record %d is from device %q, want %q", i, r.Device, device)
+			}
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, text := range []string{"first clip", "second clip", "third clip"} {
+			if bytes.Contains(data, []byte(text)) {
+				t.Errorf("audit log holds the clip data %q", text)
+			}
+		}
+	}
+
+	check(aLog, "a", []want{
+		{audit.Outgoing, audit.Sent, "", ""},
+		{audit.Outgoing, audit.Sent, "", ""},
+		{audit.Outgoing, audit.Sent, "", ""},
+	})
+	check(bLog, "b", []want{
+		{audit.Incoming, audit.Received, "a", ""},
+		{audit.Incoming, audit.Blocked, "a", "excluded_type: text"},
+	})
+}
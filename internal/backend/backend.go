@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/mindmorass/yippity-clippity/internal/clipboard"
+	"github.com/mindmorass/yippity-clippity/internal/secrets"
+	"github.com/mindmorass/yippity-clippity/internal/storage"
 )
 
 // BackendType identifies the type of storage backend
@@ -23,20 +25,33 @@ var (
 	ErrNotFound      = errors.New("clipboard data not found")
 	ErrLocked        = errors.New("resource is locked by another process")
 	ErrConflict      = errors.New("write conflict detected")
+	ErrNoSecretStore = errors.New("no secret store configured")
+	ErrLockLost      = errors.New("lock lease lost during write")
+	ErrStaleFence    = errors.New("clip written under a stale fencing token")
+	ErrAuthExpired   = errors.New("backend credentials expired or were revoked")
 )
 
+// IsAuthError returns true if err means the backend rejected its
+// credentials, so the user has to sign in again
+func IsAuthError(err error) bool {
+	return errors.Is(err, ErrAuthExpired) || isS3AuthError(err)
+}
+
 // Backend defines the interface for clipboard storage backends
 type Backend interface {
 	// Write stores clipboard content
 	Write(ctx context.Context, content *clipboard.Content) error
 
-	// Read retrieves clipboard content
+	// Read retrieves clipboard content. It returns nil, nil if no clip
+	// has been written.
 	Read(ctx context.Context) (*clipboard.Content, error)
 
-	// GetModTime returns the last modification time
+	// GetModTime returns the last modification time, or ErrNotFound if
+	// no clip has been written
 	GetModTime(ctx context.Context) (time.Time, error)
 
-	// GetChecksum returns a lightweight checksum for change detection
+	// GetChecksum returns a lightweight checksum for change detection,
+	// or ErrNotFound if no clip has been written.
 	// This should be cheaper than a full Read() operation
 	GetChecksum(ctx context.Context) (string, error)
 
@@ -59,17 +74,114 @@ type Backend interface {
 	SetLocation(location string) error
 }
 
+// Watchable is implemented by backends whose changes can be observed
+// through filesystem notifications instead of polling alone
+type Watchable interface {
+	// WatchDir returns the directory holding the clipboard file and
+	// whether change notifications are reliable for it
+	WatchDir() (string, bool)
+}
+
+// Collector is implemented by backends that store payloads as
+// content-addressed blobs
+type Collector interface {
+	// CollectGarbage removes blobs no longer referenced by the current
+	// clip and returns how many were removed
+	CollectGarbage(ctx context.Context) (int, error)
+}
+
+// Remover is implemented by backends that can delete the current clip,
+// e.g. once it has expired
+type Remover interface {
+	// RemoveClip deletes the current clip if it is still the clip with
+	// the given ID. It returns ErrConflict if another clip replaced it.
+	// Removing a missing clip is not an error.
+	RemoveClip(ctx context.Context, id string) error
+}
+
+// Fetcher is implemented by backends that can publish large clips as
+// placeholders whose payload receivers download on demand
+type Fetcher interface {
+	// CanFetch returns true if placeholders can be written in the
+	// configured clip format
+	CanFetch() bool
+
+	// Fetch downloads the payload of a placeholder clip
+	Fetch(ctx context.Context, content *clipboard.Content) (*clipboard.Content, error)
+}
+
+// HeaderReader is implemented by backends that can read the header of the
+// current clip without downloading its payload
+type HeaderReader interface {
+	// ReadHeader returns the current clip without its payload, or nil if
+	// there is none. Placeholders are returned as Read returns them.
+	ReadHeader(ctx context.Context) (*clipboard.Content, error)
+}
+
+// ObjectStore is implemented by backends that can keep small named
+// objects next to the clipboard file, such as device pairing messages.
+// Names are limited to lowercase letters, digits and dashes.
+type ObjectStore interface {
+	// PutObject creates or replaces an object
+	PutObject(ctx context.Context, name string, data []byte) error
+
+	// GetObject returns an object, or ErrNotFound if it doesn't exist
+	GetObject(ctx context.Context, name string) ([]byte, error)
+
+	// DeleteObject removes an object. Deleting a missing object is not an error.
+	DeleteObject(ctx context.Context, name string) error
+}
+
+// RawReader is implemented by backends that can return the stored bytes
+// of the current clip and decode such bytes the same way Read does
+type RawReader interface {
+	// ReadRaw returns the stored clip, or nil if there is none
+	ReadRaw(ctx context.Context) ([]byte, error)
+
+	// DecodeRaw decodes bytes returned by ReadRaw
+	DecodeRaw(ctx context.Context, data []byte) (*clipboard.Content, error)
+}
+
 // Config holds configuration for creating backends
 type Config struct {
 	Type     BackendType
 	Location string // For local: filesystem path
 
+	// StateDir holds state kept on this device, such as the highest
+	// fencing token seen by the local backend
+	StateDir string
+
+	// Secrets holds credentials for remote backends
+	Secrets secrets.Store
+
+	// Format controls the .clip format version and compression for writes
+	Format storage.Options
+
 	// S3-specific
 	S3Bucket string
 	S3Prefix string
 	S3Region string
 
+	// S3TagExpiring tags expiring clips for lifecycle rules, which needs
+	// the s3:PutObjectTagging permission
+	S3TagExpiring bool
+
 	// Dropbox-specific
 	DropboxAppKey    string
 	DropboxAppSecret string
+	DropboxPath      string // Sync folder, defaults to DropboxDefaultFolder
+	DropboxPathRoot  string // "", "team", or a namespace ID
+
+	// Faults wraps the backend in a FaultyBackend when any are enabled.
+	// For debugging only.
+	Faults Faults
+}
+
+// headerContent returns the clip described by a stored clip header,
+// without its payload
+func headerContent(header *storage.FileHeader) (*clipboard.Content, error) {
+	if header.Placeholder {
+		return storage.PlaceholderContent(header)
+	}
+	return storage.ContentFromHeader(header, nil)
 }
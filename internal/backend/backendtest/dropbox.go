@@ -0,0 +1,375 @@
+package backendtest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mindmorass/yippity-clippity/internal/backend"
+)
+
+// dropboxBlockSize is the block size of the Dropbox content hash
+const dropboxBlockSize = 4 * 1024 * 1024
+
+// dropboxFile is a file held by FakeDropbox
+type dropboxFile struct {
+	name           string
+	data           []byte
+	rev            string
+	contentHash    string
+	serverModified time.Time
+}
+
+// dropboxEntry is file or folder metadata as the Dropbox API returns it
+type dropboxEntry struct {
+	Tag            string `json:".tag"`
+	Name           string `json:"name"`
+	PathDisplay    string `json:"path_display"`
+	Rev            string `json:"rev,omitempty"`
+	ContentHash    string `json:"content_hash,omitempty"`
+	ServerModified string `json:"server_modified,omitempty"`
+	Size           int    `json:"size,omitempty"`
+}
+
+// FakeDropbox is an in-memory stand-in for the parts of the Dropbox API
+// the Dropbox backend uses. Paths are case-insensitive, as on Dropbox.
+type FakeDropbox struct {
+	token   string
+	server  *httptest.Server
+	files   map[string]dropboxFile
+	folders map[string]bool
+	rev     int
+	mu      sync.Mutex
+}
+
+// NewFakeDropbox starts a fake Dropbox server that accepts token as the
+// access token. It serves both the API and the content endpoints, and is
+// stopped when the test ends.
+func NewFakeDropbox(t testing.TB, token string) *FakeDropbox {
+	s := &FakeDropbox{
+		token:   token,
+		files:   make(map[string]dropboxFile),
+		folders: map[string]bool{"": true},
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+// URL returns the API base URL of the fake server, including the version
+func (s *FakeDropbox) URL() string {
+	return s.server.URL + "/2"
+}
+
+// Opener returns an Opener for Dropbox backends syncing through folder
+func (s *FakeDropbox) Opener(folder string) Opener {
+	return func() (backend.Backend, error) {
+		b := backend.NewDropboxBackend("conformance", "")
+		b.SetTokens(s.token, "", time.Now().Add(time.Hour))
+		b.SetAPIEndpoints(s.URL(), s.URL())
+		if err := b.SetLocation(folder); err != nil {
+			return nil, err
+		}
+		if err := b.Init(context.Background()); err != nil {
+			return nil, err
+		}
+		return b, nil
+	}
+}
+
+// Age moves the server modification time of the file at p back by d, and
+// reports whether there is such a file
+func (s *FakeDropbox) Age(p string, d time.Duration) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	file, ok := s.files[dropboxKey(p)]
+	if ok {
+		file.serverModified = file.serverModified.Add(-d)
+		s.files[dropboxKey(p)] = file
+	}
+	return ok
+}
+
+func (s *FakeDropbox) handle(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer "+s.token {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"error_summary": "invalid_access_token/", "error": {".tag": "invalid_access_token"}}`)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch strings.TrimPrefix(r.URL.Path, "/2") {
+	case "/files/upload":
+		s.upload(w, r)
+	case "/files/download":
+		s.download(w, r)
+	case "/files/get_metadata":
+		s.getMetadata(w, r)
+	case "/files/create_folder_v2":
+		s.createFolder(w, r)
+	case "/files/delete_v2":
+		s.deleteFile(w, r)
+	case "/files/list_folder":
+		s.listFolder(w, r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (s *FakeDropbox) upload(w http.ResponseWriter, r *http.Request) {
+	var args struct {
+		Path string          `json:"path"`
+		Mode json.RawMessage `json:"mode"`
+	}
+	if err := json.Unmarshal([]byte(r.Header.Get("Dropbox-API-Arg")), &args); err != nil {
+		dropboxBadRequest(w, err)
+		return
+	}
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		dropboxBadRequest(w, err)
+		return
+	}
+
+	// Mode is "overwrite", or an update of a given revision
+	var update struct {
+		Tag    string `json:".tag"`
+		Update string `json:"update"`
+	}
+	json.Unmarshal(args.Mode, &update)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := dropboxKey(args.Path)
+	current, exists := s.files[key]
+	if update.Tag == "update" && (!exists || current.rev != update.Update) {
+		dropboxError(w, "path/conflict/file/", `{".tag": "path", "reason": {".tag": "conflict", "conflict": {".tag": "file"}}}`)
+		return
+	}
+
+	s.rev++
+	file := dropboxFile{
+		name:           path.Base(args.Path),
+		data:           data,
+		rev:            fmt.Sprintf("%09x", s.rev),
+		contentHash:    dropboxContentHash(data),
+		serverModified: time.Now().UTC().Truncate(time.Second),
+	}
+	s.files[key] = file
+	for dir := path.Dir(key); dir != "/"; dir = path.Dir(dir) {
+		s.folders[dir] = true
+	}
+
+	writeJSON(w, fileEntry(args.Path, file))
+}
+
+func (s *FakeDropbox) download(w http.ResponseWriter, r *http.Request) {
+	var args struct {
+		Path string `json:"path"`
+	}
+	if err := json.Unmarshal([]byte(r.Header.Get("Dropbox-API-Arg")), &args); err != nil {
+		dropboxBadRequest(w, err)
+		return
+	}
+
+	s.mu.Lock()
+	file, ok := s.files[dropboxKey(args.Path)]
+	s.mu.Unlock()
+
+	if !ok {
+		dropboxNotFound(w)
+		return
+	}
+
+	meta, _ := json.Marshal(fileEntry(args.Path, file))
+	w.Header().Set("Dropbox-API-Result", string(meta))
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.Itoa(len(file.data)))
+	w.Write(file.data)
+}
+
+func (s *FakeDropbox) getMetadata(w http.ResponseWriter, r *http.Request) {
+	var args struct {
+		Path string `json:"path"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		dropboxBadRequest(w, err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := dropboxKey(args.Path)
+	if file, ok := s.files[key]; ok {
+		writeJSON(w, fileEntry(args.Path, file))
+		return
+	}
+	if s.folders[key] {
+		writeJSON(w, dropboxEntry{Tag: "folder", Name: path.Base(args.Path), PathDisplay: args.Path})
+		return
+	}
+	dropboxNotFound(w)
+}
+
+func (s *FakeDropbox) createFolder(w http.ResponseWriter, r *http.Request) {
+	var args struct {
+		Path string `json:"path"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		dropboxBadRequest(w, err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := dropboxKey(args.Path)
+	if _, ok := s.files[key]; ok || s.folders[key] {
+		dropboxError(w, "path/conflict/folder/", `{".tag": "path", "path": {".tag": "conflict", "conflict": {".tag": "folder"}}}`)
+		return
+	}
+	for dir := key; dir != "/"; dir = path.Dir(dir) {
+		s.folders[dir] = true
+	}
+
+	writeJSON(w, map[string]dropboxEntry{
+		"metadata": {Tag: "folder", Name: path.Base(args.Path), PathDisplay: args.Path},
+	})
+}
+
+func (s *FakeDropbox) deleteFile(w http.ResponseWriter, r *http.Request) {
+	var args struct {
+		Path      string `json:"path"`
+		ParentRev string `json:"parent_rev"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		dropboxBadRequest(w, err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := dropboxKey(args.Path)
+	file, ok := s.files[key]
+	if !ok {
+		dropboxNotFound(w)
+		return
+	}
+	if args.ParentRev != "" && file.rev != args.ParentRev {
+		dropboxError(w, "path_write/conflict/file/", `{".tag": "path_write", "path_write": {".tag": "conflict"}}`)
+		return
+	}
+
+	delete(s.files, key)
+	writeJSON(w, map[string]dropboxEntry{"metadata": fileEntry(args.Path, file)})
+}
+
+// listFolder answers in a single page. Only files are listed.
+func (s *FakeDropbox) listFolder(w http.ResponseWriter, r *http.Request) {
+	var args struct {
+		Path string `json:"path"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		dropboxBadRequest(w, err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dir := dropboxKey(args.Path)
+	if !s.folders[dir] {
+		dropboxNotFound(w)
+		return
+	}
+
+	entries := []dropboxEntry{}
+	for key, file := range s.files {
+		if path.Dir(key) == dir {
+			entries = append(entries, fileEntry(path.Join(args.Path, file.name), file))
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+
+	writeJSON(w, map[string]interface{}{
+		"entries":  entries,
+		"cursor":   "",
+		"has_more": false,
+	})
+}
+
+// dropboxKey normalizes a path for lookups. The root folder is "".
+func dropboxKey(p string) string {
+	p = strings.ToLower(path.Clean("/" + p))
+	if p == "/" {
+		return ""
+	}
+	return p
+}
+
+// dropboxContentHash computes the Dropbox content hash: the SHA-256 of
+// the concatenated SHA-256 digests of each 4 MiB block
+func dropboxContentHash(data []byte) string {
+	h := sha256.New()
+	for len(data) > 0 {
+		n := min(len(data), dropboxBlockSize)
+		block := sha256.Sum256(data[:n])
+		h.Write(block[:])
+		data = data[n:]
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func fileEntry(p string, file dropboxFile) dropboxEntry {
+	return dropboxEntry{
+		Tag:            "file",
+		Name:           file.name,
+		PathDisplay:    p,
+		Rev:            file.rev,
+		ContentHash:    file.contentHash,
+		ServerModified: file.serverModified.Format(time.RFC3339),
+		Size:           len(file.data),
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// dropboxError writes an endpoint-specific error, which Dropbox reports
+// with status 409
+func dropboxError(w http.ResponseWriter, summary, detail string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusConflict)
+	fmt.Fprintf(w, `{"error_summary": %q, "error": %s}`, summary, detail)
+}
+
+func dropboxNotFound(w http.ResponseWriter) {
+	dropboxError(w, "path/not_found/", `{".tag": "path", "path": {".tag": "not_found"}}`)
+}
+
+func dropboxBadRequest(w http.ResponseWriter, err error) {
+	w.WriteHeader(http.StatusBadRequest)
+	fmt.Fprintf(w, "Error in call: %v", err)
+}
@@ -0,0 +1,342 @@
+package backendtest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mindmorass/yippity-clippity/internal/backend"
+	"github.com/mindmorass/yippity-clippity/internal/secrets"
+)
+
+// s3Object is an object held by FakeS3
+type s3Object struct {
+	data         []byte
+	etag         string
+	crc32        string
+	lastModified time.Time
+	metadata     map[string]string
+	tagging      string
+}
+
+// FakeS3 is an in-memory stand-in for the parts of the S3 API the S3
+// backend uses, addressed path-style. Requests aren't authenticated.
+type FakeS3 struct {
+	bucket  string
+	server  *httptest.Server
+	objects map[string]s3Object
+	mu      sync.Mutex
+}
+
+// NewFakeS3 starts a fake S3 server holding one empty bucket. It is
+// stopped when the test ends.
+func NewFakeS3(t testing.TB, bucket string) *FakeS3 {
+	s := &FakeS3{
+		bucket:  bucket,
+		objects: make(map[string]s3Object),
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+// URL returns the endpoint of the fake server
+func (s *FakeS3) URL() string {
+	return s.server.URL
+}
+
+// Opener returns an Opener for S3 backends storing under prefix in the
+// fake bucket
+func (s *FakeS3) Opener(t testing.TB, prefix string) Opener {
+	store := secrets.NewMemoryStore()
+	err := backend.SaveS3Credentials(store, backend.S3Credentials{
+		AccessKeyID:     "AKIDCONFORMANCE",
+		SecretAccessKey: "conformance",
+	})
+	if err != nil {
+		t.Fatalf("failed to save S3 credentials: %v", err)
+	}
+
+	return func() (backend.Backend, error) {
+		b := backend.NewS3Backend(s.bucket, prefix, "us-east-1")
+		b.SetEndpoint(s.URL())
+		b.SetSecretStore(store)
+		if err := b.Init(context.Background()); err != nil {
+			return nil, err
+		}
+		return b, nil
+	}
+}
+
+// Metadata returns the user metadata and tag set stored with the object
+// at key, and false if there is no such object
+func (s *FakeS3) Metadata(key string) (map[string]string, string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	obj, ok := s.objects[key]
+	return obj.metadata, obj.tagging, ok
+}
+
+// Age moves the modification time of the object at key back by d, and
+// reports whether there is such an object
+func (s *FakeS3) Age(key string, d time.Duration) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	obj, ok := s.objects[key]
+	if ok {
+		obj.lastModified = obj.lastModified.Add(-d)
+		s.objects[key] = obj
+	}
+	return ok
+}
+
+// LastModified returns the modification time of the object at key, and
+// false if there is no such object
+func (s *FakeS3) LastModified(key string) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	obj, ok := s.objects[key]
+	return obj.lastModified, ok
+}
+
+func (s *FakeS3) handle(w http.ResponseWriter, r *http.Request) {
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != s.bucket {
+		s3Error(w, r, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	switch {
+	case key == "" && r.Method == http.MethodHead:
+		w.WriteHeader(http.StatusOK)
+	case key == "" && r.Method == http.MethodGet:
+		s.list(w, r)
+	case key == "":
+		s3Error(w, r, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		s.copy(w, r, key)
+	case r.Method == http.MethodPut:
+		s.put(w, r, key)
+	case r.Method == http.MethodGet, r.Method == http.MethodHead:
+		s.get(w, r, key)
+	case r.Method == http.MethodDelete:
+		s.delete(w, r, key)
+	default:
+		s3Error(w, r, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+func (s *FakeS3) put(w http.ResponseWriter, r *http.Request, key string) {
+	data, err := readS3Body(r)
+	if err != nil {
+		s3Error(w, r, http.StatusBadRequest, "IncompleteBody")
+		return
+	}
+
+	sum := md5.Sum(data)
+	crc := binary.BigEndian.AppendUint32(nil, crc32.ChecksumIEEE(data))
+	obj := s3Object{
+		data:         data,
+		etag:         `"` + hex.EncodeToString(sum[:]) + `"`,
+		crc32:        base64.StdEncoding.EncodeToString(crc),
+		lastModified: time.Now().UTC().Truncate(time.Second),
+		tagging:      r.Header.Get("X-Amz-Tagging"),
+	}
+	for name, values := range r.Header {
+		if meta, ok := strings.CutPrefix(strings.ToLower(name), "x-amz-meta-"); ok {
+			if obj.metadata == nil {
+				obj.metadata = make(map[string]string)
+			}
+			obj.metadata[meta] = values[0]
+		}
+	}
+
+	s.mu.Lock()
+	s.objects[key] = obj
+	s.mu.Unlock()
+
+	w.Header().Set("ETag", obj.etag)
+	w.WriteHeader(http.StatusOK)
+}
+
+// copy answers CopyObject from an object in the same bucket, which may
+// be the destination itself
+func (s *FakeS3) copy(w http.ResponseWriter, r *http.Request, key string) {
+	source, err := url.PathUnescape(r.Header.Get("X-Amz-Copy-Source"))
+	if err != nil {
+		s3Error(w, r, http.StatusBadRequest, "InvalidArgument")
+		return
+	}
+	bucket, sourceKey, _ := strings.Cut(strings.TrimPrefix(source, "/"), "/")
+	if bucket != s.bucket {
+		s3Error(w, r, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	obj, ok := s.objects[sourceKey]
+	if !ok {
+		s3Error(w, r, http.StatusNotFound, "NoSuchKey")
+		return
+	}
+	obj.lastModified = time.Now().UTC().Truncate(time.Second)
+	if r.Header.Get("X-Amz-Metadata-Directive") == "REPLACE" {
+		obj.metadata = nil
+		for name, values := range r.Header {
+			if meta, ok := strings.CutPrefix(strings.ToLower(name), "x-amz-meta-"); ok {
+				if obj.metadata == nil {
+					obj.metadata = make(map[string]string)
+				}
+				obj.metadata[meta] = values[0]
+			}
+		}
+	}
+	s.objects[key] = obj
+
+	w.Header().Set("Content-Type", "application/xml")
+	fmt.Fprintf(w, "<CopyObjectResult><ETag>%s</ETag><LastModified>%s</LastModified></CopyObjectResult>",
+		obj.etag, obj.lastModified.Format(time.RFC3339))
+}
+
+func (s *FakeS3) get(w http.ResponseWriter, r *http.Request, key string) {
+	s.mu.Lock()
+	obj, ok := s.objects[key]
+	s.mu.Unlock()
+
+	if !ok {
+		s3Error(w, r, http.StatusNotFound, "NoSuchKey")
+		return
+	}
+
+	w.Header().Set("ETag", obj.etag)
+	w.Header().Set("X-Amz-Checksum-Crc32", obj.crc32)
+	w.Header().Set("Last-Modified", obj.lastModified.Format(http.TimeFormat))
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.Itoa(len(obj.data)))
+	for name, value := range obj.metadata {
+		w.Header().Set("X-Amz-Meta-"+name, value)
+	}
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodGet {
+		w.Write(obj.data)
+	}
+}
+
+func (s *FakeS3) delete(w http.ResponseWriter, r *http.Request, key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	obj, ok := s.objects[key]
+	if match := r.Header.Get("If-Match"); match != "" && (!ok || obj.etag != match) {
+		s3Error(w, r, http.StatusPreconditionFailed, "PreconditionFailed")
+		return
+	}
+
+	delete(s.objects, key)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// list answers ListObjectsV2 in a single page
+func (s *FakeS3) list(w http.ResponseWriter, r *http.Request) {
+	prefix := r.URL.Query().Get("prefix")
+
+	type object struct {
+		Key          string `xml:"Key"`
+		LastModified string `xml:"LastModified"`
+		ETag         string `xml:"ETag"`
+		Size         int    `xml:"Size"`
+	}
+	result := struct {
+		XMLName     xml.Name `xml:"ListBucketResult"`
+		Name        string   `xml:"Name"`
+		Prefix      string   `xml:"Prefix"`
+		KeyCount    int      `xml:"KeyCount"`
+		IsTruncated bool     `xml:"IsTruncated"`
+		Contents    []object `xml:"Contents"`
+	}{Name: s.bucket, Prefix: prefix}
+
+	s.mu.Lock()
+	for key, obj := range s.objects {
+		if strings.HasPrefix(key, prefix) {
+			result.Contents = append(result.Contents, object{
+				Key:          key,
+				LastModified: obj.lastModified.Format(time.RFC3339),
+				ETag:         obj.etag,
+				Size:         len(obj.data),
+			})
+		}
+	}
+	s.mu.Unlock()
+
+	sort.Slice(result.Contents, func(i, j int) bool {
+		return result.Contents[i].Key < result.Contents[j].Key
+	})
+	result.KeyCount = len(result.Contents)
+
+	w.Header().Set("Content-Type", "application/xml")
+	xml.NewEncoder(w).Encode(result)
+}
+
+// s3Error writes an S3 error response. HEAD responses carry no body, so
+// clients only see the status.
+func s3Error(w http.ResponseWriter, r *http.Request, status int, code string) {
+	if r.Method == http.MethodHead {
+		w.WriteHeader(status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, code)
+}
+
+// readS3Body reads an upload, decoding the aws-chunked encoding the SDK
+// uses for streamed payloads and trailing checksums
+func readS3Body(r *http.Request) ([]byte, error) {
+	if !strings.Contains(r.Header.Get("Content-Encoding"), "aws-chunked") &&
+		!strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return io.ReadAll(r.Body)
+	}
+
+	var data bytes.Buffer
+	br := bufio.NewReader(r.Body)
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		sizeHex, _, _ := strings.Cut(strings.TrimSpace(line), ";")
+		size, err := strconv.ParseInt(sizeHex, 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad chunk size %q", sizeHex)
+		}
+		if size == 0 {
+			// Trailers follow the last chunk
+			return data.Bytes(), nil
+		}
+		if _, err := io.CopyN(&data, br, size); err != nil {
+			return nil, err
+		}
+		if _, err := br.ReadString('\n'); err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,314 @@
+package backendtest
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mindmorass/yippity-clippity/internal/backend"
+	"github.com/mindmorass/yippity-clippity/internal/clipboard"
+)
+
+// concurrentWriters is how many backends write at the same time in the
+// concurrency check
+const concurrentWriters = 4
+
+// Opener returns a new, initialized backend client for a shared location.
+// Every call must return a separate client for the same location, as
+// separate devices would use.
+type Opener func() (backend.Backend, error)
+
+// Run checks that a backend implements the semantics the sync engine
+// relies on. setup is called once per check and must return an Opener
+// for a new, empty location.
+func Run(t *testing.T, setup func(t *testing.T) Opener) {
+	checks := []struct {
+		name  string
+		check func(t *testing.T, open Opener)
+	}{
+		{"RoundTrip", testRoundTrip},
+		{"ReadHeader", testReadHeader},
+		{"NotFound", testNotFound},
+		{"Exists", testExists},
+		{"ModTimeMonotonic", testModTimeMonotonic},
+		{"ChecksumChanges", testChecksumChanges},
+		{"SharedLocation", testSharedLocation},
+		{"ConcurrentWriters", testConcurrentWriters},
+	}
+
+	for _, c := range checks {
+		t.Run(c.name, func(t *testing.T) {
+			c.check(t, setup(t))
+		})
+	}
+}
+
+// NewClip returns a text clip with a fresh ID and a correct checksum
+func NewClip(machine, text string) *clipboard.Content {
+	data := []byte(text)
+	checksum := sha256.Sum256(data)
+	now := time.Now()
+
+	return &clipboard.Content{
+		ID:            fmt.Sprintf("%s-%d", machine, now.UnixNano()),
+		Timestamp:     now,
+		SourceMachine: machine,
+		SourceUser:    "conformance",
+		ContentType:   clipboard.ContentTypeText,
+		MimeType:      "text/plain",
+		Checksum:      hex.EncodeToString(checksum[:]),
+		Size:          int64(len(data)),
+		Data:          data,
+	}
+}
+
+// mustOpen opens a backend, closing it when the test ends
+func mustOpen(t *testing.T, open Opener) backend.Backend {
+	t.Helper()
+	b, err := open()
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	t.Cleanup(func() { b.Close() })
+	return b
+}
+
+func mustWrite(t *testing.T, b backend.Backend, content *clipboard.Content) {
+	t.Helper()
+	if err := b.Write(context.Background(), content); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+}
+
+// checkSame fails if got isn't the clip that was written
+func checkSame(t *testing.T, got, want *clipboard.Content) {
+	t.Helper()
+	if got == nil {
+		t.Fatalf("Read returned no clip, want %s", want.ID)
+	}
+	if got.ID != want.ID {
+		t.Errorf("ID = %q, want %q", got.ID, want.ID)
+	}
+	if got.Checksum != want.Checksum {
+		t.Errorf("Checksum = %q, want %q", got.Checksum, want.Checksum)
+	}
+	if !bytes.Equal(got.Data, want.Data) {
+		t.Errorf("Data = %q, want %q", got.Data, want.Data)
+	}
+	if got.ContentType != want.ContentType || got.MimeType != want.MimeType {
+		t.Errorf("type = %s %s, want %s %s", got.ContentType, got.MimeType, want.ContentType, want.MimeType)
+	}
+	if got.SourceMachine != want.SourceMachine {
+		t.Errorf("SourceMachine = %q, want %q", got.SourceMachine, want.SourceMachine)
+	}
+	if !got.Timestamp.Truncate(time.Millisecond).Equal(want.Timestamp.Truncate(time.Millisecond)) {
+		t.Errorf("Timestamp = %v, want %v", got.Timestamp, want.Timestamp)
+	}
+}
+
+func testRoundTrip(t *testing.T, open Opener) {
+	ctx := context.Background()
+	b := mustOpen(t, open)
+
+	for _, text := range []string{"first", "second, longer than the first"} {
+		clip := NewClip("device-a", text)
+		mustWrite(t, b, clip)
+
+		got, err := b.Read(ctx)
+		if err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+		checkSame(t, got, clip)
+	}
+}
+
+func testReadHeader(t *testing.T, open Opener) {
+	ctx := context.Background()
+	b := mustOpen(t, open)
+
+	reader, ok := b.(backend.HeaderReader)
+	if !ok {
+		t.Skip("backend can't read headers alone")
+	}
+	if got, err := reader.ReadHeader(ctx); err != nil || got != nil {
+		t.Fatalf("ReadHeader before the first write = %v, %v, want nil, nil", got, err)
+	}
+
+	clip := NewClip("device-a", "header only")
+	mustWrite(t, b, clip)
+
+	got, err := reader.ReadHeader(ctx)
+	if err != nil {
+		t.Fatalf("ReadHeader failed: %v", err)
+	}
+	if got.Data != nil {
+		t.Errorf("ReadHeader returned the payload %q", got.Data)
+	}
+	got.Data = clip.Data
+	checkSame(t, got, clip)
+	if got.Size != clip.Size {
+		t.Errorf("Size = %d, want %d", got.Size, clip.Size)
+	}
+}
+
+func testNotFound(t *testing.T, open Opener) {
+	ctx := context.Background()
+	b := mustOpen(t, open)
+
+	got, err := b.Read(ctx)
+	if err != nil || got != nil {
+		t.Errorf("Read = %v, %v, want nil, nil", got, err)
+	}
+	if _, err := b.GetModTime(ctx); !errors.Is(err, backend.ErrNotFound) {
+		t.Errorf("GetModTime error = %v, want ErrNotFound", err)
+	}
+	if _, err := b.GetChecksum(ctx); !errors.Is(err, backend.ErrNotFound) {
+		t.Errorf("GetChecksum error = %v, want ErrNotFound", err)
+	}
+}
+
+func testExists(t *testing.T, open Opener) {
+	ctx := context.Background()
+	b := mustOpen(t, open)
+
+	if b.Exists(ctx) {
+		t.Fatal("Exists = true before the first write")
+	}
+	mustWrite(t, b, NewClip("device-a", "hello"))
+	if !b.Exists(ctx) {
+		t.Fatal("Exists = false after a write")
+	}
+}
+
+func testModTimeMonotonic(t *testing.T, open Opener) {
+	ctx := context.Background()
+	b := mustOpen(t, open)
+
+	var last time.Time
+	for i := 0; i < 3; i++ {
+		mustWrite(t, b, NewClip("device-a", fmt.Sprintf("clip %d", i)))
+
+		modTime, err := b.GetModTime(ctx)
+		if err != nil {
+			t.Fatalf("GetModTime failed: %v", err)
+		}
+		if modTime.IsZero() {
+			t.Fatal("GetModTime returned the zero time")
+		}
+		if modTime.Before(last) {
+			t.Fatalf("GetModTime went backwards: %v after %v", modTime, last)
+		}
+		last = modTime
+	}
+}
+
+func testChecksumChanges(t *testing.T, open Opener) {
+	ctx := context.Background()
+	b := mustOpen(t, open)
+
+	mustWrite(t, b, NewClip("device-a", "one"))
+	first, err := b.GetChecksum(ctx)
+	if err != nil {
+		t.Fatalf("GetChecksum failed: %v", err)
+	}
+	again, err := b.GetChecksum(ctx)
+	if err != nil {
+		t.Fatalf("GetChecksum failed: %v", err)
+	}
+	if first != again {
+		t.Errorf("GetChecksum changed without a write: %q, %q", first, again)
+	}
+
+	mustWrite(t, b, NewClip("device-a", "two"))
+	second, err := b.GetChecksum(ctx)
+	if err != nil {
+		t.Fatalf("GetChecksum failed: %v", err)
+	}
+	if first == second {
+		t.Errorf("GetChecksum = %q after writing different content", second)
+	}
+}
+
+func testSharedLocation(t *testing.T, open Opener) {
+	ctx := context.Background()
+	writer := mustOpen(t, open)
+	reader := mustOpen(t, open)
+
+	clip := NewClip("device-a", "from another device")
+	mustWrite(t, writer, clip)
+
+	if !reader.Exists(ctx) {
+		t.Fatal("clip written by one client doesn't exist for another")
+	}
+	got, err := reader.Read(ctx)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	checkSame(t, got, clip)
+}
+
+func testConcurrentWriters(t *testing.T, open Opener) {
+	ctx := context.Background()
+
+	backends := make([]backend.Backend, concurrentWriters)
+	clips := make([]*clipboard.Content, concurrentWriters)
+	for i := range backends {
+		backends[i] = mustOpen(t, open)
+		clips[i] = NewClip(fmt.Sprintf("device-%d", i), fmt.Sprintf("clip from writer %d", i))
+	}
+
+	errs := make([]error, concurrentWriters)
+	var wg sync.WaitGroup
+	for i := range backends {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = backends[i].Write(ctx, clips[i])
+		}(i)
+	}
+	wg.Wait()
+
+	// Writers may lose the race, but the clip left behind must be one
+	// of the successful writes, intact
+	written := make(map[string]*clipboard.Content)
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			written[clips[i].ID] = clips[i]
+		case !lostRace(err):
+			t.Errorf("writer %d failed: %v", i, err)
+		}
+	}
+	if len(written) == 0 {
+		t.Fatal("no writer succeeded")
+	}
+
+	got, err := mustOpen(t, open).Read(ctx)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if got == nil {
+		t.Fatal("Read returned no clip after concurrent writes")
+	}
+	want, ok := written[got.ID]
+	if !ok {
+		t.Fatalf("Read returned clip %s, which no successful writer wrote", got.ID)
+	}
+	checkSame(t, got, want)
+}
+
+// lostRace reports whether a write failed only because another writer
+// got there first: an optimistic write conflict, or a lock held, lost or
+// superseded by another writer
+func lostRace(err error) bool {
+	return errors.Is(err, backend.ErrConflict) ||
+		errors.Is(err, backend.ErrLocked) ||
+		errors.Is(err, backend.ErrLockLost) ||
+		errors.Is(err, backend.ErrStaleFence)
+}
@@ -0,0 +1,41 @@
+package backend
+
+import (
+	"sync"
+	"time"
+)
+
+// BlobGCGrace is how old an unreferenced blob must be before it is
+// collected. A writer uploads the blob before publishing the manifest
+// that references it, so fresh blobs may still be about to be used.
+const BlobGCGrace = 10 * time.Minute
+
+// blobRefreshAge is how old an existing remote blob may be before a writer
+// refreshes it instead of reusing it as is, keeping it clear of collection
+const blobRefreshAge = BlobGCGrace / 2
+
+// blobCache remembers the most recently read or written payload, so an
+// unchanged manifest doesn't fetch its blob again
+type blobCache struct {
+	name string
+	data []byte
+	mu   sync.Mutex
+}
+
+// get returns the cached payload if it is the named blob
+func (c *blobCache) get(name string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.name == "" || c.name != name {
+		return nil, false
+	}
+	return c.data, true
+}
+
+// put caches the payload of the named blob
+func (c *blobCache) put(name string, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.name = name
+	c.data = data
+}
@@ -4,20 +4,27 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"path"
 	"strings"
 	"time"
 
 	"github.com/mindmorass/yippity-clippity/internal/clipboard"
+	"github.com/mindmorass/yippity-clippity/internal/metrics"
+	"github.com/mindmorass/yippity-clippity/internal/secrets"
 	"github.com/mindmorass/yippity-clippity/internal/storage"
 	"golang.org/x/oauth2"
 )
 
 const (
-	// DropboxFilePath is the file path in Dropbox
-	DropboxFilePath = "/Apps/YippityClippity/current.clip"
+	// DropboxDefaultFolder is the default sync folder in Dropbox
+	DropboxDefaultFolder = "/Apps/YippityClippity"
+
+	// DropboxPathRootTeam selects the team space root namespace
+	DropboxPathRootTeam = "team"
 
 	// Dropbox API endpoints
 	dropboxContentAPI  = "https://content.dropboxapi.com/2"
@@ -25,14 +32,23 @@ const (
 	dropboxAuthURL     = "https://www.dropbox.com/oauth2/authorize"
 	dropboxTokenURL    = "https://api.dropboxapi.com/oauth2/token"
 
-	// KeychainService is the service name for storing tokens
-	KeychainService = "com.yippityclippity.dropbox"
+	// DropboxSecretService is the secret store service name for Dropbox credentials
+	DropboxSecretService = "com.yippityclippity.dropbox"
+
+	// DropboxAppSecretAccount is the secret store account for the app secret
+	DropboxAppSecretAccount = "app_secret"
+
+	// dropboxTokensAccount is the secret store account for OAuth tokens
+	dropboxTokensAccount = "tokens"
 )
 
 // DropboxBackend implements Backend for Dropbox storage
 type DropboxBackend struct {
 	appKey       string
 	appSecret    string
+	folder       string
+	pathRoot     string
+	rootHeader   string
 	accessToken  string
 	refreshToken string
 	tokenExpiry  time.Time
@@ -40,6 +56,16 @@ type DropboxBackend struct {
 	lastHash     string
 	httpClient   *http.Client
 	oauthConfig  *oauth2.Config
+	secrets      secrets.Store
+	format       storage.Options
+	blobs        blobCache
+
+	// API and OAuth endpoints (overridable for testing)
+	apiURL       string
+	contentURL   string
+	authURL      string
+	tokenURL     string
+	redirectAddr string
 }
 
 // NewDropboxBackend creates a new Dropbox backend
@@ -47,31 +73,92 @@ func NewDropboxBackend(appKey, appSecret string) *DropboxBackend {
 	return &DropboxBackend{
 		appKey:    appKey,
 		appSecret: appSecret,
+		folder:    DropboxDefaultFolder,
+		format:    storage.DefaultOptions(),
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		apiURL:       dropboxAPI,
+		contentURL:   dropboxContentAPI,
+		authURL:      dropboxAuthURL,
+		tokenURL:     dropboxTokenURL,
+		redirectAddr: DropboxRedirectAddr,
 	}
 }
 
+// SetAPIEndpoints points the backend at another Dropbox API server, such
+// as a fake one in tests. Both URLs include the API version, e.g.
+// "https://api.dropboxapi.com/2".
+func (b *DropboxBackend) SetAPIEndpoints(apiURL, contentURL string) {
+	b.apiURL = strings.TrimSuffix(apiURL, "/")
+	b.contentURL = strings.TrimSuffix(contentURL, "/")
+}
+
 // Type returns the backend type
 func (b *DropboxBackend) Type() BackendType {
 	return BackendDropbox
 }
 
-// GetLocation returns "dropbox" as the location identifier
+// GetLocation returns the sync folder as dropbox:/path
 func (b *DropboxBackend) GetLocation() string {
 	if b.accessToken == "" {
 		return ""
 	}
-	return "dropbox:" + DropboxFilePath
+	return "dropbox:" + b.folder
 }
 
-// SetLocation is not used for Dropbox (path is fixed)
+// SetLocation sets the sync folder within Dropbox
+// Accepts format: dropbox:/path or just /path. An empty location
+// resets to the default folder.
 func (b *DropboxBackend) SetLocation(location string) error {
-	// For Dropbox, we use a fixed path
+	location = strings.TrimPrefix(location, "dropbox:")
+	if location == "" {
+		b.folder = DropboxDefaultFolder
+		return nil
+	}
+
+	if !strings.HasPrefix(location, "/") {
+		return fmt.Errorf("Dropbox path must start with /: %s", location)
+	}
+
+	b.folder = path.Clean(location)
 	return nil
 }
 
+// SetPathRoot selects the namespace paths are resolved against.
+// Empty uses the user's home namespace, DropboxPathRootTeam uses the
+// team space root, and any other value is taken as a namespace ID
+// (e.g. a team folder).
+func (b *DropboxBackend) SetPathRoot(pathRoot string) {
+	b.pathRoot = pathRoot
+	b.rootHeader = ""
+}
+
+// GetPathRoot returns the configured path root
+func (b *DropboxBackend) GetPathRoot() string {
+	return b.pathRoot
+}
+
+// SetFormat sets the .clip format options used for writes
+func (b *DropboxBackend) SetFormat(opts storage.Options) {
+	b.format = opts
+}
+
+// filePath returns the full Dropbox path of the clipboard file
+func (b *DropboxBackend) filePath() string {
+	return path.Join(b.folder, CurrentFile)
+}
+
+// blobDir returns the Dropbox folder holding blobs
+func (b *DropboxBackend) blobDir() string {
+	return path.Join(b.folder, storage.BlobDir)
+}
+
+// blobPath returns the full Dropbox path of a blob
+func (b *DropboxBackend) blobPath(name string) string {
+	return path.Join(b.blobDir(), name)
+}
+
 // Init initializes the Dropbox backend
 func (b *DropboxBackend) Init(ctx context.Context) error {
 	if b.appKey == "" {
@@ -79,19 +166,16 @@ func (b *DropboxBackend) Init(ctx context.Context) error {
 	}
 
 	// Initialize OAuth config
-	b.oauthConfig = &oauth2.Config{
-		ClientID:     b.appKey,
-		ClientSecret: b.appSecret,
-		Endpoint: oauth2.Endpoint{
-			AuthURL:  dropboxAuthURL,
-			TokenURL: dropboxTokenURL,
-		},
-	}
+	b.loadAppSecret()
+	b.oauthConfig = b.newOAuthConfig()
 
-	// Try to load tokens from keychain
-	if err := b.loadTokensFromKeychain(); err != nil {
-		// Tokens not found - will need OAuth flow
-		return fmt.Errorf("Dropbox not authenticated: %w", err)
+	// Try to load tokens from the secret store, unless they were set
+	// directly
+	if b.accessToken == "" {
+		if err := b.loadTokens(); err != nil {
+			// Tokens not found - will need OAuth flow
+			return fmt.Errorf("Dropbox not authenticated: %w", err)
+		}
 	}
 
 	// Refresh token if expired
@@ -101,6 +185,16 @@ func (b *DropboxBackend) Init(ctx context.Context) error {
 		}
 	}
 
+	// Resolve the namespace for team spaces
+	if err := b.resolvePathRoot(ctx); err != nil {
+		return fmt.Errorf("failed to resolve Dropbox path root: %w", err)
+	}
+
+	// Make sure the sync folder exists
+	if err := b.ensureFolder(ctx); err != nil {
+		return fmt.Errorf("failed to prepare Dropbox folder %s: %w", b.folder, err)
+	}
+
 	return nil
 }
 
@@ -110,20 +204,37 @@ func (b *DropboxBackend) Close() error {
 }
 
 // Write stores clipboard content to Dropbox
-func (b *DropboxBackend) Write(ctx context.Context, content *clipboard.Content) error {
+func (b *DropboxBackend) Write(ctx context.Context, content *clipboard.Content) (err error) {
+	defer observe(BackendDropbox, metrics.OpWrite, time.Now(), &err)
+
 	if b.accessToken == "" {
 		return ErrNotConfigured
 	}
 
-	// Encode content
-	data, err := storage.Encode(content)
-	if err != nil {
-		return fmt.Errorf("encode failed: %w", err)
+	// Encode content. With blobs, the payload is uploaded first and only
+	// the manifest goes into the clipboard file.
+	var body io.ReadSeeker
+	var size int64
+	if storage.UsesBlobs(b.format) {
+		if err := b.writeBlob(ctx, content); err != nil {
+			return err
+		}
+		manifest, err := storage.EncodeManifest(content)
+		if err != nil {
+			return fmt.Errorf("encode failed: %w", err)
+		}
+		body, size = bytes.NewReader(manifest), int64(len(manifest))
+	} else {
+		var err error
+		body, size, err = storage.EncodeReader(content, b.format)
+		if err != nil {
+			return fmt.Errorf("encode failed: %w", err)
+		}
 	}
 
 	// Prepare upload args
 	args := map[string]interface{}{
-		"path":       DropboxFilePath,
+		"path":       b.filePath(),
 		"mode":       "overwrite",
 		"autorename": false,
 		"mute":       true,
@@ -143,13 +254,14 @@ func (b *DropboxBackend) Write(ctx context.Context, content *clipboard.Content)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST",
-		dropboxContentAPI+"/files/upload",
-		bytes.NewReader(data))
+		b.contentURL+"/files/upload",
+		body)
 	if err != nil {
 		return err
 	}
+	req.ContentLength = size
 
-	req.Header.Set("Authorization", "Bearer "+b.accessToken)
+	b.setHeaders(req)
 	req.Header.Set("Content-Type", "application/octet-stream")
 	req.Header.Set("Dropbox-API-Arg", string(argsJSON))
 
@@ -159,15 +271,12 @@ func (b *DropboxBackend) Write(ctx context.Context, content *clipboard.Content)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode == 409 {
-		// Conflict - another client modified the file
-		return ErrConflict
-	}
-
 	if resp.StatusCode != 200 {
+		// A conflict means another client modified the file
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("upload failed with status %d: %s", resp.StatusCode, string(body))
+		return dropboxStatusError("upload", resp.StatusCode, body)
 	}
+	sent(BackendDropbox, size)
 
 	// Parse response to get new rev
 	var uploadResp struct {
@@ -185,40 +294,74 @@ func (b *DropboxBackend) Write(ctx context.Context, content *clipboard.Content)
 }
 
 // Read retrieves clipboard content from Dropbox
-func (b *DropboxBackend) Read(ctx context.Context) (*clipboard.Content, error) {
-	if b.accessToken == "" {
-		return nil, ErrNotConfigured
+func (b *DropboxBackend) Read(ctx context.Context) (_ *clipboard.Content, err error) {
+	defer observe(BackendDropbox, metrics.OpRead, time.Now(), &err)
+
+	body, err := b.openClip(ctx)
+	if body == nil || err != nil {
+		return nil, err
 	}
+	defer body.Close()
 
-	args := map[string]string{
-		"path": DropboxFilePath,
+	return b.decodeClip(ctx, body)
+}
+
+// ReadHeader reads the current clip from Dropbox without its payload. Only
+// the header is downloaded; the rest of the clipboard file is left unread.
+func (b *DropboxBackend) ReadHeader(ctx context.Context) (_ *clipboard.Content, err error) {
+	defer observe(BackendDropbox, metrics.OpReadHeader, time.Now(), &err)
+
+	body, err := b.openClip(ctx)
+	if body == nil || err != nil {
+		return nil, err
 	}
-	argsJSON, _ := json.Marshal(args)
+	defer body.Close()
 
-	req, err := http.NewRequestWithContext(ctx, "POST",
-		dropboxContentAPI+"/files/download",
-		nil)
+	header, _, err := storage.ReadHeader(body)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("decode failed: %w", err)
 	}
+	content, err := headerContent(header)
+	if err != nil {
+		return nil, fmt.Errorf("decode failed: %w", err)
+	}
+	return content, nil
+}
 
-	req.Header.Set("Authorization", "Bearer "+b.accessToken)
-	req.Header.Set("Dropbox-API-Arg", string(argsJSON))
+// ReadRaw returns the stored bytes of the clipboard file, or nil if there
+// is none
+func (b *DropboxBackend) ReadRaw(ctx context.Context) ([]byte, error) {
+	body, err := b.openClip(ctx)
+	if body == nil || err != nil {
+		return nil, err
+	}
+	defer body.Close()
 
-	resp, err := b.httpClient.Do(req)
+	data, err := io.ReadAll(body)
 	if err != nil {
 		return nil, fmt.Errorf("download failed: %w", err)
 	}
-	defer resp.Body.Close()
+	return data, nil
+}
 
-	if resp.StatusCode == 409 {
-		// File not found
-		return nil, nil
+// DecodeRaw decodes stored clip bytes the way Read does
+func (b *DropboxBackend) DecodeRaw(ctx context.Context, data []byte) (*clipboard.Content, error) {
+	return b.decodeClip(ctx, bytes.NewReader(data))
+}
+
+// openClip starts downloading the clipboard file and remembers its
+// revision. It returns a nil body if there is no file.
+func (b *DropboxBackend) openClip(ctx context.Context) (io.ReadCloser, error) {
+	if b.accessToken == "" {
+		return nil, ErrNotConfigured
 	}
 
-	if resp.StatusCode != 200 {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("download failed with status %d: %s", resp.StatusCode, string(body))
+	resp, err := b.download(ctx, b.filePath())
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
 	}
 
 	// Get metadata from response header
@@ -233,13 +376,25 @@ func (b *DropboxBackend) Read(ctx context.Context) (*clipboard.Content, error) {
 			b.lastHash = meta.ContentHash
 		}
 	}
+	return receiving(BackendDropbox, resp.Body), nil
+}
 
-	data, err := io.ReadAll(resp.Body)
+// decodeClip decodes a stored clip, loading its blob if it has one
+func (b *DropboxBackend) decodeClip(ctx context.Context, r io.Reader) (*clipboard.Content, error) {
+	header, version, err := storage.ReadHeader(r)
 	if err != nil {
-		return nil, fmt.Errorf("read body failed: %w", err)
+		return nil, fmt.Errorf("decode failed: %w", err)
 	}
 
-	content, err := storage.Decode(data)
+	var content *clipboard.Content
+	switch {
+	case header.Placeholder:
+		content, err = storage.PlaceholderContent(header)
+	case header.Blob != "":
+		content, err = b.readBlob(ctx, header)
+	default:
+		content, err = storage.DecodePayload(r, header, version)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("decode failed: %w", err)
 	}
@@ -247,8 +402,399 @@ func (b *DropboxBackend) Read(ctx context.Context) (*clipboard.Content, error) {
 	return content, nil
 }
 
+// RemoveClip deletes the clipboard file if it still holds the clip with
+// id. The delete only applies to the revision that was checked; if the
+// file changed in between, Dropbox refuses it and ErrConflict is returned.
+func (b *DropboxBackend) RemoveClip(ctx context.Context, id string) (err error) {
+	defer observe(BackendDropbox, metrics.OpRemove, time.Now(), &err)
+
+	if b.accessToken == "" {
+		return ErrNotConfigured
+	}
+
+	resp, err := b.download(ctx, b.filePath())
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var meta dropboxMetadata
+	json.Unmarshal([]byte(resp.Header.Get("Dropbox-API-Result")), &meta)
+	header, _, err := storage.ReadHeader(receiving(BackendDropbox, resp.Body))
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("decode failed: %w", err)
+	}
+	if header.ID != id || meta.Rev == "" {
+		return ErrConflict
+	}
+
+	err = b.rpc(ctx, "/files/delete_v2", map[string]string{
+		"path":       b.filePath(),
+		"parent_rev": meta.Rev,
+	}, nil)
+	if errors.Is(err, ErrNotFound) {
+		// Deleted by another device in the meantime
+		return ErrConflict
+	}
+	return err
+}
+
+// download starts downloading a file. A missing file returns ErrNotFound;
+// the caller must close the response body otherwise.
+func (b *DropboxBackend) download(ctx context.Context, p string) (*http.Response, error) {
+	argsJSON, _ := json.Marshal(map[string]string{
+		"path": p,
+	})
+
+	req, err := http.NewRequestWithContext(ctx, "POST",
+		b.contentURL+"/files/download",
+		nil)
+	if err != nil {
+		return nil, err
+	}
+
+	b.setHeaders(req)
+	req.Header.Set("Dropbox-API-Arg", string(argsJSON))
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("download failed: %w", err)
+	}
+
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, dropboxStatusError("download", resp.StatusCode, body)
+	}
+
+	return resp, nil
+}
+
+// writeBlob uploads the payload of content under its checksum, unless a
+// blob with that name already exists and is recent or still referenced
+func (b *DropboxBackend) writeBlob(ctx context.Context, content *clipboard.Content) error {
+	name := content.Checksum
+	if !storage.ValidBlobName(name) {
+		return fmt.Errorf("invalid checksum: %q", name)
+	}
+
+	meta, err := b.getPathMetadata(ctx, b.blobPath(name))
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	if err == nil && b.canReuseBlob(ctx, name, meta.ServerModified) {
+		b.blobs.put(name, content.Data)
+		return nil
+	}
+
+	body, size, err := storage.EncodeBlobReader(content, b.format)
+	if err != nil {
+		return fmt.Errorf("encode failed: %w", err)
+	}
+
+	argsJSON, err := json.Marshal(map[string]interface{}{
+		"path":       b.blobPath(name),
+		"mode":       "overwrite",
+		"autorename": false,
+		"mute":       true,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST",
+		b.contentURL+"/files/upload",
+		body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+
+	b.setHeaders(req)
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Dropbox-API-Arg", string(argsJSON))
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("blob upload failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return dropboxStatusError("blob upload", resp.StatusCode, respBody)
+	}
+	sent(BackendDropbox, size)
+
+	b.blobs.put(name, content.Data)
+	return nil
+}
+
+// readBlob returns the content a manifest refers to, reusing the cached
+// payload if the manifest is unchanged
+func (b *DropboxBackend) readBlob(ctx context.Context, header *storage.FileHeader) (*clipboard.Content, error) {
+	data, ok := b.blobs.get(header.Blob)
+	if !ok {
+		resp, err := b.download(ctx, b.blobPath(header.Blob))
+		if err != nil {
+			return nil, fmt.Errorf("blob %s: %w", header.Blob, err)
+		}
+		defer resp.Body.Close()
+
+		data, err = storage.DecodeBlobFrom(receiving(BackendDropbox, resp.Body), header)
+		if err != nil {
+			return nil, err
+		}
+		b.blobs.put(header.Blob, data)
+	}
+
+	return storage.ContentFromHeader(header, data)
+}
+
+// CanFetch returns true if clips are stored as blobs, which placeholders
+// refer to
+func (b *DropboxBackend) CanFetch() bool {
+	return storage.UsesBlobs(b.format)
+}
+
+// Fetch downloads the payload blob of a placeholder clip
+func (b *DropboxBackend) Fetch(ctx context.Context, content *clipboard.Content) (_ *clipboard.Content, err error) {
+	defer observe(BackendDropbox, metrics.OpFetch, time.Now(), &err)
+
+	if b.accessToken == "" {
+		return nil, ErrNotConfigured
+	}
+
+	header := storage.ManifestHeader(content)
+	header.Placeholder = false
+	return b.readBlob(ctx, &header)
+}
+
+// CollectGarbage removes blobs the current manifest doesn't reference and
+// that are older than BlobGCGrace
+func (b *DropboxBackend) CollectGarbage(ctx context.Context) (_ int, err error) {
+	defer observe(BackendDropbox, metrics.OpCollect, time.Now(), &err)
+
+	if b.accessToken == "" {
+		return 0, ErrNotConfigured
+	}
+
+	// Never collect anything if the manifest can't be read
+	referenced, err := b.referencedBlob(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	entries, err := b.listFolder(ctx, b.blobDir())
+	if errors.Is(err, ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := time.Now().Add(-BlobGCGrace)
+	removed := 0
+	for _, entry := range entries {
+		if entry.Tag != "file" || entry.Name == referenced || !storage.ValidBlobName(entry.Name) {
+			continue
+		}
+		if entry.ServerModified.After(cutoff) {
+			continue
+		}
+
+		err := b.rpc(ctx, "/files/delete_v2", map[string]string{
+			"path": b.blobPath(entry.Name),
+		}, nil)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return removed, fmt.Errorf("delete blob failed: %w", err)
+		}
+		removed++
+	}
+
+	return removed, nil
+}
+
+// canReuseBlob reports whether an existing blob modified at modified can
+// be referenced without uploading it again. Dropbox can't refresh a file's
+// age in place, but collection spares the blob the manifest names, so an
+// old blob stays safe while the manifest is rewritten to reference it.
+func (b *DropboxBackend) canReuseBlob(ctx context.Context, name string, modified time.Time) bool {
+	if time.Since(modified) < blobRefreshAge {
+		return true
+	}
+	referenced, err := b.referencedBlob(ctx)
+	return err == nil && referenced == name
+}
+
+// referencedBlob returns the blob named by the current manifest, if any
+func (b *DropboxBackend) referencedBlob(ctx context.Context) (string, error) {
+	resp, err := b.download(ctx, b.filePath())
+	if errors.Is(err, ErrNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	header, _, err := storage.ReadHeader(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return header.Blob, nil
+}
+
+// storePath returns the full Dropbox path of an ObjectStore object
+func (b *DropboxBackend) storePath(name string) string {
+	return path.Join(b.folder, ObjectDir, name)
+}
+
+// PutObject stores a small named object in the sync folder
+func (b *DropboxBackend) PutObject(ctx context.Context, name string, data []byte) error {
+	if b.accessToken == "" {
+		return ErrNotConfigured
+	}
+	if err := checkObjectName(name); err != nil {
+		return err
+	}
+
+	argsJSON, err := json.Marshal(map[string]interface{}{
+		"path":       b.storePath(name),
+		"mode":       "overwrite",
+		"autorename": false,
+		"mute":       true,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST",
+		b.contentURL+"/files/upload",
+		bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	b.setHeaders(req)
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Dropbox-API-Arg", string(argsJSON))
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("upload failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(resp.Body)
+		return dropboxStatusError("upload", resp.StatusCode, body)
+	}
+	return nil
+}
+
+// GetObject reads a named object from the sync folder
+func (b *DropboxBackend) GetObject(ctx context.Context, name string) ([]byte, error) {
+	if b.accessToken == "" {
+		return nil, ErrNotConfigured
+	}
+	if err := checkObjectName(name); err != nil {
+		return nil, err
+	}
+
+	resp, err := b.download(ctx, b.storePath(name))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return io.ReadAll(resp.Body)
+}
+
+// DeleteObject removes a named object from the sync folder
+func (b *DropboxBackend) DeleteObject(ctx context.Context, name string) error {
+	if b.accessToken == "" {
+		return ErrNotConfigured
+	}
+	if err := checkObjectName(name); err != nil {
+		return err
+	}
+
+	err := b.rpc(ctx, "/files/delete_v2", map[string]string{
+		"path": b.storePath(name),
+	}, nil)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// listFolder returns all entries of a folder, following the cursor
+func (b *DropboxBackend) listFolder(ctx context.Context, p string) ([]dropboxMetadata, error) {
+	var page struct {
+		Entries []dropboxMetadata `json:"entries"`
+		Cursor  string            `json:"cursor"`
+		HasMore bool              `json:"has_more"`
+	}
+
+	if err := b.rpc(ctx, "/files/list_folder", map[string]string{"path": p}, &page); err != nil {
+		return nil, err
+	}
+	entries := page.Entries
+
+	for page.HasMore {
+		cursor := page.Cursor
+		page.Entries = nil
+		if err := b.rpc(ctx, "/files/list_folder/continue", map[string]string{"cursor": cursor}, &page); err != nil {
+			return nil, err
+		}
+		entries = append(entries, page.Entries...)
+	}
+
+	return entries, nil
+}
+
+// rpc calls a Dropbox RPC endpoint with JSON arguments, decoding the
+// result into out if it is not nil. A missing path returns ErrNotFound.
+func (b *DropboxBackend) rpc(ctx context.Context, endpoint string, args, out interface{}) error {
+	argsJSON, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST",
+		b.apiURL+endpoint,
+		bytes.NewReader(argsJSON))
+	if err != nil {
+		return err
+	}
+
+	b.setHeaders(req)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(resp.Body)
+		return dropboxStatusError(endpoint, resp.StatusCode, body)
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
 // GetModTime returns the last modification time from Dropbox metadata
-func (b *DropboxBackend) GetModTime(ctx context.Context) (time.Time, error) {
+func (b *DropboxBackend) GetModTime(ctx context.Context) (_ time.Time, err error) {
+	defer observe(BackendDropbox, metrics.OpModTime, time.Now(), &err)
+
 	meta, err := b.getMetadata(ctx)
 	if err != nil {
 		return time.Time{}, err
@@ -278,31 +824,38 @@ func (b *DropboxBackend) Exists(ctx context.Context) bool {
 
 // dropboxMetadata represents file metadata from Dropbox
 type dropboxMetadata struct {
+	Tag            string    `json:".tag"`
+	Name           string    `json:"name"`
 	Rev            string    `json:"rev"`
 	ContentHash    string    `json:"content_hash"`
 	ServerModified time.Time `json:"server_modified"`
 	Size           int64     `json:"size"`
 }
 
-// getMetadata retrieves file metadata from Dropbox
+// getMetadata retrieves clipboard file metadata from Dropbox
 func (b *DropboxBackend) getMetadata(ctx context.Context) (*dropboxMetadata, error) {
+	return b.getPathMetadata(ctx, b.filePath())
+}
+
+// getPathMetadata retrieves metadata for any path from Dropbox
+func (b *DropboxBackend) getPathMetadata(ctx context.Context, p string) (*dropboxMetadata, error) {
 	if b.accessToken == "" {
 		return nil, ErrNotConfigured
 	}
 
 	args := map[string]string{
-		"path": DropboxFilePath,
+		"path": p,
 	}
 	argsJSON, _ := json.Marshal(args)
 
 	req, err := http.NewRequestWithContext(ctx, "POST",
-		dropboxAPI+"/files/get_metadata",
+		b.apiURL+"/files/get_metadata",
 		bytes.NewReader(argsJSON))
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Set("Authorization", "Bearer "+b.accessToken)
+	b.setHeaders(req)
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := b.httpClient.Do(req)
@@ -311,13 +864,9 @@ func (b *DropboxBackend) getMetadata(ctx context.Context) (*dropboxMetadata, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode == 409 {
-		return nil, ErrNotFound
-	}
-
 	if resp.StatusCode != 200 {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("get_metadata failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, dropboxStatusError("get_metadata", resp.StatusCode, body)
 	}
 
 	var meta dropboxMetadata
@@ -328,17 +877,137 @@ func (b *DropboxBackend) getMetadata(ctx context.Context) (*dropboxMetadata, err
 	return &meta, nil
 }
 
+// setHeaders sets authorization and namespace headers on an API request
+func (b *DropboxBackend) setHeaders(req *http.Request) {
+	req.Header.Set("Authorization", "Bearer "+b.accessToken)
+	if b.rootHeader != "" {
+		req.Header.Set("Dropbox-API-Path-Root", b.rootHeader)
+	}
+}
+
+// resolvePathRoot builds the Dropbox-API-Path-Root header for the
+// configured path root, looking up the team space root if needed
+func (b *DropboxBackend) resolvePathRoot(ctx context.Context) error {
+	var root map[string]string
+
+	switch b.pathRoot {
+	case "":
+		b.rootHeader = ""
+		return nil
+	case DropboxPathRootTeam:
+		nsID, err := b.getRootNamespace(ctx)
+		if err != nil {
+			return err
+		}
+		root = map[string]string{".tag": "root", "root": nsID}
+	default:
+		root = map[string]string{".tag": "namespace_id", "namespace_id": b.pathRoot}
+	}
+
+	header, err := json.Marshal(root)
+	if err != nil {
+		return err
+	}
+	b.rootHeader = string(header)
+	return nil
+}
+
+// getRootNamespace returns the root namespace ID of the current account.
+// For members of a team space this is the team's root namespace.
+func (b *DropboxBackend) getRootNamespace(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST",
+		b.apiURL+"/users/get_current_account",
+		nil)
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+b.accessToken)
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(resp.Body)
+		return "", dropboxStatusError("get_current_account", resp.StatusCode, body)
+	}
+
+	var account struct {
+		RootInfo struct {
+			Tag             string `json:".tag"`
+			RootNamespaceID string `json:"root_namespace_id"`
+		} `json:"root_info"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&account); err != nil {
+		return "", err
+	}
+
+	if account.RootInfo.RootNamespaceID == "" {
+		return "", fmt.Errorf("account has no root namespace")
+	}
+
+	return account.RootInfo.RootNamespaceID, nil
+}
+
+// ensureFolder verifies the sync folder exists, creating it if needed
+func (b *DropboxBackend) ensureFolder(ctx context.Context) error {
+	if b.folder == "/" {
+		return nil
+	}
+
+	meta, err := b.getPathMetadata(ctx, b.folder)
+	if err == nil {
+		if meta.Tag != "folder" {
+			return fmt.Errorf("%s exists and is not a folder", b.folder)
+		}
+		return nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	args, _ := json.Marshal(map[string]interface{}{
+		"path":       b.folder,
+		"autorename": false,
+	})
+
+	req, err := http.NewRequestWithContext(ctx, "POST",
+		b.apiURL+"/files/create_folder_v2",
+		bytes.NewReader(args))
+	if err != nil {
+		return err
+	}
+
+	b.setHeaders(req)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+
+	// Another device may have created the folder in the meantime
+	if resp.StatusCode == 409 && dropboxErrorTag(body) == "path/conflict/folder" {
+		return nil
+	}
+
+	if resp.StatusCode != 200 {
+		return dropboxStatusError("create_folder", resp.StatusCode, body)
+	}
+
+	return nil
+}
+
 // GetAuthURL returns the OAuth authorization URL for user authentication
 func (b *DropboxBackend) GetAuthURL(state string) string {
 	if b.oauthConfig == nil {
-		b.oauthConfig = &oauth2.Config{
-			ClientID:     b.appKey,
-			ClientSecret: b.appSecret,
-			Endpoint: oauth2.Endpoint{
-				AuthURL:  dropboxAuthURL,
-				TokenURL: dropboxTokenURL,
-			},
-		}
+		b.oauthConfig = b.newOAuthConfig()
 	}
 
 	// Use PKCE for better security
@@ -349,6 +1018,10 @@ func (b *DropboxBackend) GetAuthURL(state string) string {
 
 // ExchangeCode exchanges an authorization code for tokens
 func (b *DropboxBackend) ExchangeCode(ctx context.Context, code string) error {
+	if b.oauthConfig == nil {
+		b.oauthConfig = b.newOAuthConfig()
+	}
+
 	token, err := b.oauthConfig.Exchange(ctx, code)
 	if err != nil {
 		return fmt.Errorf("failed to exchange code: %w", err)
@@ -358,8 +1031,8 @@ func (b *DropboxBackend) ExchangeCode(ctx context.Context, code string) error {
 	b.refreshToken = token.RefreshToken
 	b.tokenExpiry = token.Expiry
 
-	// Save tokens to keychain
-	return b.saveTokensToKeychain()
+	// Save tokens to the secret store
+	return b.saveTokens()
 }
 
 // SetTokens sets the OAuth tokens directly (for testing or migration)
@@ -374,6 +1047,28 @@ func (b *DropboxBackend) IsAuthenticated() bool {
 	return b.accessToken != ""
 }
 
+// dropboxStatusError describes a failed API call. Dropbox answers 401
+// once the access token has expired or was revoked, and 409 with an
+// endpoint-specific error: a missing path is ErrNotFound and a
+// conflicting file or revision is ErrConflict.
+func dropboxStatusError(what string, status int, body []byte) error {
+	if status == http.StatusUnauthorized {
+		return fmt.Errorf("%s failed: %w", what, ErrAuthExpired)
+	}
+	if status == http.StatusConflict {
+		if isDropboxNotFound(body) {
+			return ErrNotFound
+		}
+		if isDropboxConflict(body) {
+			return ErrConflict
+		}
+		if tag := dropboxErrorTag(body); tag != "" {
+			return fmt.Errorf("%s failed: %s", what, tag)
+		}
+	}
+	return fmt.Errorf("%s failed with status %d: %s", what, status, string(body))
+}
+
 // refreshAccessToken refreshes the access token using the refresh token
 func (b *DropboxBackend) refreshAccessToken(ctx context.Context) error {
 	if b.refreshToken == "" {
@@ -387,6 +1082,11 @@ func (b *DropboxBackend) refreshAccessToken(ctx context.Context) error {
 	tokenSource := b.oauthConfig.TokenSource(ctx, token)
 	newToken, err := tokenSource.Token()
 	if err != nil {
+		// A rejected refresh token means the user has to log in again
+		var retrieveErr *oauth2.RetrieveError
+		if errors.As(err, &retrieveErr) {
+			return fmt.Errorf("%w: %v", ErrAuthExpired, err)
+		}
 		return err
 	}
 
@@ -396,13 +1096,32 @@ func (b *DropboxBackend) refreshAccessToken(ctx context.Context) error {
 	}
 	b.tokenExpiry = newToken.Expiry
 
-	return b.saveTokensToKeychain()
+	return b.saveTokens()
+}
+
+// SetSecretStore sets where OAuth tokens and the app secret are kept
+func (b *DropboxBackend) SetSecretStore(store secrets.Store) {
+	b.secrets = store
 }
 
-// loadTokensFromKeychain loads OAuth tokens from the macOS keychain
-func (b *DropboxBackend) loadTokensFromKeychain() error {
-	// Try to load from keychain
-	item, err := loadFromKeychain(KeychainService, "tokens")
+// loadAppSecret reads the app secret from the secret store if it was not
+// configured directly. A missing secret is fine: login then uses PKCE only.
+func (b *DropboxBackend) loadAppSecret() {
+	if b.appSecret != "" || b.secrets == nil {
+		return
+	}
+	if secret, err := b.secrets.Get(DropboxSecretService, DropboxAppSecretAccount); err == nil {
+		b.appSecret = string(secret)
+	}
+}
+
+// loadTokens loads OAuth tokens from the secret store
+func (b *DropboxBackend) loadTokens() error {
+	if b.secrets == nil {
+		return ErrNoSecretStore
+	}
+
+	item, err := b.secrets.Get(DropboxSecretService, dropboxTokensAccount)
 	if err != nil {
 		return err
 	}
@@ -424,8 +1143,12 @@ func (b *DropboxBackend) loadTokensFromKeychain() error {
 	return nil
 }
 
-// saveTokensToKeychain saves OAuth tokens to the macOS keychain
-func (b *DropboxBackend) saveTokensToKeychain() error {
+// saveTokens saves OAuth tokens to the secret store
+func (b *DropboxBackend) saveTokens() error {
+	if b.secrets == nil {
+		return ErrNoSecretStore
+	}
+
 	tokens := struct {
 		AccessToken  string    `json:"access_token"`
 		RefreshToken string    `json:"refresh_token"`
@@ -441,7 +1164,7 @@ func (b *DropboxBackend) saveTokensToKeychain() error {
 		return err
 	}
 
-	return saveToKeychain(KeychainService, "tokens", data)
+	return b.secrets.Set(DropboxSecretService, dropboxTokensAccount, data)
 }
 
 // ClearTokens removes stored tokens (for logout)
@@ -449,21 +1172,39 @@ func (b *DropboxBackend) ClearTokens() error {
 	b.accessToken = ""
 	b.refreshToken = ""
 	b.tokenExpiry = time.Time{}
-	return deleteFromKeychain(KeychainService, "tokens")
+	if b.secrets == nil {
+		return nil
+	}
+	return b.secrets.Delete(DropboxSecretService, dropboxTokensAccount)
 }
 
-// Helper function to check for 409 conflict error path
-func isDropboxNotFound(body []byte) bool {
+// dropboxErrorTag returns the tag path of an endpoint error, such as
+// "path/not_found" or "path/conflict/folder". Dropbox reports it in
+// error_summary, followed by a trailing slash and optional detail.
+func dropboxErrorTag(body []byte) string {
 	var errResp struct {
-		Error struct {
-			Tag  string `json:".tag"`
-			Path struct {
-				Tag string `json:".tag"`
-			} `json:"path"`
-		} `json:"error"`
+		Summary string `json:"error_summary"`
 	}
-	if json.Unmarshal(body, &errResp) == nil {
-		return errResp.Error.Path.Tag == "not_found"
+	if json.Unmarshal(body, &errResp) != nil {
+		return ""
 	}
-	return strings.Contains(string(body), "not_found")
+	return strings.TrimRight(errResp.Summary, "./ ")
+}
+
+// dropboxErrorIs reports whether the second tag of an endpoint error is
+// reason, as in "path/not_found" or "path_lookup/not_found"
+func dropboxErrorIs(body []byte, reason string) bool {
+	parts := strings.Split(dropboxErrorTag(body), "/")
+	return len(parts) > 1 && parts[1] == reason
+}
+
+// isDropboxNotFound reports whether an endpoint error is a missing path
+func isDropboxNotFound(body []byte) bool {
+	return dropboxErrorIs(body, "not_found")
+}
+
+// isDropboxConflict reports whether an endpoint error is a conflicting
+// file or folder at the path
+func isDropboxConflict(body []byte) bool {
+	return dropboxErrorIs(body, "conflict")
 }
@@ -0,0 +1,160 @@
+package backend
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"net"
+	"net/http"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+const (
+	// DropboxRedirectAddr is the loopback address the login flow listens on.
+	// The matching redirect URI (http://127.0.0.1:53682/callback) must be
+	// registered in the Dropbox app console.
+	DropboxRedirectAddr = "127.0.0.1:53682"
+
+	// DropboxRedirectPath is the callback path for the OAuth redirect
+	DropboxRedirectPath = "/callback"
+
+	// DropboxLoginTimeout is how long to wait for the user to authorize
+	DropboxLoginTimeout = 5 * time.Minute
+)
+
+// URLOpener opens a URL for the user, typically in the default browser
+type URLOpener func(url string)
+
+// newOAuthConfig builds the OAuth config for the configured app.
+// Without an app secret the client authenticates with PKCE only, so
+// client_id must be sent in the request body.
+func (b *DropboxBackend) newOAuthConfig() *oauth2.Config {
+	cfg := &oauth2.Config{
+		ClientID:     b.appKey,
+		ClientSecret: b.appSecret,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  b.authURL,
+			TokenURL: b.tokenURL,
+		},
+	}
+	if b.appSecret == "" {
+		cfg.Endpoint.AuthStyle = oauth2.AuthStyleInParams
+	}
+	return cfg
+}
+
+// Login runs the OAuth authorization code flow with PKCE.
+// It starts a temporary HTTP listener on the loopback interface, opens the
+// authorization URL, waits for the redirect carrying the code, exchanges it
+// for tokens and persists them. No app secret is required.
+func (b *DropboxBackend) Login(ctx context.Context, open URLOpener) error {
+	if b.appKey == "" {
+		return fmt.Errorf("Dropbox app key not configured")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, DropboxLoginTimeout)
+	defer cancel()
+
+	ln, err := net.Listen("tcp", b.redirectAddr)
+	if err != nil {
+		return fmt.Errorf("failed to start callback listener: %w", err)
+	}
+
+	b.loadAppSecret()
+	cfg := b.newOAuthConfig()
+	cfg.RedirectURL = "http://" + ln.Addr().String() + DropboxRedirectPath
+
+	state, err := randomState()
+	if err != nil {
+		ln.Close()
+		return err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	type callbackResult struct {
+		code string
+		err  error
+	}
+	results := make(chan callbackResult, 1)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(DropboxRedirectPath, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		var res callbackResult
+		switch {
+		case query.Get("state") != state:
+			res.err = errors.New("OAuth state mismatch")
+		case query.Get("error") != "":
+			res.err = fmt.Errorf("authorization denied: %s %s", query.Get("error"), query.Get("error_description"))
+		case query.Get("code") == "":
+			res.err = errors.New("no authorization code in callback")
+		default:
+			res.code = query.Get("code")
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if res.err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "<p>Dropbox login failed: %s</p>", html.EscapeString(res.err.Error()))
+		} else {
+			fmt.Fprint(w, "<p>Yippity-Clippity is connected to Dropbox. You can close this window.</p>")
+		}
+
+		select {
+		case results <- res:
+		default:
+		}
+	})
+
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go server.Serve(ln)
+	defer server.Close()
+
+	authURL := cfg.AuthCodeURL(state,
+		oauth2.S256ChallengeOption(verifier),
+		oauth2.SetAuthURLParam("token_access_type", "offline"),
+	)
+	if open != nil {
+		open(authURL)
+	}
+
+	var res callbackResult
+	select {
+	case res = <-results:
+	case <-ctx.Done():
+		return fmt.Errorf("Dropbox login timed out: %w", ctx.Err())
+	}
+	if res.err != nil {
+		return res.err
+	}
+
+	token, err := cfg.Exchange(ctx, res.code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return fmt.Errorf("failed to exchange code: %w", err)
+	}
+
+	b.oauthConfig = cfg
+	b.accessToken = token.AccessToken
+	b.refreshToken = token.RefreshToken
+	b.tokenExpiry = token.Expiry
+
+	return b.saveTokens()
+}
+
+// randomState returns an unguessable OAuth state parameter
+func randomState() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate state: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,215 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/mindmorass/yippity-clippity/internal/secrets"
+)
+
+// fakeTokenEndpoint is a Dropbox OAuth token endpoint that accepts one
+// authorization code
+type fakeTokenEndpoint struct {
+	code   string
+	status int
+	form   url.Values
+}
+
+func (f *fakeTokenEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f.form = r.PostForm
+
+	w.Header().Set("Content-Type", "application/json")
+	if f.status != 0 {
+		w.WriteHeader(f.status)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error":             "server_error",
+			"error_description": "token endpoint unavailable",
+		})
+		return
+	}
+	if r.PostForm.Get("code") != f.code {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]any{
+		"access_token":  "access-1",
+		"refresh_token": "refresh-1",
+		"token_type":    "bearer",
+		"expires_in":    14400,
+	})
+}
+
+// newTestDropboxAuth returns a backend whose OAuth endpoints and secret
+// store are local to the test
+func newTestDropboxAuth(t *testing.T, token http.Handler) (*DropboxBackend, secrets.Store) {
+	t.Helper()
+
+	server := httptest.NewServer(token)
+	t.Cleanup(server.Close)
+
+	store := secrets.NewMemoryStore()
+	b := NewDropboxBackend("app-key", "")
+	b.authURL = "https://dropbox.invalid/oauth2/authorize"
+	b.tokenURL = server.URL
+	b.redirectAddr = "127.0.0.1:0"
+	b.SetSecretStore(store)
+	return b, store
+}
+
+// redirect plays the browser: it follows the authorization URL's
+// redirect_uri back to the login listener with the given query
+// parameters. A missing state is copied from the authorization URL.
+func redirect(t *testing.T, params url.Values) URLOpener {
+	return func(authURL string) {
+		u, err := url.Parse(authURL)
+		if err != nil {
+			t.Errorf("bad authorization URL: %v", err)
+			return
+		}
+		query := u.Query()
+		if query.Get("code_challenge") == "" || query.Get("code_challenge_method") != "S256" {
+			t.Errorf("authorization URL has no PKCE challenge: %s", authURL)
+		}
+
+		if !params.Has("state") {
+			params.Set("state", query.Get("state"))
+		}
+		callback := query.Get("redirect_uri") + "?" + params.Encode()
+
+		go func() {
+			resp, err := http.Get(callback)
+			if err != nil {
+				t.Errorf("callback request failed: %v", err)
+				return
+			}
+			resp.Body.Close()
+		}()
+	}
+}
+
+func TestDropboxLogin(t *testing.T) {
+	endpoint := &fakeTokenEndpoint{code: "code-1"}
+	b, store := newTestDropboxAuth(t, endpoint)
+
+	err := b.Login(context.Background(), redirect(t, url.Values{"code": {"code-1"}}))
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	if endpoint.form.Get("code_verifier") == "" {
+		t.Error("token request has no PKCE verifier")
+	}
+	if got := endpoint.form.Get("client_id"); got != "app-key" {
+		t.Errorf("token request client_id = %q, want app-key", got)
+	}
+	if !b.IsAuthenticated() {
+		t.Error("backend not authenticated after login")
+	}
+
+	saved, err := store.Get(DropboxSecretService, dropboxTokensAccount)
+	if err != nil {
+		t.Fatalf("tokens not saved: %v", err)
+	}
+	if !strings.Contains(string(saved), "refresh-1") {
+		t.Errorf("saved tokens = %s, want refresh token", saved)
+	}
+}
+
+func TestDropboxLoginStateMismatch(t *testing.T) {
+	endpoint := &fakeTokenEndpoint{code: "code-1"}
+	b, store := newTestDropboxAuth(t, endpoint)
+
+	params := url.Values{"code": {"code-1"}, "state": {"forged"}}
+	err := b.Login(context.Background(), redirect(t, params))
+	if err == nil || !strings.Contains(err.Error(), "state mismatch") {
+		t.Fatalf("Login error = %v, want state mismatch", err)
+	}
+	if endpoint.form != nil {
+		t.Error("code exchanged despite state mismatch")
+	}
+	if _, err := store.Get(DropboxSecretService, dropboxTokensAccount); err == nil {
+		t.Error("tokens saved despite state mismatch")
+	}
+}
+
+func TestDropboxLoginDenied(t *testing.T) {
+	endpoint := &fakeTokenEndpoint{code: "code-1"}
+	b, _ := newTestDropboxAuth(t, endpoint)
+
+	params := url.Values{
+		"error":             {"access_denied"},
+		"error_description": {"The user chose not to give your app access"},
+	}
+	err := b.Login(context.Background(), redirect(t, params))
+	if err == nil || !strings.Contains(err.Error(), "access_denied") {
+		t.Fatalf("Login error = %v, want access_denied", err)
+	}
+	if endpoint.form != nil {
+		t.Error("token endpoint called after the user denied access")
+	}
+	if b.IsAuthenticated() {
+		t.Error("backend authenticated after the user denied access")
+	}
+}
+
+func TestDropboxLoginTokenError(t *testing.T) {
+	endpoint := &fakeTokenEndpoint{code: "code-1", status: http.StatusInternalServerError}
+	b, store := newTestDropboxAuth(t, endpoint)
+
+	err := b.Login(context.Background(), redirect(t, url.Values{"code": {"code-1"}}))
+	if err == nil || !strings.Contains(err.Error(), "failed to exchange code") {
+		t.Fatalf("Login error = %v, want exchange failure", err)
+	}
+	if b.IsAuthenticated() {
+		t.Error("backend authenticated after a failed exchange")
+	}
+	if _, err := store.Get(DropboxSecretService, dropboxTokensAccount); err == nil {
+		t.Error("tokens saved after a failed exchange")
+	}
+}
+
+func TestDropboxExchangeCode(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		code    string
+		wantErr bool
+	}{
+		{name: "success", code: "code-1"},
+		{name: "wrong code", code: "code-2", wantErr: true},
+		{name: "token endpoint error", code: "code-1", status: http.StatusServiceUnavailable, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			endpoint := &fakeTokenEndpoint{code: "code-1", status: tt.status}
+			b, store := newTestDropboxAuth(t, endpoint)
+
+			err := b.ExchangeCode(context.Background(), tt.code)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExchangeCode error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			_, saveErr := store.Get(DropboxSecretService, dropboxTokensAccount)
+			if tt.wantErr {
+				if b.IsAuthenticated() || saveErr == nil {
+					t.Error("tokens kept after a failed exchange")
+				}
+				return
+			}
+			if !b.IsAuthenticated() || saveErr != nil {
+				t.Errorf("tokens not kept after exchange (save error %v)", saveErr)
+			}
+		})
+	}
+}
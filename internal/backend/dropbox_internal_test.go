@@ -0,0 +1,71 @@
+package backend
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestDropboxStatusError(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   error
+	}{
+		{
+			name:   "path not found",
+			status: http.StatusConflict,
+			body:   `{"error_summary": "path/not_found/..", "error": {".tag": "path", "path": {".tag": "not_found"}}}`,
+			want:   ErrNotFound,
+		},
+		{
+			name:   "lookup not found",
+			status: http.StatusConflict,
+			body:   `{"error_summary": "path_lookup/not_found/", "error": {".tag": "path_lookup", "path_lookup": {".tag": "not_found"}}}`,
+			want:   ErrNotFound,
+		},
+		{
+			name:   "write conflict",
+			status: http.StatusConflict,
+			body:   `{"error_summary": "path/conflict/file/...", "error": {".tag": "path", "reason": {".tag": "conflict"}}}`,
+			want:   ErrConflict,
+		},
+		{
+			name:   "insufficient space",
+			status: http.StatusConflict,
+			body:   `{"error_summary": "path/insufficient_space/", "error": {".tag": "path", "reason": {".tag": "insufficient_space"}}}`,
+		},
+		{
+			name:   "not a file",
+			status: http.StatusConflict,
+			body:   `{"error_summary": "path/not_file/.", "error": {".tag": "path", "path": {".tag": "not_file"}}}`,
+		},
+		{
+			name:   "unreadable body",
+			status: http.StatusConflict,
+			body:   `not_found`,
+		},
+		{
+			name:   "expired token",
+			status: http.StatusUnauthorized,
+			body:   `{"error_summary": "expired_access_token/"}`,
+			want:   ErrAuthExpired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := dropboxStatusError("call", tt.status, []byte(tt.body))
+			if err == nil {
+				t.Fatal("no error")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+			if tt.want == nil && (errors.Is(err, ErrNotFound) || errors.Is(err, ErrConflict)) {
+				t.Errorf("error = %v, want a plain failure", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package backend_test
+
+import (
+	"context"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/mindmorass/yippity-clippity/internal/backend"
+	"github.com/mindmorass/yippity-clippity/internal/backend/backendtest"
+	"github.com/mindmorass/yippity-clippity/internal/clipboard"
+	"github.com/mindmorass/yippity-clippity/internal/metrics"
+	"github.com/mindmorass/yippity-clippity/internal/storage"
+)
+
+func TestDropboxConformance(t *testing.T) {
+	backendtest.Run(t, func(t *testing.T) backendtest.Opener {
+		return backendtest.NewFakeDropbox(t, "token").Opener("/yippity-clippity")
+	})
+}
+
+func TestDropboxWriteReusesOldReferencedBlob(t *testing.T) {
+	ctx := context.Background()
+	fake := backendtest.NewFakeDropbox(t, "token")
+	b, err := fake.Opener("/yippity-clippity")()
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	b.(*backend.DropboxBackend).SetFormat(storage.Options{Version: storage.BlobVersion})
+	clip := backendtest.NewClip("device-a", strings.Repeat("a payload worth storing once ", 512))
+	other := backendtest.NewClip("device-a", "something else")
+	blob := path.Join("/yippity-clippity", storage.BlobDir, clip.Checksum)
+
+	// write stores clip once its blob is close to collection and returns
+	// the bytes sent
+	write := func(clip *clipboard.Content) float64 {
+		t.Helper()
+		fake.Age(blob, backend.BlobGCGrace)
+		before := backendBytes(t, backend.BackendDropbox, metrics.Sent)
+		if err := b.Write(ctx, clip); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		return backendBytes(t, backend.BackendDropbox, metrics.Sent) - before
+	}
+
+	first := write(clip)
+
+	// The manifest still names the old blob, so it is reused
+	if again := write(clip); again <= 0 || again >= first {
+		t.Errorf("bytes sent = %v then %v, want the referenced blob reused", first, again)
+	}
+
+	// Once another clip replaced it, the old blob is uploaded again
+	write(other)
+	if again := write(clip); again < first {
+		t.Errorf("bytes sent = %v then %v, want the unreferenced blob uploaded again", first, again)
+	}
+}
@@ -2,6 +2,8 @@ package backend
 
 import (
 	"fmt"
+
+	"github.com/mindmorass/yippity-clippity/internal/storage"
 )
 
 // New creates a new backend based on the configuration
@@ -10,17 +12,46 @@ func New(cfg *Config) (Backend, error) {
 		cfg = &Config{Type: BackendLocal}
 	}
 
+	format := cfg.Format
+	if format.Version == 0 {
+		format = storage.DefaultOptions()
+	}
+
+	b, err := newBackend(cfg, format)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Faults.Enabled() {
+		return NewFaultyBackend(b, cfg.Faults), nil
+	}
+	return b, nil
+}
+
+// newBackend creates the backend for cfg.Type
+func newBackend(cfg *Config, format storage.Options) (Backend, error) {
 	switch cfg.Type {
 	case BackendLocal, "":
 		b := NewLocalBackend(cfg.Location)
+		b.SetStateDir(cfg.StateDir)
+		b.SetFormat(format)
 		return b, nil
 
 	case BackendS3:
 		b := NewS3Backend(cfg.S3Bucket, cfg.S3Prefix, cfg.S3Region)
+		b.SetTagExpiring(cfg.S3TagExpiring)
+		b.SetSecretStore(cfg.Secrets)
+		b.SetFormat(format)
 		return b, nil
 
 	case BackendDropbox:
 		b := NewDropboxBackend(cfg.DropboxAppKey, cfg.DropboxAppSecret)
+		if err := b.SetLocation(cfg.DropboxPath); err != nil {
+			return nil, err
+		}
+		b.SetPathRoot(cfg.DropboxPathRoot)
+		b.SetSecretStore(cfg.Secrets)
+		b.SetFormat(format)
 		return b, nil
 
 	default:
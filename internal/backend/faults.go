@@ -0,0 +1,429 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/mindmorass/yippity-clippity/internal/clipboard"
+)
+
+// ErrInjected is returned by calls a FaultyBackend made fail
+var ErrInjected = errors.New("injected backend fault")
+
+// Faults configures what a FaultyBackend injects. Rates are probabilities
+// from 0 to 1, drawn independently for every call.
+type Faults struct {
+	// Latency is added to every call, plus a random delay up to Jitter
+	Latency time.Duration
+	Jitter  time.Duration
+
+	// ErrorRate makes calls fail with ErrInjected, as on a flaky network
+	ErrorRate float64
+
+	// TornReadRate makes Read see a clip torn by a concurrent write:
+	// either cut short, or with its tail overwritten. Only backends that
+	// implement RawReader can be torn.
+	TornReadRate float64
+
+	// StaleModTimeRate makes GetModTime return the modification time it
+	// reported before the latest change, as a caching server might
+	StaleModTimeRate float64
+
+	// LockContentionRate makes writes fail with ErrLocked, as if another
+	// device held the lock
+	LockContentionRate float64
+
+	// Seed makes the injected faults repeatable. Zero picks a random seed.
+	Seed uint64
+}
+
+// Enabled returns true if any fault is configured
+func (f Faults) Enabled() bool {
+	return f.Latency > 0 || f.Jitter > 0 || f.ErrorRate > 0 || f.TornReadRate > 0 ||
+		f.StaleModTimeRate > 0 || f.LockContentionRate > 0
+}
+
+// FaultyBackend wraps a Backend and injects failures into its calls, to
+// reproduce flaky networks and storage. Init, Close and the location
+// methods are passed through unchanged. The wrapper is never Watchable,
+// so the sync engine polls and every check goes through the faults.
+type FaultyBackend struct {
+	inner  Backend
+	faults Faults
+
+	rand         *rand.Rand
+	modTime      time.Time
+	staleModTime time.Time
+	mu           sync.Mutex
+}
+
+// NewFaultyBackend wraps inner with the given faults. The wrapper
+// implements the optional interfaces inner implements, and no others.
+func NewFaultyBackend(inner Backend, faults Faults) Backend {
+	seed := faults.Seed
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+	b := &FaultyBackend{
+		inner:  inner,
+		faults: faults,
+		rand:   rand.New(rand.NewPCG(seed, seed)),
+	}
+	return b.withCapabilities()
+}
+
+// Unwrap returns the backend underneath any fault injection wrapper
+func Unwrap(b Backend) Backend {
+	if faulty, ok := b.(interface{ unwrap() *FaultyBackend }); ok {
+		return faulty.unwrap().inner
+	}
+	return b
+}
+
+func (b *FaultyBackend) unwrap() *FaultyBackend {
+	return b
+}
+
+// SetFaults replaces the injected faults
+func (b *FaultyBackend) SetFaults(faults Faults) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.faults = faults
+}
+
+// chance returns true with the given probability
+func (b *FaultyBackend) chance(rate func(Faults) float64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	p := rate(b.faults)
+	return p > 0 && b.rand.Float64() < p
+}
+
+// inject delays a call and decides whether it fails
+func (b *FaultyBackend) inject(ctx context.Context) error {
+	b.mu.Lock()
+	delay := b.faults.Latency
+	if b.faults.Jitter > 0 {
+		delay += time.Duration(b.rand.Int64N(int64(b.faults.Jitter)))
+	}
+	b.mu.Unlock()
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if b.chance(func(f Faults) float64 { return f.ErrorRate }) {
+		return ErrInjected
+	}
+	return nil
+}
+
+// Write stores clipboard content, unless the lock is contended
+func (b *FaultyBackend) Write(ctx context.Context, content *clipboard.Content) error {
+	if err := b.inject(ctx); err != nil {
+		return err
+	}
+	if b.chance(func(f Faults) float64 { return f.LockContentionRate }) {
+		return ErrLocked
+	}
+	return b.inner.Write(ctx, content)
+}
+
+// Read retrieves clipboard content, possibly torn
+func (b *FaultyBackend) Read(ctx context.Context) (*clipboard.Content, error) {
+	if err := b.inject(ctx); err != nil {
+		return nil, err
+	}
+	raw, ok := b.inner.(RawReader)
+	if ok && b.chance(func(f Faults) float64 { return f.TornReadRate }) {
+		return b.tornRead(ctx, raw)
+	}
+	return b.inner.Read(ctx)
+}
+
+// tornRead reads the stored clip as a reader racing a write would see it
+// and decodes it the way the wrapped backend's Read does
+func (b *FaultyBackend) tornRead(ctx context.Context, raw RawReader) (*clipboard.Content, error) {
+	data, err := raw.ReadRaw(ctx)
+	if err != nil || len(data) == 0 {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	cut := b.rand.IntN(2) == 0
+	at := b.rand.IntN(len(data))
+	b.mu.Unlock()
+
+	if cut {
+		// The reader caught the file half written
+		data = data[:at]
+	} else {
+		// The tail was overwritten mid-read
+		data[len(data)-1] ^= 0xff
+	}
+	return raw.DecodeRaw(ctx, data)
+}
+
+// GetModTime returns the last modification time, sometimes a stale one
+func (b *FaultyBackend) GetModTime(ctx context.Context) (time.Time, error) {
+	if err := b.inject(ctx); err != nil {
+		return time.Time{}, err
+	}
+	modTime, err := b.inner.GetModTime(ctx)
+	if err != nil {
+		return modTime, err
+	}
+
+	b.mu.Lock()
+	if !modTime.Equal(b.modTime) {
+		b.staleModTime, b.modTime = b.modTime, modTime
+	}
+	stale := b.staleModTime
+	b.mu.Unlock()
+
+	if !stale.IsZero() && b.chance(func(f Faults) float64 { return f.StaleModTimeRate }) {
+		return stale, nil
+	}
+	return modTime, nil
+}
+
+// GetChecksum returns a lightweight checksum for change detection
+func (b *FaultyBackend) GetChecksum(ctx context.Context) (string, error) {
+	if err := b.inject(ctx); err != nil {
+		return "", err
+	}
+	return b.inner.GetChecksum(ctx)
+}
+
+// Exists returns true if clipboard data exists. Failed calls report
+// false.
+func (b *FaultyBackend) Exists(ctx context.Context) bool {
+	if err := b.inject(ctx); err != nil {
+		return false
+	}
+	return b.inner.Exists(ctx)
+}
+
+// Init initializes the wrapped backend
+func (b *FaultyBackend) Init(ctx context.Context) error {
+	return b.inner.Init(ctx)
+}
+
+// Close closes the wrapped backend
+func (b *FaultyBackend) Close() error {
+	return b.inner.Close()
+}
+
+// Type returns the wrapped backend's type
+func (b *FaultyBackend) Type() BackendType {
+	return b.inner.Type()
+}
+
+// GetLocation returns the wrapped backend's location
+func (b *FaultyBackend) GetLocation() string {
+	return b.inner.GetLocation()
+}
+
+// SetLocation updates the wrapped backend's location
+func (b *FaultyBackend) SetLocation(location string) error {
+	return b.inner.SetLocation(location)
+}
+
+// Capabilities of the wrapped backend that the wrapper forwards
+const (
+	canRemove = 1 << iota
+	canCollect
+	canFetch
+	canStoreObjects
+)
+
+// withCapabilities returns b with the optional interfaces of the wrapped
+// backend, so type assertions on the wrapper answer as they would on the
+// backend itself
+func (b *FaultyBackend) withCapabilities() Backend {
+	var caps int
+	if _, ok := b.inner.(Remover); ok {
+		caps |= canRemove
+	}
+	if _, ok := b.inner.(Collector); ok {
+		caps |= canCollect
+	}
+	if _, ok := b.inner.(Fetcher); ok {
+		caps |= canFetch
+	}
+	if _, ok := b.inner.(ObjectStore); ok {
+		caps |= canStoreObjects
+	}
+
+	r, c, f, o := faultyRemover{b}, faultyCollector{b}, faultyFetcher{b}, faultyObjectStore{b}
+	switch caps {
+	case canRemove:
+		return struct {
+			*FaultyBackend
+			faultyRemover
+		}{b, r}
+	case canCollect:
+		return struct {
+			*FaultyBackend
+			faultyCollector
+		}{b, c}
+	case canRemove | canCollect:
+		return struct {
+			*FaultyBackend
+			faultyRemover
+			faultyCollector
+		}{b, r, c}
+	case canFetch:
+		return struct {
+			*FaultyBackend
+			faultyFetcher
+		}{b, f}
+	case canRemove | canFetch:
+		return struct {
+			*FaultyBackend
+			faultyRemover
+			faultyFetcher
+		}{b, r, f}
+	case canCollect | canFetch:
+		return struct {
+			*FaultyBackend
+			faultyCollector
+			faultyFetcher
+		}{b, c, f}
+	case canRemove | canCollect | canFetch:
+		return struct {
+			*FaultyBackend
+			faultyRemover
+			faultyCollector
+			faultyFetcher
+		}{b, r, c, f}
+	case canStoreObjects:
+		return struct {
+			*FaultyBackend
+			faultyObjectStore
+		}{b, o}
+	case canRemove | canStoreObjects:
+		return struct {
+			*FaultyBackend
+			faultyRemover
+			faultyObjectStore
+		}{b, r, o}
+	case canCollect | canStoreObjects:
+		return struct {
+			*FaultyBackend
+			faultyCollector
+			faultyObjectStore
+		}{b, c, o}
+	case canRemove | canCollect | canStoreObjects:
+		return struct {
+			*FaultyBackend
+			faultyRemover
+			faultyCollector
+			faultyObjectStore
+		}{b, r, c, o}
+	case canFetch | canStoreObjects:
+		return struct {
+			*FaultyBackend
+			faultyFetcher
+			faultyObjectStore
+		}{b, f, o}
+	case canRemove | canFetch | canStoreObjects:
+		return struct {
+			*FaultyBackend
+			faultyRemover
+			faultyFetcher
+			faultyObjectStore
+		}{b, r, f, o}
+	case canCollect | canFetch | canStoreObjects:
+		return struct {
+			*FaultyBackend
+			faultyCollector
+			faultyFetcher
+			faultyObjectStore
+		}{b, c, f, o}
+	case canRemove | canCollect | canFetch | canStoreObjects:
+		return struct {
+			*FaultyBackend
+			faultyRemover
+			faultyCollector
+			faultyFetcher
+			faultyObjectStore
+		}{b, r, c, f, o}
+	default:
+		return b
+	}
+}
+
+// faultyRemover forwards RemoveClip to a wrapped Remover
+type faultyRemover struct{ b *FaultyBackend }
+
+// RemoveClip deletes the current clip
+func (r faultyRemover) RemoveClip(ctx context.Context, id string) error {
+	if err := r.b.inject(ctx); err != nil {
+		return err
+	}
+	return r.b.inner.(Remover).RemoveClip(ctx, id)
+}
+
+// faultyCollector forwards CollectGarbage to a wrapped Collector
+type faultyCollector struct{ b *FaultyBackend }
+
+// CollectGarbage removes unreferenced blobs
+func (c faultyCollector) CollectGarbage(ctx context.Context) (int, error) {
+	if err := c.b.inject(ctx); err != nil {
+		return 0, err
+	}
+	return c.b.inner.(Collector).CollectGarbage(ctx)
+}
+
+// faultyFetcher forwards placeholder fetches to a wrapped Fetcher
+type faultyFetcher struct{ b *FaultyBackend }
+
+// CanFetch returns true if the wrapped backend can fetch placeholders
+func (f faultyFetcher) CanFetch() bool {
+	return f.b.inner.(Fetcher).CanFetch()
+}
+
+// Fetch downloads the payload of a placeholder clip
+func (f faultyFetcher) Fetch(ctx context.Context, content *clipboard.Content) (*clipboard.Content, error) {
+	if err := f.b.inject(ctx); err != nil {
+		return nil, err
+	}
+	return f.b.inner.(Fetcher).Fetch(ctx, content)
+}
+
+// faultyObjectStore forwards object calls to a wrapped ObjectStore
+type faultyObjectStore struct{ b *FaultyBackend }
+
+// PutObject creates or replaces an object
+func (o faultyObjectStore) PutObject(ctx context.Context, name string, data []byte) error {
+	if err := o.b.inject(ctx); err != nil {
+		return err
+	}
+	return o.b.inner.(ObjectStore).PutObject(ctx, name, data)
+}
+
+// GetObject returns an object
+func (o faultyObjectStore) GetObject(ctx context.Context, name string) ([]byte, error) {
+	if err := o.b.inject(ctx); err != nil {
+		return nil, err
+	}
+	return o.b.inner.(ObjectStore).GetObject(ctx, name)
+}
+
+// DeleteObject removes an object
+func (o faultyObjectStore) DeleteObject(ctx context.Context, name string) error {
+	if err := o.b.inject(ctx); err != nil {
+		return err
+	}
+	return o.b.inner.(ObjectStore).DeleteObject(ctx, name)
+}
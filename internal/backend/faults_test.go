@@ -0,0 +1,70 @@
+package backend
+
+import (
+	"context"
+	"testing"
+)
+
+// plainBackend has only the methods every backend has
+type plainBackend struct {
+	Backend
+}
+
+func TestFaultyBackendCapabilities(t *testing.T) {
+	faults := Faults{ErrorRate: 0.5, Seed: 1}
+
+	plain := NewFaultyBackend(plainBackend{}, faults)
+	if _, ok := plain.(Remover); ok {
+		t.Error("wrapper is a Remover around a backend that isn't")
+	}
+	if _, ok := plain.(Collector); ok {
+		t.Error("wrapper is a Collector around a backend that isn't")
+	}
+	if _, ok := plain.(Fetcher); ok {
+		t.Error("wrapper is a Fetcher around a backend that isn't")
+	}
+	if _, ok := plain.(ObjectStore); ok {
+		t.Error("wrapper is an ObjectStore around a backend that isn't")
+	}
+
+	local := NewLocalBackend(t.TempDir())
+	full := NewFaultyBackend(local, faults)
+	if _, ok := full.(Remover); !ok {
+		t.Error("wrapper around a local backend isn't a Remover")
+	}
+	if _, ok := full.(Collector); !ok {
+		t.Error("wrapper around a local backend isn't a Collector")
+	}
+	if _, ok := full.(Fetcher); !ok {
+		t.Error("wrapper around a local backend isn't a Fetcher")
+	}
+	if _, ok := full.(ObjectStore); !ok {
+		t.Error("wrapper around a local backend isn't an ObjectStore")
+	}
+	if _, ok := full.(Watchable); ok {
+		t.Error("wrapper is Watchable")
+	}
+	if Unwrap(full) != local {
+		t.Error("Unwrap doesn't return the wrapped backend")
+	}
+}
+
+func TestFaultyBackendTornRead(t *testing.T) {
+	ctx := context.Background()
+	local := newTestLocal(t, t.TempDir(), "")
+	if err := local.Write(ctx, newTestClip("a", "a clip long enough to be torn anywhere")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	faulty := NewFaultyBackend(local, Faults{TornReadRate: 1, Seed: 1})
+	for i := 0; i < 20; i++ {
+		if content, err := faulty.Read(ctx); err == nil {
+			t.Fatalf("torn Read returned clip %s", content.ID)
+		}
+	}
+
+	// Tearing happens in the reader, the stored clip is intact
+	if content, err := local.Read(ctx); err != nil || content.ID != "a" {
+		t.Errorf("Read = %v, %v; want clip a", content, err)
+	}
+}
@@ -0,0 +1,38 @@
+//go:build darwin
+
+package backend
+
+import (
+	"syscall"
+)
+
+// networkFSTypes are macOS filesystem types that don't deliver reliable
+// change notifications for writes made by other machines
+var networkFSTypes = map[string]bool{
+	"smbfs":  true,
+	"nfs":    true,
+	"afpfs":  true,
+	"webdav": true,
+	"cifs":   true,
+	"ftp":    true,
+}
+
+// filesystemType returns the filesystem type name and whether it is a
+// network filesystem
+func filesystemType(path string) (string, bool, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return "", false, err
+	}
+
+	buf := make([]byte, 0, len(stat.Fstypename))
+	for _, c := range stat.Fstypename {
+		if c == 0 {
+			break
+		}
+		buf = append(buf, byte(c))
+	}
+	name := string(buf)
+
+	return name, networkFSTypes[name], nil
+}
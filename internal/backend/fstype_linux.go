@@ -0,0 +1,67 @@
+//go:build linux
+
+package backend
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// Filesystem magic numbers from statfs(2)
+const (
+	nfsSuperMagic   = 0x6969
+	smbSuperMagic   = 0x517b
+	smb2SuperMagic  = 0xfe534d42
+	cifsSuperMagic  = 0xff534d42
+	fuseSuperMagic  = 0x65735546
+	codaSuperMagic  = 0x73757245
+	afsSuperMagic   = 0x5346414f
+	cephSuperMagic  = 0x00c36400
+	v9fsSuperMagic  = 0x01021997
+	ncpSuperMagic   = 0x564c
+	ext4SuperMagic  = 0xef53
+	btrfsSuperMagic = 0x9123683e
+	xfsSuperMagic   = 0x58465342
+	tmpfsMagic      = 0x01021994
+)
+
+// networkFSTypes are filesystems that don't deliver reliable change
+// notifications for writes made by other machines. FUSE is included
+// because it commonly backs sshfs and cloud mounts.
+var networkFSTypes = map[uint32]string{
+	nfsSuperMagic:  "nfs",
+	smbSuperMagic:  "smb",
+	smb2SuperMagic: "smb2",
+	cifsSuperMagic: "cifs",
+	fuseSuperMagic: "fuse",
+	codaSuperMagic: "coda",
+	afsSuperMagic:  "afs",
+	cephSuperMagic: "ceph",
+	v9fsSuperMagic: "9p",
+	ncpSuperMagic:  "ncp",
+}
+
+var localFSTypes = map[uint32]string{
+	ext4SuperMagic:  "ext4",
+	btrfsSuperMagic: "btrfs",
+	xfsSuperMagic:   "xfs",
+	tmpfsMagic:      "tmpfs",
+}
+
+// filesystemType returns the filesystem type name and whether it is a
+// network filesystem
+func filesystemType(path string) (string, bool, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return "", false, err
+	}
+
+	magic := uint32(stat.Type)
+	if name, ok := networkFSTypes[magic]; ok {
+		return name, true, nil
+	}
+	if name, ok := localFSTypes[magic]; ok {
+		return name, false, nil
+	}
+	return fmt.Sprintf("0x%x", magic), false, nil
+}
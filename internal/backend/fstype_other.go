@@ -0,0 +1,9 @@
+//go:build !darwin && !linux
+
+package backend
+
+// filesystemType can't tell local from network filesystems on this
+// platform, so it reports everything as network to keep polling
+func filesystemType(path string) (string, bool, error) {
+	return "unknown", true, nil
+}
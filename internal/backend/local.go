@@ -1,14 +1,21 @@
 package backend
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/mindmorass/yippity-clippity/internal/clipboard"
+	"github.com/mindmorass/yippity-clippity/internal/metrics"
 	"github.com/mindmorass/yippity-clippity/internal/storage"
 )
 
@@ -22,7 +29,7 @@ const (
 	// LockFile is the filename for the write lock
 	LockFile = "current.clip.lock"
 
-	// LockTimeout is how long a lock is valid
+	// LockTimeout is how long a lock lease is valid unless renewed
 	LockTimeout = 10 * time.Second
 
 	// FilePermissions for clipboard files
@@ -36,18 +43,37 @@ const (
 type LockInfo struct {
 	Holder     string    `json:"holder"`
 	PID        int       `json:"pid"`
+	Instance   uint64    `json:"instance,omitempty"`
+	Token      uint64    `json:"token"`
 	AcquiredAt time.Time `json:"acquired_at"`
 	ExpiresAt  time.Time `json:"expires_at"`
 }
 
 // LocalBackend implements Backend for local filesystem storage
 type LocalBackend struct {
-	basePath string
+	basePath     string
+	stateDir     string
+	format       storage.Options
+	highestFence uint64
+	fenceLoaded  string
+	blobs        blobCache
+	instance     uint64
+	lockTimeout  time.Duration
+	mu           sync.Mutex
 }
 
+// localInstances numbers the local backends in this process, so a lock
+// held by one is never taken for another's
+var localInstances atomic.Uint64
+
 // NewLocalBackend creates a new local filesystem backend
 func NewLocalBackend(basePath string) *LocalBackend {
-	return &LocalBackend{basePath: basePath}
+	return &LocalBackend{
+		basePath:    basePath,
+		format:      storage.DefaultOptions(),
+		instance:    localInstances.Add(1),
+		lockTimeout: LockTimeout,
+	}
 }
 
 // Type returns the backend type
@@ -84,6 +110,21 @@ func (b *LocalBackend) SetLocation(location string) error {
 	return nil
 }
 
+// SetFormat sets the .clip format options used for writes
+func (b *LocalBackend) SetFormat(opts storage.Options) {
+	b.format = opts
+}
+
+// SetStateDir sets the directory for state kept on this device, such as
+// the highest fencing token seen. Without one, that state is lost on
+// restart.
+func (b *LocalBackend) SetStateDir(dir string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.stateDir = dir
+	b.fenceLoaded = ""
+}
+
 // syncDir returns the full path to the sync directory
 func (b *LocalBackend) syncDir() string {
 	return filepath.Join(b.basePath, DirName)
@@ -94,6 +135,16 @@ func (b *LocalBackend) clipPath() string {
 	return filepath.Join(b.syncDir(), CurrentFile)
 }
 
+// blobDir returns the full path to the blob directory
+func (b *LocalBackend) blobDir() string {
+	return filepath.Join(b.syncDir(), storage.BlobDir)
+}
+
+// blobPath returns the full path to a blob
+func (b *LocalBackend) blobPath(name string) string {
+	return filepath.Join(b.blobDir(), name)
+}
+
 // lockPath returns the full path to the lock file
 func (b *LocalBackend) lockPath() string {
 	return filepath.Join(b.syncDir(), LockFile)
@@ -127,7 +178,9 @@ func (b *LocalBackend) Close() error {
 }
 
 // Write stores clipboard content to the shared location
-func (b *LocalBackend) Write(ctx context.Context, content *clipboard.Content) error {
+func (b *LocalBackend) Write(ctx context.Context, content *clipboard.Content) (err error) {
+	defer observe(BackendLocal, metrics.OpWrite, time.Now(), &err)
+
 	if b.basePath == "" {
 		return ErrNotConfigured
 	}
@@ -136,22 +189,53 @@ func (b *LocalBackend) Write(ctx context.Context, content *clipboard.Content) er
 		return err
 	}
 
-	// Try to acquire lock
-	if err := b.acquireLock(); err != nil {
-		return err
+	// Store the payload before the manifest that references it
+	if storage.UsesBlobs(b.format) {
+		if err := b.writeBlob(content); err != nil {
+			return err
+		}
 	}
-	defer b.releaseLock()
 
-	// Encode content using shared format
-	data, err := storage.Encode(content)
+	// Try to acquire lock; the lease is renewed while the write runs
+	l, err := b.acquireLock()
 	if err != nil {
-		return fmt.Errorf("encode failed: %w", err)
+		return err
+	}
+	defer l.release()
+
+	// A newer writer has already been seen, our token would be rejected
+	if !b.observeFenceToken(l.Token()) {
+		return ErrStaleFence
 	}
 
-	// Write to temp file first (atomic write)
+	// Stamp the clip with our fencing token
+	fenced := *content
+	fenced.FenceToken = l.Token()
+
+	// Stream the encoded clip to a temp file first (atomic write)
 	tempPath := b.clipPath() + ".tmp"
-	if err := os.WriteFile(tempPath, data, FilePermissions); err != nil {
-		return fmt.Errorf("write temp file failed: %w", err)
+	if err := b.writeTemp(tempPath, &fenced); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+
+	return b.publish(l, tempPath)
+}
+
+// publish moves a fully written temp file into place, unless the lease
+// it was written under is no longer current
+func (b *LocalBackend) publish(l *lease, tempPath string) error {
+	// Don't publish if the lease ran out or was taken over during the write
+	if !l.Verify() {
+		os.Remove(tempPath)
+		return ErrLockLost
+	}
+
+	// Nor if a newer token was claimed in the meantime, e.g. by a writer
+	// that found our lease expired while we were paused
+	if b.latestFenceToken() > l.Token() {
+		os.Remove(tempPath)
+		return ErrStaleFence
 	}
 
 	// Rename to final location (atomic on POSIX)
@@ -164,7 +248,63 @@ func (b *LocalBackend) Write(ctx context.Context, content *clipboard.Content) er
 }
 
 // Read retrieves clipboard content from the shared location
-func (b *LocalBackend) Read(ctx context.Context) (*clipboard.Content, error) {
+func (b *LocalBackend) Read(ctx context.Context) (_ *clipboard.Content, err error) {
+	defer observe(BackendLocal, metrics.OpRead, time.Now(), &err)
+
+	if b.basePath == "" {
+		return nil, ErrNotConfigured
+	}
+
+	f, err := os.Open(b.clipPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read failed: %w", err)
+	}
+	defer f.Close()
+
+	return b.decodeClip(bufio.NewReader(receiving(BackendLocal, f)))
+}
+
+// ReadHeader reads the current clip from the shared location without its
+// payload
+func (b *LocalBackend) ReadHeader(ctx context.Context) (_ *clipboard.Content, err error) {
+	defer observe(BackendLocal, metrics.OpReadHeader, time.Now(), &err)
+
+	if b.basePath == "" {
+		return nil, ErrNotConfigured
+	}
+
+	f, err := os.Open(b.clipPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read failed: %w", err)
+	}
+	defer f.Close()
+
+	header, _, err := storage.ReadHeader(receiving(BackendLocal, f))
+	if err != nil {
+		return nil, fmt.Errorf("decode failed: %w", err)
+	}
+	content, err := headerContent(header)
+	if err != nil {
+		return nil, fmt.Errorf("decode failed: %w", err)
+	}
+
+	// Reject clips from a writer whose lease was superseded
+	if !b.observeFenceToken(content.FenceToken) {
+		return nil, ErrStaleFence
+	}
+
+	return content, nil
+}
+
+// ReadRaw returns the stored bytes of the current clip, or nil if there
+// is none
+func (b *LocalBackend) ReadRaw(ctx context.Context) ([]byte, error) {
 	if b.basePath == "" {
 		return nil, ErrNotConfigured
 	}
@@ -176,19 +316,108 @@ func (b *LocalBackend) Read(ctx context.Context) (*clipboard.Content, error) {
 		}
 		return nil, fmt.Errorf("read failed: %w", err)
 	}
+	received(BackendLocal, int64(len(data)))
+	return data, nil
+}
+
+// DecodeRaw decodes stored clip bytes the way Read does
+func (b *LocalBackend) DecodeRaw(ctx context.Context, data []byte) (*clipboard.Content, error) {
+	return b.decodeClip(bufio.NewReader(bytes.NewReader(data)))
+}
+
+// decodeClip decodes a stored clip, loading its blob if it has one, and
+// rejects clips from a writer whose lease was superseded
+func (b *LocalBackend) decodeClip(r *bufio.Reader) (*clipboard.Content, error) {
+	header, version, err := storage.ReadHeader(r)
+	if err != nil {
+		return nil, fmt.Errorf("decode failed: %w", err)
+	}
 
-	content, err := storage.Decode(data)
+	var content *clipboard.Content
+	switch {
+	case header.Placeholder:
+		content, err = storage.PlaceholderContent(header)
+	case header.Blob != "":
+		content, err = b.readBlob(header)
+	default:
+		content, err = storage.DecodePayload(r, header, version)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("decode failed: %w", err)
 	}
 
+	// Reject clips from a writer whose lease was superseded
+	if !b.observeFenceToken(content.FenceToken) {
+		return nil, ErrStaleFence
+	}
+
 	return content, nil
 }
 
+// RemoveClip deletes the current clip if it is still the clip with id
+func (b *LocalBackend) RemoveClip(ctx context.Context, id string) (err error) {
+	defer observe(BackendLocal, metrics.OpRemove, time.Now(), &err)
+
+	if b.basePath == "" {
+		return ErrNotConfigured
+	}
+
+	l, err := b.acquireLock()
+	if err != nil {
+		return err
+	}
+	defer l.release()
+
+	f, err := os.Open(b.clipPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	header, _, err := storage.ReadHeader(bufio.NewReader(receiving(BackendLocal, f)))
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("decode failed: %w", err)
+	}
+	if header.ID != id {
+		return ErrConflict
+	}
+
+	if !l.Verify() {
+		return ErrLockLost
+	}
+	if err := os.Remove(b.clipPath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// WatchDir returns the sync directory and whether fsnotify can be relied
+// on for it. Network filesystems (SMB, NFS, AFP) don't report changes
+// made by other machines, so those must be polled.
+func (b *LocalBackend) WatchDir() (string, bool) {
+	if b.basePath == "" {
+		return "", false
+	}
+
+	_, network, err := filesystemType(b.basePath)
+	if err != nil {
+		return "", false
+	}
+
+	return b.syncDir(), !network
+}
+
 // GetModTime returns the modification time of the clipboard file
-func (b *LocalBackend) GetModTime(ctx context.Context) (time.Time, error) {
+func (b *LocalBackend) GetModTime(ctx context.Context) (_ time.Time, err error) {
+	defer observe(BackendLocal, metrics.OpModTime, time.Now(), &err)
+
 	info, err := os.Stat(b.clipPath())
 	if err != nil {
+		if os.IsNotExist(err) {
+			return time.Time{}, ErrNotFound
+		}
 		return time.Time{}, err
 	}
 	return info.ModTime(), nil
@@ -197,14 +426,278 @@ func (b *LocalBackend) GetModTime(ctx context.Context) (time.Time, error) {
 // GetChecksum returns the checksum without reading full content
 // For local backend, we read and decode the file but could optimize later
 func (b *LocalBackend) GetChecksum(ctx context.Context) (string, error) {
-	content, err := b.Read(ctx)
+	if b.basePath == "" {
+		return "", ErrNotConfigured
+	}
+
+	// Only the header is needed, skip reading the payload
+	f, err := os.Open(b.clipPath())
 	if err != nil {
+		if os.IsNotExist(err) {
+			return "", ErrNotFound
+		}
 		return "", err
 	}
-	if content == nil {
-		return "", ErrNotFound
+	defer f.Close()
+
+	header, _, err := storage.ReadHeader(bufio.NewReader(f))
+	if err != nil {
+		return "", fmt.Errorf("decode failed: %w", err)
+	}
+	return header.Checksum, nil
+}
+
+// writeTemp encodes content, or its manifest when payloads are stored
+// as blobs, into a new file at path
+func (b *LocalBackend) writeTemp(path string, content *clipboard.Content) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, FilePermissions)
+	if err != nil {
+		return fmt.Errorf("write temp file failed: %w", err)
 	}
-	return content.Checksum, nil
+
+	return encodeFile(f, func(w io.Writer) error {
+		if !storage.UsesBlobs(b.format) {
+			return storage.EncodeTo(w, content, b.format)
+		}
+		manifest, err := storage.EncodeManifest(content)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(manifest)
+		return err
+	})
+}
+
+// writeBlob stores the payload of content under its checksum, unless a
+// blob with that name already exists
+func (b *LocalBackend) writeBlob(content *clipboard.Content) error {
+	name := content.Checksum
+	if !storage.ValidBlobName(name) {
+		return fmt.Errorf("invalid checksum: %q", name)
+	}
+
+	path := b.blobPath(name)
+	if _, err := os.Stat(path); err == nil {
+		// Already stored; refresh it so garbage collection leaves it alone
+		// until our manifest is published
+		now := time.Now()
+		os.Chtimes(path, now, now)
+		b.blobs.put(name, content.Data)
+		return nil
+	}
+
+	if err := os.MkdirAll(b.blobDir(), DirPermissions); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(b.blobDir(), name+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("write blob failed: %w", err)
+	}
+	tempPath := f.Name()
+
+	err = encodeFile(f, func(w io.Writer) error {
+		return storage.EncodeBlobTo(w, content, b.format)
+	})
+	if err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+
+	if err := os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("rename blob failed: %w", err)
+	}
+
+	b.blobs.put(name, content.Data)
+	return nil
+}
+
+// readBlob returns the content a manifest refers to, reusing the cached
+// payload if the manifest is unchanged
+func (b *LocalBackend) readBlob(header *storage.FileHeader) (*clipboard.Content, error) {
+	data, ok := b.blobs.get(header.Blob)
+	if !ok {
+		f, err := os.Open(b.blobPath(header.Blob))
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		data, err = storage.DecodeBlobFrom(bufio.NewReader(receiving(BackendLocal, f)), header)
+		if err != nil {
+			return nil, err
+		}
+		b.blobs.put(header.Blob, data)
+	}
+
+	return storage.ContentFromHeader(header, data)
+}
+
+// CanFetch returns true if clips are stored as blobs, which placeholders
+// refer to
+func (b *LocalBackend) CanFetch() bool {
+	return storage.UsesBlobs(b.format)
+}
+
+// Fetch reads the payload blob of a placeholder clip
+func (b *LocalBackend) Fetch(ctx context.Context, content *clipboard.Content) (_ *clipboard.Content, err error) {
+	defer observe(BackendLocal, metrics.OpFetch, time.Now(), &err)
+
+	if b.basePath == "" {
+		return nil, ErrNotConfigured
+	}
+
+	header := storage.ManifestHeader(content)
+	header.Placeholder = false
+	fetched, err := b.readBlob(&header)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return fetched, err
+}
+
+// CollectGarbage removes blobs the current manifest doesn't reference and
+// that are older than BlobGCGrace, along with abandoned temp files
+func (b *LocalBackend) CollectGarbage(ctx context.Context) (_ int, err error) {
+	defer observe(BackendLocal, metrics.OpCollect, time.Now(), &err)
+
+	if b.basePath == "" {
+		return 0, ErrNotConfigured
+	}
+
+	entries, err := os.ReadDir(b.blobDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	// Never collect anything if the manifest can't be read
+	referenced, err := b.referencedBlob()
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := time.Now().Add(-BlobGCGrace)
+	removed := 0
+	for _, entry := range entries {
+		name := entry.Name()
+		if name == referenced {
+			continue
+		}
+		if !storage.ValidBlobName(name) && !strings.Contains(name, ".tmp-") {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+
+		if err := os.Remove(b.blobPath(name)); err == nil {
+			removed++
+		}
+	}
+
+	return removed, nil
+}
+
+// referencedBlob returns the blob named by the current manifest, if any
+func (b *LocalBackend) referencedBlob() (string, error) {
+	f, err := os.Open(b.clipPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	defer f.Close()
+
+	header, _, err := storage.ReadHeader(bufio.NewReader(f))
+	if err != nil {
+		return "", err
+	}
+	return header.Blob, nil
+}
+
+// PutObject stores a small named object in the sync directory
+func (b *LocalBackend) PutObject(ctx context.Context, name string, data []byte) error {
+	if b.basePath == "" {
+		return ErrNotConfigured
+	}
+	if err := checkObjectName(name); err != nil {
+		return err
+	}
+
+	dir := filepath.Join(b.syncDir(), ObjectDir)
+	if err := os.MkdirAll(dir, DirPermissions); err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, name)
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, data, FilePermissions); err != nil {
+		return err
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+	return nil
+}
+
+// GetObject reads a named object from the sync directory
+func (b *LocalBackend) GetObject(ctx context.Context, name string) ([]byte, error) {
+	if b.basePath == "" {
+		return nil, ErrNotConfigured
+	}
+	if err := checkObjectName(name); err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(b.syncDir(), ObjectDir, name))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+// DeleteObject removes a named object from the sync directory
+func (b *LocalBackend) DeleteObject(ctx context.Context, name string) error {
+	if b.basePath == "" {
+		return ErrNotConfigured
+	}
+	if err := checkObjectName(name); err != nil {
+		return err
+	}
+
+	err := os.Remove(filepath.Join(b.syncDir(), ObjectDir, name))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// encodeFile writes to f through a buffer, closes it and records the
+// bytes written
+func encodeFile(f *os.File, encode func(w io.Writer) error) error {
+	counted := &countingWriter{w: f}
+	w := bufio.NewWriter(counted)
+	if err := encode(w); err != nil {
+		f.Close()
+		return fmt.Errorf("encode failed: %w", err)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return fmt.Errorf("write %s failed: %w", filepath.Base(f.Name()), err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("write %s failed: %w", filepath.Base(f.Name()), err)
+	}
+	sent(BackendLocal, counted.n)
+	return nil
 }
 
 // Exists returns true if the clipboard file exists
@@ -213,35 +706,43 @@ func (b *LocalBackend) Exists(ctx context.Context) bool {
 	return err == nil
 }
 
-// acquireLock attempts to acquire the write lock using atomic operations
-func (b *LocalBackend) acquireLock() error {
+// acquireLock attempts to acquire the write lock using atomic operations.
+// On success it returns a lease carrying a fresh fencing token, which is
+// renewed in the background until released.
+func (b *LocalBackend) acquireLock() (*lease, error) {
 	lockPath := b.lockPath()
 	hostname, _ := os.Hostname()
 
-	// Prepare lock info
+	// The lock is created without a token; one is claimed only once the
+	// lock is ours, so contenders that lose never burn a token
 	lockInfo := LockInfo{
 		Holder:     hostname,
 		PID:        os.Getpid(),
+		Instance:   b.instance,
 		AcquiredAt: time.Now(),
-		ExpiresAt:  time.Now().Add(LockTimeout),
+		ExpiresAt:  time.Now().Add(b.lockTimeout),
 	}
 
 	data, err := json.Marshal(lockInfo)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Try to create lock file exclusively (atomic operation)
 	f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, FilePermissions)
 	if err == nil {
 		// Successfully created new lock
-		defer f.Close()
-		_, err = f.Write(data)
-		return err
+		_, err := f.Write(data)
+		f.Close()
+		if err != nil {
+			os.Remove(lockPath)
+			return nil, err
+		}
+		return b.issueLease(lockInfo)
 	}
 
 	if !os.IsExist(err) {
-		return err
+		return nil, err
 	}
 
 	// Lock file exists - check if it's stale or owned by us
@@ -249,50 +750,65 @@ func (b *LocalBackend) acquireLock() error {
 	if readErr != nil {
 		// Can't read lock file, try to remove and retry once
 		os.Remove(lockPath)
-		return b.acquireLockOnce(data)
+		return b.acquireLockOnce(lockInfo, data)
 	}
 
 	var existingLock LockInfo
 	if json.Unmarshal(existingData, &existingLock) != nil {
 		// Corrupted lock file, remove and retry
 		os.Remove(lockPath)
-		return b.acquireLockOnce(data)
+		return b.acquireLockOnce(lockInfo, data)
 	}
 
 	// Check if we own this lock
-	if existingLock.Holder == hostname && existingLock.PID == os.Getpid() {
-		// We own it, update expiry
-		return os.WriteFile(lockPath, data, FilePermissions)
+	if existingLock.Holder == hostname && existingLock.PID == os.Getpid() && existingLock.Instance == b.instance {
+		// We own it, take it over with a new token
+		return b.issueLease(lockInfo)
 	}
 
 	// Check if lock is expired
 	if time.Now().After(existingLock.ExpiresAt) {
 		// Expired, remove and retry
 		os.Remove(lockPath)
-		return b.acquireLockOnce(data)
+		return b.acquireLockOnce(lockInfo, data)
 	}
 
 	// Lock is held by another process and not expired
-	return ErrLocked
+	return nil, ErrLocked
 }
 
 // acquireLockOnce attempts to create lock file once (helper to avoid infinite recursion)
-func (b *LocalBackend) acquireLockOnce(data []byte) error {
+func (b *LocalBackend) acquireLockOnce(info LockInfo, data []byte) (*lease, error) {
 	f, err := os.OpenFile(b.lockPath(), os.O_CREATE|os.O_EXCL|os.O_WRONLY, FilePermissions)
 	if err != nil {
 		if os.IsExist(err) {
-			return ErrLocked
+			return nil, ErrLocked
 		}
-		return err
+		return nil, err
 	}
-	defer f.Close()
 	_, err = f.Write(data)
-	return err
+	f.Close()
+	if err != nil {
+		os.Remove(b.lockPath())
+		return nil, err
+	}
+	return b.issueLease(info)
 }
 
-// releaseLock releases the write lock
-func (b *LocalBackend) releaseLock() {
-	os.Remove(b.lockPath())
+// issueLease claims a fencing token for a lock we hold, records it in the
+// lock file and starts renewing the lease. The lock is given up if no
+// token can be claimed.
+func (b *LocalBackend) issueLease(info LockInfo) (*lease, error) {
+	token, err := b.claimFenceToken()
+	if err == nil {
+		info.Token = token
+		err = b.writeLock(info)
+	}
+	if err != nil {
+		os.Remove(b.lockPath())
+		return nil, err
+	}
+	return b.startLease(info), nil
 }
 
 // cleanStaleLocks removes expired lock files
@@ -0,0 +1,316 @@
+package backend
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// FenceDir holds one file per issued fencing token
+	FenceDir = "fence"
+
+	// FenceStateFile records, in the state directory, the highest fencing
+	// token seen for each location
+	FenceStateFile = "fence_tokens.json"
+
+	// fenceKeep is how many recent fence files are kept when pruning
+	fenceKeep = 16
+
+	// fenceClaimAttempts bounds the search for a free fencing token
+	fenceClaimAttempts = 64
+
+	// lockRenewals is how many times a held lease is extended within
+	// each lock timeout
+	lockRenewals = 3
+)
+
+// lease is a held write lock that is renewed in the background until
+// released. It records whether the lock was lost to another writer.
+type lease struct {
+	backend *LocalBackend
+	info    LockInfo
+	stop    chan struct{}
+	done    chan struct{}
+	lost    bool
+	mu      sync.Mutex
+}
+
+// startLease begins renewing a freshly acquired lock
+func (b *LocalBackend) startLease(info LockInfo) *lease {
+	l := &lease{
+		backend: b,
+		info:    info,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go l.run()
+	return l
+}
+
+func (l *lease) run() {
+	defer close(l.done)
+
+	ticker := time.NewTicker(l.backend.lockTimeout / lockRenewals)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := l.renew(); err != nil {
+				l.mu.Lock()
+				l.lost = true
+				l.mu.Unlock()
+				return
+			}
+		case <-l.stop:
+			return
+		}
+	}
+}
+
+// renew extends the lease if the lock file still carries our token
+func (l *lease) renew() error {
+	current, err := l.backend.readLock()
+	if err != nil {
+		return err
+	}
+	if current.Token != l.info.Token {
+		return ErrLockLost
+	}
+
+	l.mu.Lock()
+	l.info.ExpiresAt = time.Now().Add(l.backend.lockTimeout)
+	info := l.info
+	l.mu.Unlock()
+
+	return l.backend.writeLock(info)
+}
+
+// Held returns true if the lease has not been lost or run out
+func (l *lease) Held() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return !l.lost && time.Now().Before(l.info.ExpiresAt)
+}
+
+// Verify returns true if the lease is held and the lock file on disk
+// still carries our token. It is checked right before publishing.
+func (l *lease) Verify() bool {
+	if !l.Held() {
+		return false
+	}
+	current, err := l.backend.readLock()
+	return err == nil && current.Token == l.info.Token
+}
+
+// Token returns the fencing token issued with the lease
+func (l *lease) Token() uint64 {
+	return l.info.Token
+}
+
+// release stops renewal and removes the lock file if it is still ours
+func (l *lease) release() {
+	close(l.stop)
+	<-l.done
+
+	current, err := l.backend.readLock()
+	if err == nil && current.Token == l.info.Token {
+		os.Remove(l.backend.lockPath())
+	}
+}
+
+// readLock reads the current lock file
+func (b *LocalBackend) readLock() (LockInfo, error) {
+	var info LockInfo
+	data, err := os.ReadFile(b.lockPath())
+	if err != nil {
+		return info, err
+	}
+	err = json.Unmarshal(data, &info)
+	return info, err
+}
+
+// writeLock replaces the lock file contents atomically so readers
+// never see a partially written lease
+func (b *LocalBackend) writeLock(info LockInfo) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+
+	tempPath := b.lockPath() + ".renew"
+	if err := os.WriteFile(tempPath, data, FilePermissions); err != nil {
+		return err
+	}
+	if err := os.Rename(tempPath, b.lockPath()); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+	return nil
+}
+
+// fenceDir returns the full path to the fencing token directory
+func (b *LocalBackend) fenceDir() string {
+	return filepath.Join(b.syncDir(), FenceDir)
+}
+
+// claimFenceToken issues the next fencing token. Each token is claimed by
+// exclusively creating a file named after it, so two writers can never
+// receive the same token even if both believe they hold the lock.
+func (b *LocalBackend) claimFenceToken() (uint64, error) {
+	dir := b.fenceDir()
+	if err := os.MkdirAll(dir, DirPermissions); err != nil {
+		return 0, err
+	}
+
+	// Never go below a token we've already seen, even if fence files
+	// were removed from the share
+	latest := b.latestFenceToken()
+	b.mu.Lock()
+	b.loadFenceState()
+	if b.highestFence > latest {
+		latest = b.highestFence
+	}
+	b.mu.Unlock()
+
+	next := latest + 1
+	for i := 0; i < fenceClaimAttempts; i++ {
+		token := next + uint64(i)
+		path := filepath.Join(dir, strconv.FormatUint(token, 10))
+
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, FilePermissions)
+		if err == nil {
+			f.Close()
+			b.pruneFenceTokens(token)
+			return token, nil
+		}
+		if !os.IsExist(err) {
+			return 0, err
+		}
+	}
+
+	return 0, ErrLocked
+}
+
+// latestFenceToken returns the highest token claimed so far
+func (b *LocalBackend) latestFenceToken() uint64 {
+	entries, err := os.ReadDir(b.fenceDir())
+	if err != nil {
+		return 0
+	}
+
+	var latest uint64
+	for _, entry := range entries {
+		token, err := strconv.ParseUint(entry.Name(), 10, 64)
+		if err == nil && token > latest {
+			latest = token
+		}
+	}
+	return latest
+}
+
+// pruneFenceTokens removes old token files, keeping the most recent ones
+func (b *LocalBackend) pruneFenceTokens(latest uint64) {
+	if latest <= fenceKeep {
+		return
+	}
+
+	entries, err := os.ReadDir(b.fenceDir())
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		token, err := strconv.ParseUint(entry.Name(), 10, 64)
+		if err == nil && token <= latest-fenceKeep {
+			os.Remove(filepath.Join(b.fenceDir(), entry.Name()))
+		}
+	}
+}
+
+// observeFenceToken records a token seen on disk and reports whether it
+// is current. Clips without a token (older writers) are always accepted.
+func (b *LocalBackend) observeFenceToken(token uint64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if token == 0 {
+		return true
+	}
+	b.loadFenceState()
+	if token < b.highestFence {
+		return false
+	}
+	if token > b.highestFence {
+		b.highestFence = token
+		b.saveFenceState()
+	}
+	return true
+}
+
+// fenceStatePath returns the path of the fence state file, or "" if no
+// state directory is set
+func (b *LocalBackend) fenceStatePath() string {
+	if b.stateDir == "" {
+		return ""
+	}
+	return filepath.Join(b.stateDir, FenceStateFile)
+}
+
+// readFenceState reads the highest token seen per location. A missing
+// or unreadable file has none.
+func (b *LocalBackend) readFenceState() map[string]uint64 {
+	tokens := make(map[string]uint64)
+	if path := b.fenceStatePath(); path != "" {
+		if data, err := os.ReadFile(path); err == nil {
+			json.Unmarshal(data, &tokens)
+		}
+	}
+	return tokens
+}
+
+// loadFenceState restores the highest token seen for the current location,
+// so a restarted client keeps rejecting stale writers. The caller must
+// hold b.mu.
+func (b *LocalBackend) loadFenceState() {
+	if b.fenceLoaded == b.basePath {
+		return
+	}
+	b.highestFence = b.readFenceState()[b.basePath]
+	b.fenceLoaded = b.basePath
+}
+
+// saveFenceState persists the highest token seen for the current
+// location. The caller must hold b.mu.
+func (b *LocalBackend) saveFenceState() {
+	path := b.fenceStatePath()
+	if path == "" {
+		return
+	}
+
+	tokens := b.readFenceState()
+	tokens[b.basePath] = b.highestFence
+	data, err := json.Marshal(tokens)
+	if err != nil {
+		return
+	}
+
+	if err := os.MkdirAll(b.stateDir, DirPermissions); err != nil {
+		slog.Warn("Failed to save fencing token", "error", err)
+		return
+	}
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, data, FilePermissions); err != nil {
+		slog.Warn("Failed to save fencing token", "error", err)
+		return
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath)
+		slog.Warn("Failed to save fencing token", "error", err)
+	}
+}
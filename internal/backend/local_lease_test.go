@@ -0,0 +1,215 @@
+package backend
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mindmorass/yippity-clippity/internal/clipboard"
+)
+
+// testLockTimeout runs leases at a faster pace so expiry and renewal can
+// be tested without long waits
+const testLockTimeout = 300 * time.Millisecond
+
+func newTestClip(id, text string) *clipboard.Content {
+	checksum := sha256.Sum256([]byte(text))
+	return &clipboard.Content{
+		ID:            id,
+		Timestamp:     time.Now(),
+		SourceMachine: "test",
+		ContentType:   clipboard.ContentTypeText,
+		MimeType:      "text/plain",
+		Checksum:      hex.EncodeToString(checksum[:]),
+		Size:          int64(len(text)),
+		Data:          []byte(text),
+	}
+}
+
+func newTestLocal(t *testing.T, dir, stateDir string) *LocalBackend {
+	t.Helper()
+	b := NewLocalBackend(dir)
+	b.SetStateDir(stateDir)
+	b.lockTimeout = testLockTimeout
+	if err := b.Init(context.Background()); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	return b
+}
+
+func TestLocalFenceTokenNotClaimedWhileLocked(t *testing.T) {
+	b := newTestLocal(t, t.TempDir(), "")
+
+	// Another device holds the lock
+	held, _ := json.Marshal(LockInfo{
+		Holder:    "other-host",
+		PID:       1,
+		Token:     7,
+		ExpiresAt: time.Now().Add(time.Minute),
+	})
+	if err := os.WriteFile(b.lockPath(), held, FilePermissions); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := b.acquireLock(); !errors.Is(err, ErrLocked) {
+			t.Fatalf("acquireLock error = %v, want ErrLocked", err)
+		}
+	}
+	if latest := b.latestFenceToken(); latest != 0 {
+		t.Errorf("latest fence token = %d after failed attempts, want 0", latest)
+	}
+
+	os.Remove(b.lockPath())
+	l, err := b.acquireLock()
+	if err != nil {
+		t.Fatalf("acquireLock: %v", err)
+	}
+	defer l.release()
+	if l.Token() != 1 {
+		t.Errorf("token = %d, want 1", l.Token())
+	}
+
+	current, err := b.readLock()
+	if err != nil || current.Token != l.Token() {
+		t.Errorf("lock file token = %d (%v), want %d", current.Token, err, l.Token())
+	}
+}
+
+func TestLocalWriteRejectsNewerFenceToken(t *testing.T) {
+	b := newTestLocal(t, t.TempDir(), "")
+	if err := b.Write(context.Background(), newTestClip("a", "first")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	l, err := b.acquireLock()
+	if err != nil {
+		t.Fatalf("acquireLock: %v", err)
+	}
+	defer l.release()
+
+	tempPath := b.clipPath() + ".tmp"
+	stale := newTestClip("b", "second")
+	stale.FenceToken = l.Token()
+	if err := b.writeTemp(tempPath, stale); err != nil {
+		t.Fatalf("writeTemp: %v", err)
+	}
+
+	// Another writer claims a newer token before ours publishes
+	if _, err := b.claimFenceToken(); err != nil {
+		t.Fatalf("claimFenceToken: %v", err)
+	}
+
+	if err := b.publish(l, tempPath); !errors.Is(err, ErrStaleFence) {
+		t.Fatalf("publish error = %v, want ErrStaleFence", err)
+	}
+	if _, err := os.Stat(tempPath); !os.IsNotExist(err) {
+		t.Error("temp file left behind")
+	}
+	if content, err := b.Read(context.Background()); err != nil || content.ID != "a" {
+		t.Errorf("Read = %v, %v; want clip a", content, err)
+	}
+}
+
+func TestLocalFenceStatePersists(t *testing.T) {
+	dir := t.TempDir()
+	stateDir := t.TempDir()
+	ctx := context.Background()
+
+	writer := newTestLocal(t, dir, "")
+	if err := writer.Write(ctx, newTestClip("old", "old")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	stale, err := os.ReadFile(writer.clipPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Write(ctx, newTestClip("new", "new")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	reader := newTestLocal(t, dir, stateDir)
+	if content, err := reader.Read(ctx); err != nil || content.ID != "new" {
+		t.Fatalf("Read = %v, %v; want clip new", content, err)
+	}
+	if _, err := os.Stat(filepath.Join(stateDir, FenceStateFile)); err != nil {
+		t.Fatalf("fence state not saved: %v", err)
+	}
+
+	// A stale writer's clip lands after the reader restarted
+	if err := os.WriteFile(writer.clipPath(), stale, FilePermissions); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := newTestLocal(t, dir, stateDir)
+	if _, err := restarted.Read(ctx); !errors.Is(err, ErrStaleFence) {
+		t.Errorf("Read after restart error = %v, want ErrStaleFence", err)
+	}
+
+	// State is kept per location
+	other := newTestLocal(t, t.TempDir(), stateDir)
+	if err := other.Write(ctx, newTestClip("other", "other")); err != nil {
+		t.Errorf("Write to another location: %v", err)
+	}
+}
+
+func TestLocalLeaseRenewed(t *testing.T) {
+	dir := t.TempDir()
+	holder := newTestLocal(t, dir, "")
+	contender := newTestLocal(t, dir, "")
+
+	l, err := holder.acquireLock()
+	if err != nil {
+		t.Fatalf("acquireLock: %v", err)
+	}
+
+	// Held well past the original expiry
+	time.Sleep(3 * testLockTimeout)
+	if !l.Verify() {
+		t.Fatal("lease not renewed")
+	}
+	if _, err := contender.acquireLock(); !errors.Is(err, ErrLocked) {
+		t.Fatalf("contender acquireLock error = %v, want ErrLocked", err)
+	}
+
+	l.release()
+	taken, err := contender.acquireLock()
+	if err != nil {
+		t.Fatalf("acquireLock after release: %v", err)
+	}
+	defer taken.release()
+	if taken.Token() <= l.Token() {
+		t.Errorf("token = %d after release, want more than %d", taken.Token(), l.Token())
+	}
+}
+
+func TestLocalExpiredLeaseTakenOver(t *testing.T) {
+	dir := t.TempDir()
+	holder := newTestLocal(t, dir, "")
+	contender := newTestLocal(t, dir, "")
+
+	l, err := holder.acquireLock()
+	if err != nil {
+		t.Fatalf("acquireLock: %v", err)
+	}
+
+	// The holder stalls and stops renewing
+	close(l.stop)
+	<-l.done
+	time.Sleep(testLockTimeout + testLockTimeout/lockRenewals)
+
+	taken, err := contender.acquireLock()
+	if err != nil {
+		t.Fatalf("acquireLock on an expired lease: %v", err)
+	}
+	defer taken.release()
+	if l.Verify() {
+		t.Error("expired lease still verifies after takeover")
+	}
+}
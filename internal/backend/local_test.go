@@ -0,0 +1,22 @@
+package backend_test
+
+import (
+	"testing"
+
+	"github.com/mindmorass/yippity-clippity/internal/backend"
+	"github.com/mindmorass/yippity-clippity/internal/backend/backendtest"
+)
+
+func TestLocalConformance(t *testing.T) {
+	backendtest.Run(t, func(t *testing.T) backendtest.Opener {
+		dir := t.TempDir()
+		return func() (backend.Backend, error) {
+			b := backend.NewLocalBackend(dir)
+			b.SetStateDir(t.TempDir())
+			if err := b.Init(t.Context()); err != nil {
+				return nil, err
+			}
+			return b, nil
+		}
+	})
+}
@@ -0,0 +1,70 @@
+package backend
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	"github.com/mindmorass/yippity-clippity/internal/metrics"
+)
+
+// observe records an operation of a backend of type t that started at
+// start. It is deferred with a pointer to the operation's error.
+func observe(t BackendType, op string, start time.Time, err *error) {
+	metrics.ObserveBackend(string(t), op, time.Since(start), outcome(*err))
+}
+
+// outcome classifies the result of a backend operation
+func outcome(err error) string {
+	switch {
+	case err == nil:
+		return metrics.OutcomeOK
+	case errors.Is(err, ErrNotFound):
+		return metrics.OutcomeNotFound
+	case errors.Is(err, ErrConflict), errors.Is(err, ErrStaleFence):
+		return metrics.OutcomeConflict
+	case errors.Is(err, ErrLocked), errors.Is(err, ErrLockLost):
+		return metrics.OutcomeLocked
+	default:
+		return metrics.OutcomeError
+	}
+}
+
+// sent records n bytes stored by a backend of type t
+func sent(t BackendType, n int64) {
+	metrics.AddBytes(string(t), metrics.Sent, n)
+}
+
+// received records n bytes read from storage by a backend of type t
+func received(t BackendType, n int64) {
+	metrics.AddBytes(string(t), metrics.Received, n)
+}
+
+// receiving wraps a stored clip or blob being read by a backend of type
+// t, recording the bytes as they arrive
+func receiving(t BackendType, r io.ReadCloser) io.ReadCloser {
+	return &receivingReader{ReadCloser: r, backend: t}
+}
+
+type receivingReader struct {
+	io.ReadCloser
+	backend BackendType
+}
+
+func (r *receivingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	received(r.backend, int64(n))
+	return n, err
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,22 @@
+package backend
+
+import "fmt"
+
+// ObjectDir is the directory holding ObjectStore objects
+const ObjectDir = "objects"
+
+// maxObjectName limits object name length
+const maxObjectName = 128
+
+// checkObjectName rejects names that could escape the object directory
+func checkObjectName(name string) error {
+	if name == "" || len(name) > maxObjectName {
+		return fmt.Errorf("invalid object name: %q", name)
+	}
+	for _, c := range name {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+			return fmt.Errorf("invalid object name: %q", name)
+		}
+	}
+	return nil
+}
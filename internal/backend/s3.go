@@ -3,32 +3,100 @@ package backend
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"net/url"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
 	"github.com/mindmorass/yippity-clippity/internal/clipboard"
+	"github.com/mindmorass/yippity-clippity/internal/metrics"
+	"github.com/mindmorass/yippity-clippity/internal/secrets"
 	"github.com/mindmorass/yippity-clippity/internal/storage"
 )
 
 const (
 	// S3ObjectKey is the key suffix for the clipboard object
 	S3ObjectKey = ".yippity-clippity/current.clip"
+
+	// S3BlobPrefix is the key suffix under which blobs are stored
+	S3BlobPrefix = ".yippity-clippity/" + storage.BlobDir + "/"
+
+	// S3ObjectPrefix is the key suffix under which ObjectStore objects are stored
+	S3ObjectPrefix = ".yippity-clippity/" + ObjectDir + "/"
+
+	// S3ExpiresMetadata is the user metadata key holding a clip's expiry
+	S3ExpiresMetadata = "expires-at"
+
+	// S3ExpiringTag tags clipboard objects that carry an expiry, so a
+	// lifecycle rule can be scoped to them
+	S3ExpiringTag = "yippity-clippity-expiring"
+
+	// S3SecretService is the secret store service name for S3 credentials
+	S3SecretService = "com.yippityclippity.s3"
+
+	// s3CredentialsAccount is the secret store account for static keys
+	s3CredentialsAccount = "credentials"
 )
 
+// S3Credentials holds static AWS keys kept in the secret store
+type S3Credentials struct {
+	AccessKeyID     string `json:"access_key_id"`
+	SecretAccessKey string `json:"secret_access_key"`
+	SessionToken    string `json:"session_token,omitempty"`
+}
+
+// SaveS3Credentials stores static AWS keys in the secret store
+func SaveS3Credentials(store secrets.Store, creds S3Credentials) error {
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return fmt.Errorf("access key ID and secret access key are required")
+	}
+
+	data, err := json.Marshal(creds)
+	if err != nil {
+		return err
+	}
+
+	return store.Set(S3SecretService, s3CredentialsAccount, data)
+}
+
+// loadS3Credentials reads static AWS keys from the secret store
+func loadS3Credentials(store secrets.Store) (*S3Credentials, error) {
+	data, err := store.Get(S3SecretService, s3CredentialsAccount)
+	if err != nil {
+		return nil, err
+	}
+
+	var creds S3Credentials
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return nil, err
+	}
+
+	return &creds, nil
+}
+
 // S3Backend implements Backend for AWS S3 storage
 type S3Backend struct {
 	bucket   string
 	prefix   string
 	region   string
+	endpoint string
 	client   *s3.Client
 	lastETag string
+	secrets  secrets.Store
+	format   storage.Options
+	blobs    blobCache
+
+	// tagExpiring tags clips that carry an expiry
+	tagExpiring bool
 }
 
 // NewS3Backend creates a new S3 backend
@@ -37,6 +105,7 @@ func NewS3Backend(bucket, prefix, region string) *S3Backend {
 		bucket: bucket,
 		prefix: strings.TrimSuffix(prefix, "/"),
 		region: region,
+		format: storage.DefaultOptions(),
 	}
 }
 
@@ -85,6 +154,22 @@ func (b *S3Backend) SetLocation(location string) error {
 	return nil
 }
 
+// SetSecretStore sets where static AWS keys are looked up
+func (b *S3Backend) SetSecretStore(store secrets.Store) {
+	b.secrets = store
+}
+
+// SetFormat sets the .clip format options used for writes
+func (b *S3Backend) SetFormat(opts storage.Options) {
+	b.format = opts
+}
+
+// SetTagExpiring sets whether clips that carry an expiry are tagged with
+// S3ExpiringTag. Tagging needs the s3:PutObjectTagging permission.
+func (b *S3Backend) SetTagExpiring(tag bool) {
+	b.tagExpiring = tag
+}
+
 // objectKey returns the full S3 object key
 func (b *S3Backend) objectKey() string {
 	if b.prefix != "" {
@@ -93,6 +178,19 @@ func (b *S3Backend) objectKey() string {
 	return S3ObjectKey
 }
 
+// blobPrefix returns the full S3 key prefix for blobs
+func (b *S3Backend) blobPrefix() string {
+	if b.prefix != "" {
+		return b.prefix + "/" + S3BlobPrefix
+	}
+	return S3BlobPrefix
+}
+
+// blobKey returns the full S3 object key of a blob
+func (b *S3Backend) blobKey(name string) string {
+	return b.blobPrefix() + name
+}
+
 // Init initializes the S3 client
 func (b *S3Backend) Init(ctx context.Context) error {
 	if b.bucket == "" {
@@ -105,12 +203,30 @@ func (b *S3Backend) Init(ctx context.Context) error {
 		opts = append(opts, config.WithRegion(b.region))
 	}
 
+	// Static keys from the secret store take precedence over the chain
+	if b.secrets != nil {
+		creds, err := loadS3Credentials(b.secrets)
+		switch {
+		case err == nil:
+			opts = append(opts, config.WithCredentialsProvider(
+				credentials.NewStaticCredentialsProvider(creds.AccessKeyID, creds.SecretAccessKey, creds.SessionToken),
+			))
+		case !errors.Is(err, secrets.ErrNotFound):
+			return fmt.Errorf("failed to load S3 credentials: %w", err)
+		}
+	}
+
 	cfg, err := config.LoadDefaultConfig(ctx, opts...)
 	if err != nil {
 		return fmt.Errorf("failed to load AWS config: %w", err)
 	}
 
-	b.client = s3.NewFromConfig(cfg)
+	b.client = s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if b.endpoint != "" {
+			o.BaseEndpoint = aws.String(b.endpoint)
+			o.UsePathStyle = true
+		}
+	})
 
 	// Verify bucket access with a HEAD request
 	_, err = b.client.HeadBucket(ctx, &s3.HeadBucketInput{
@@ -129,22 +245,53 @@ func (b *S3Backend) Close() error {
 }
 
 // Write stores clipboard content to S3 with optimistic locking
-func (b *S3Backend) Write(ctx context.Context, content *clipboard.Content) error {
+func (b *S3Backend) Write(ctx context.Context, content *clipboard.Content) (err error) {
+	defer observe(BackendS3, metrics.OpWrite, time.Now(), &err)
+
 	if b.client == nil {
 		return ErrNotConfigured
 	}
 
-	// Encode content
-	data, err := storage.Encode(content)
-	if err != nil {
-		return fmt.Errorf("encode failed: %w", err)
+	// Encode content; the SDK needs a seekable body to sign and retry.
+	// With blobs, the payload is stored first and only the manifest goes
+	// into the clipboard object.
+	var body io.ReadSeeker
+	var size int64
+	if storage.UsesBlobs(b.format) {
+		if err := b.writeBlob(ctx, content); err != nil {
+			return err
+		}
+		manifest, err := storage.EncodeManifest(content)
+		if err != nil {
+			return fmt.Errorf("encode failed: %w", err)
+		}
+		body, size = bytes.NewReader(manifest), int64(len(manifest))
+	} else {
+		var err error
+		body, size, err = storage.EncodeReader(content, b.format)
+		if err != nil {
+			return fmt.Errorf("encode failed: %w", err)
+		}
 	}
 
 	input := &s3.PutObjectInput{
-		Bucket:      aws.String(b.bucket),
-		Key:         aws.String(b.objectKey()),
-		Body:        bytes.NewReader(data),
-		ContentType: aws.String("application/octet-stream"),
+		Bucket:        aws.String(b.bucket),
+		Key:           aws.String(b.objectKey()),
+		Body:          body,
+		ContentLength: aws.Int64(size),
+		ContentType:   aws.String("application/octet-stream"),
+	}
+
+	// Expose the expiry outside the clip so a lifecycle rule can remove
+	// expired clips no device is left to clean up
+	if !content.ExpiresAt.IsZero() {
+		input.Expires = aws.Time(content.ExpiresAt)
+		input.Metadata = map[string]string{
+			S3ExpiresMetadata: content.ExpiresAt.UTC().Format(time.RFC3339),
+		}
+		if b.tagExpiring {
+			input.Tagging = aws.String(S3ExpiringTag + "=true")
+		}
 	}
 
 	// Use If-None-Match for optimistic locking when we have a known ETag
@@ -159,6 +306,7 @@ func (b *S3Backend) Write(ctx context.Context, content *clipboard.Content) error
 	if err != nil {
 		return fmt.Errorf("S3 put failed: %w", err)
 	}
+	sent(BackendS3, size)
 
 	// Store the new ETag for future conflict detection
 	if result.ETag != nil {
@@ -169,7 +317,64 @@ func (b *S3Backend) Write(ctx context.Context, content *clipboard.Content) error
 }
 
 // Read retrieves clipboard content from S3
-func (b *S3Backend) Read(ctx context.Context) (*clipboard.Content, error) {
+func (b *S3Backend) Read(ctx context.Context) (_ *clipboard.Content, err error) {
+	defer observe(BackendS3, metrics.OpRead, time.Now(), &err)
+
+	body, err := b.openClip(ctx)
+	if body == nil || err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	return b.decodeClip(ctx, body)
+}
+
+// ReadHeader reads the current clip from S3 without its payload. Only
+// the header is downloaded; the rest of the clipboard object is left unread.
+func (b *S3Backend) ReadHeader(ctx context.Context) (_ *clipboard.Content, err error) {
+	defer observe(BackendS3, metrics.OpReadHeader, time.Now(), &err)
+
+	body, err := b.openClip(ctx)
+	if body == nil || err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	header, _, err := storage.ReadHeader(body)
+	if err != nil {
+		return nil, fmt.Errorf("decode failed: %w", err)
+	}
+	content, err := headerContent(header)
+	if err != nil {
+		return nil, fmt.Errorf("decode failed: %w", err)
+	}
+	return content, nil
+}
+
+// ReadRaw returns the stored bytes of the clipboard object, or nil if
+// there is none
+func (b *S3Backend) ReadRaw(ctx context.Context) ([]byte, error) {
+	body, err := b.openClip(ctx)
+	if body == nil || err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("S3 get failed: %w", err)
+	}
+	return data, nil
+}
+
+// DecodeRaw decodes stored clip bytes the way Read does
+func (b *S3Backend) DecodeRaw(ctx context.Context, data []byte) (*clipboard.Content, error) {
+	return b.decodeClip(ctx, bytes.NewReader(data))
+}
+
+// openClip starts downloading the clipboard object and remembers its
+// ETag. It returns a nil body if there is no object.
+func (b *S3Backend) openClip(ctx context.Context) (io.ReadCloser, error) {
 	if b.client == nil {
 		return nil, ErrNotConfigured
 	}
@@ -179,30 +384,35 @@ func (b *S3Backend) Read(ctx context.Context) (*clipboard.Content, error) {
 		Key:    aws.String(b.objectKey()),
 	})
 	if err != nil {
-		var noSuchKey *types.NoSuchKey
-		if errors.As(err, &noSuchKey) {
-			return nil, nil
-		}
-		// Also check for 404 status
-		var notFound *types.NotFound
-		if errors.As(err, &notFound) {
+		if isS3NotFound(err) {
 			return nil, nil
 		}
 		return nil, fmt.Errorf("S3 get failed: %w", err)
 	}
-	defer result.Body.Close()
 
 	// Update ETag
 	if result.ETag != nil {
 		b.lastETag = strings.Trim(*result.ETag, "\"")
 	}
+	return receiving(BackendS3, result.Body), nil
+}
 
-	data, err := io.ReadAll(result.Body)
+// decodeClip decodes a stored clip, loading its blob if it has one
+func (b *S3Backend) decodeClip(ctx context.Context, r io.Reader) (*clipboard.Content, error) {
+	header, version, err := storage.ReadHeader(r)
 	if err != nil {
-		return nil, fmt.Errorf("read body failed: %w", err)
+		return nil, fmt.Errorf("decode failed: %w", err)
 	}
 
-	content, err := storage.Decode(data)
+	var content *clipboard.Content
+	switch {
+	case header.Placeholder:
+		content, err = storage.PlaceholderContent(header)
+	case header.Blob != "":
+		content, err = b.readBlob(ctx, header)
+	default:
+		content, err = storage.DecodePayload(r, header, version)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("decode failed: %w", err)
 	}
@@ -210,8 +420,333 @@ func (b *S3Backend) Read(ctx context.Context) (*clipboard.Content, error) {
 	return content, nil
 }
 
+// RemoveClip deletes the clipboard object if it still holds the clip with
+// id. The delete is conditional on the ETag that was checked.
+func (b *S3Backend) RemoveClip(ctx context.Context, id string) (err error) {
+	defer observe(BackendS3, metrics.OpRemove, time.Now(), &err)
+
+	if b.client == nil {
+		return ErrNotConfigured
+	}
+
+	result, err := b.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(b.objectKey()),
+	})
+	if err != nil {
+		if isS3NotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("S3 get failed: %w", err)
+	}
+	header, _, err := storage.ReadHeader(receiving(BackendS3, result.Body))
+	result.Body.Close()
+	if err != nil {
+		return fmt.Errorf("decode failed: %w", err)
+	}
+	if header.ID != id {
+		return ErrConflict
+	}
+
+	_, err = b.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket:  aws.String(b.bucket),
+		Key:     aws.String(b.objectKey()),
+		IfMatch: result.ETag,
+	})
+	if err != nil {
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) && apiErr.ErrorCode() == "PreconditionFailed" {
+			return ErrConflict
+		}
+		return fmt.Errorf("S3 delete failed: %w", err)
+	}
+	return nil
+}
+
+// writeBlob uploads the payload of content under its checksum, unless a
+// recent blob with that name already exists
+func (b *S3Backend) writeBlob(ctx context.Context, content *clipboard.Content) error {
+	name := content.Checksum
+	if !storage.ValidBlobName(name) {
+		return fmt.Errorf("invalid checksum: %q", name)
+	}
+
+	head, err := b.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(b.blobKey(name)),
+	})
+	if err != nil && !isS3NotFound(err) {
+		return fmt.Errorf("S3 head blob failed: %w", err)
+	}
+	if err == nil {
+		fresh := head.LastModified != nil && time.Since(*head.LastModified) < blobRefreshAge
+		if fresh {
+			b.blobs.put(name, content.Data)
+			return nil
+		}
+		err = b.touchBlob(ctx, name)
+		if err == nil {
+			b.blobs.put(name, content.Data)
+			return nil
+		}
+		// Collected since the head; upload it again
+		if !isS3NotFound(err) {
+			return fmt.Errorf("S3 refresh blob failed: %w", err)
+		}
+	}
+
+	body, size, err := storage.EncodeBlobReader(content, b.format)
+	if err != nil {
+		return fmt.Errorf("encode failed: %w", err)
+	}
+
+	_, err = b.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(b.bucket),
+		Key:           aws.String(b.blobKey(name)),
+		Body:          body,
+		ContentLength: aws.Int64(size),
+		ContentType:   aws.String("application/octet-stream"),
+	})
+	if err != nil {
+		return fmt.Errorf("S3 put blob failed: %w", err)
+	}
+	sent(BackendS3, size)
+
+	b.blobs.put(name, content.Data)
+	return nil
+}
+
+// touchBlob resets the modification time of an existing blob by copying
+// it onto itself, so it stays clear of collection without another upload.
+// S3 only accepts a copy onto the same key if it replaces the metadata.
+func (b *S3Backend) touchBlob(ctx context.Context, name string) error {
+	key := b.blobKey(name)
+	_, err := b.client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:            aws.String(b.bucket),
+		Key:               aws.String(key),
+		CopySource:        aws.String((&url.URL{Path: b.bucket + "/" + key}).EscapedPath()),
+		MetadataDirective: types.MetadataDirectiveReplace,
+		ContentType:       aws.String("application/octet-stream"),
+	})
+	return err
+}
+
+// readBlob returns the content a manifest refers to, reusing the cached
+// payload if the manifest is unchanged
+func (b *S3Backend) readBlob(ctx context.Context, header *storage.FileHeader) (*clipboard.Content, error) {
+	data, ok := b.blobs.get(header.Blob)
+	if !ok {
+		result, err := b.client.GetObject(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(b.bucket),
+			Key:    aws.String(b.blobKey(header.Blob)),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("S3 get blob failed: %w", err)
+		}
+		defer result.Body.Close()
+
+		data, err = storage.DecodeBlobFrom(receiving(BackendS3, result.Body), header)
+		if err != nil {
+			return nil, err
+		}
+		b.blobs.put(header.Blob, data)
+	}
+
+	return storage.ContentFromHeader(header, data)
+}
+
+// CanFetch returns true if clips are stored as blobs, which placeholders
+// refer to
+func (b *S3Backend) CanFetch() bool {
+	return storage.UsesBlobs(b.format)
+}
+
+// Fetch downloads the payload blob of a placeholder clip
+func (b *S3Backend) Fetch(ctx context.Context, content *clipboard.Content) (_ *clipboard.Content, err error) {
+	defer observe(BackendS3, metrics.OpFetch, time.Now(), &err)
+
+	if b.client == nil {
+		return nil, ErrNotConfigured
+	}
+
+	header := storage.ManifestHeader(content)
+	header.Placeholder = false
+	return b.readBlob(ctx, &header)
+}
+
+// CollectGarbage removes blobs the current manifest doesn't reference and
+// that are older than BlobGCGrace
+func (b *S3Backend) CollectGarbage(ctx context.Context) (_ int, err error) {
+	defer observe(BackendS3, metrics.OpCollect, time.Now(), &err)
+
+	if b.client == nil {
+		return 0, ErrNotConfigured
+	}
+
+	// Never collect anything if the manifest can't be read
+	referenced, err := b.referencedBlob(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := time.Now().Add(-BlobGCGrace)
+	removed := 0
+
+	paginator := s3.NewListObjectsV2Paginator(b.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(b.bucket),
+		Prefix: aws.String(b.blobPrefix()),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return removed, fmt.Errorf("S3 list blobs failed: %w", err)
+		}
+
+		for _, obj := range page.Contents {
+			name := strings.TrimPrefix(aws.ToString(obj.Key), b.blobPrefix())
+			if name == referenced || !storage.ValidBlobName(name) {
+				continue
+			}
+			if obj.LastModified == nil || obj.LastModified.After(cutoff) {
+				continue
+			}
+
+			_, err := b.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+				Bucket: aws.String(b.bucket),
+				Key:    obj.Key,
+			})
+			if err != nil {
+				return removed, fmt.Errorf("S3 delete blob failed: %w", err)
+			}
+			removed++
+		}
+	}
+
+	return removed, nil
+}
+
+// referencedBlob returns the blob named by the current manifest, if any
+func (b *S3Backend) referencedBlob(ctx context.Context) (string, error) {
+	result, err := b.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(b.objectKey()),
+	})
+	if err != nil {
+		if isS3NotFound(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("S3 get failed: %w", err)
+	}
+	defer result.Body.Close()
+
+	header, _, err := storage.ReadHeader(result.Body)
+	if err != nil {
+		return "", err
+	}
+	return header.Blob, nil
+}
+
+// storeKey returns the full S3 key of an ObjectStore object
+func (b *S3Backend) storeKey(name string) string {
+	if b.prefix != "" {
+		return b.prefix + "/" + S3ObjectPrefix + name
+	}
+	return S3ObjectPrefix + name
+}
+
+// PutObject stores a small named object in the bucket
+func (b *S3Backend) PutObject(ctx context.Context, name string, data []byte) error {
+	if b.client == nil {
+		return ErrNotConfigured
+	}
+	if err := checkObjectName(name); err != nil {
+		return err
+	}
+
+	_, err := b.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(b.bucket),
+		Key:           aws.String(b.storeKey(name)),
+		Body:          bytes.NewReader(data),
+		ContentLength: aws.Int64(int64(len(data))),
+		ContentType:   aws.String("application/octet-stream"),
+	})
+	if err != nil {
+		return fmt.Errorf("S3 put failed: %w", err)
+	}
+	return nil
+}
+
+// GetObject reads a named object from the bucket
+func (b *S3Backend) GetObject(ctx context.Context, name string) ([]byte, error) {
+	if b.client == nil {
+		return nil, ErrNotConfigured
+	}
+	if err := checkObjectName(name); err != nil {
+		return nil, err
+	}
+
+	result, err := b.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(b.storeKey(name)),
+	})
+	if err != nil {
+		if isS3NotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("S3 get failed: %w", err)
+	}
+	defer result.Body.Close()
+
+	return io.ReadAll(result.Body)
+}
+
+// DeleteObject removes a named object from the bucket
+func (b *S3Backend) DeleteObject(ctx context.Context, name string) error {
+	if b.client == nil {
+		return ErrNotConfigured
+	}
+	if err := checkObjectName(name); err != nil {
+		return err
+	}
+
+	_, err := b.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(b.storeKey(name)),
+	})
+	if err != nil {
+		return fmt.Errorf("S3 delete failed: %w", err)
+	}
+	return nil
+}
+
+// isS3NotFound returns true if err reports a missing object
+func isS3NotFound(err error) bool {
+	var noSuchKey *types.NoSuchKey
+	if errors.As(err, &noSuchKey) {
+		return true
+	}
+	// HEAD requests report a plain 404
+	var notFound *types.NotFound
+	return errors.As(err, &notFound)
+}
+
+// isS3AuthError returns true if S3 rejected the credentials
+func isS3AuthError(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.ErrorCode() {
+	case "ExpiredToken", "InvalidAccessKeyId", "InvalidToken", "SignatureDoesNotMatch":
+		return true
+	}
+	return false
+}
+
 // GetModTime returns the last modification time of the S3 object
-func (b *S3Backend) GetModTime(ctx context.Context) (time.Time, error) {
+func (b *S3Backend) GetModTime(ctx context.Context) (_ time.Time, err error) {
+	defer observe(BackendS3, metrics.OpModTime, time.Now(), &err)
+
 	if b.client == nil {
 		return time.Time{}, ErrNotConfigured
 	}
@@ -221,6 +756,9 @@ func (b *S3Backend) GetModTime(ctx context.Context) (time.Time, error) {
 		Key:    aws.String(b.objectKey()),
 	})
 	if err != nil {
+		if isS3NotFound(err) {
+			return time.Time{}, ErrNotFound
+		}
 		return time.Time{}, err
 	}
 
@@ -248,8 +786,7 @@ func (b *S3Backend) GetChecksum(ctx context.Context) (string, error) {
 		Key:    aws.String(b.objectKey()),
 	})
 	if err != nil {
-		var noSuchKey *types.NoSuchKey
-		if errors.As(err, &noSuchKey) {
+		if isS3NotFound(err) {
 			return "", ErrNotFound
 		}
 		return "", err
@@ -296,3 +833,9 @@ func (b *S3Backend) GetRegion() string {
 func (b *S3Backend) SetRegion(region string) {
 	b.region = region
 }
+
+// SetEndpoint points the backend at an S3-compatible service instead of
+// AWS. Such services are addressed path-style. Empty uses AWS.
+func (b *S3Backend) SetEndpoint(endpoint string) {
+	b.endpoint = endpoint
+}
@@ -0,0 +1,163 @@
+package backend_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mindmorass/yippity-clippity/internal/backend"
+	"github.com/mindmorass/yippity-clippity/internal/backend/backendtest"
+	"github.com/mindmorass/yippity-clippity/internal/metrics"
+	"github.com/mindmorass/yippity-clippity/internal/storage"
+)
+
+func TestS3Conformance(t *testing.T) {
+	backendtest.Run(t, func(t *testing.T) backendtest.Opener {
+		return backendtest.NewFakeS3(t, "clips").Opener(t, "yippity-clippity")
+	})
+}
+
+// backendBytes returns the bytes a backend has moved in direction
+func backendBytes(t *testing.T, b backend.BackendType, direction string) float64 {
+	t.Helper()
+	families, err := metrics.Registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() != "yippity_clippity_backend_bytes_total" {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range m.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["backend"] == string(b) && labels["direction"] == direction {
+				return m.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
+
+func TestS3BytesSentSkipsStoredBlobs(t *testing.T) {
+	ctx := context.Background()
+	b, err := backendtest.NewFakeS3(t, "clips").Opener(t, "yippity-clippity")()
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	b.(*backend.S3Backend).SetFormat(storage.Options{Version: storage.BlobVersion})
+	clip := backendtest.NewClip("device-a", strings.Repeat("a payload worth storing once ", 512))
+
+	start := backendBytes(t, backend.BackendS3, metrics.Sent)
+	if err := b.Write(ctx, clip); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	first := backendBytes(t, backend.BackendS3, metrics.Sent) - start
+
+	// The blob is already stored, only the manifest is uploaded again
+	if err := b.Write(ctx, clip); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	second := backendBytes(t, backend.BackendS3, metrics.Sent) - start - first
+
+	if second <= 0 || second >= first {
+		t.Errorf("bytes sent = %v then %v, want the second write to send only the manifest", first, second)
+	}
+
+	before := backendBytes(t, backend.BackendS3, metrics.Received)
+	if _, err := b.Read(ctx); err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if backendBytes(t, backend.BackendS3, metrics.Received) <= before {
+		t.Error("Read received no bytes")
+	}
+}
+
+func TestS3WriteRefreshesOldBlobInPlace(t *testing.T) {
+	ctx := context.Background()
+	fake := backendtest.NewFakeS3(t, "clips")
+	b, err := fake.Opener(t, "yippity-clippity")()
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	b.(*backend.S3Backend).SetFormat(storage.Options{Version: storage.BlobVersion})
+	clip := backendtest.NewClip("device-a", strings.Repeat("a payload worth storing once ", 512))
+	key := "yippity-clippity/" + backend.S3BlobPrefix + clip.Checksum
+
+	start := backendBytes(t, backend.BackendS3, metrics.Sent)
+	if err := b.Write(ctx, clip); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	first := backendBytes(t, backend.BackendS3, metrics.Sent) - start
+
+	// Close to collection, the blob is refreshed rather than uploaded
+	if !fake.Age(key, backend.BlobGCGrace) {
+		t.Fatalf("no blob at %s", key)
+	}
+	if err := b.Write(ctx, clip); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	second := backendBytes(t, backend.BackendS3, metrics.Sent) - start - first
+
+	if second <= 0 || second >= first {
+		t.Errorf("bytes sent = %v then %v, want the second write to send only the manifest", first, second)
+	}
+	if modified, _ := fake.LastModified(key); time.Since(modified) > time.Minute {
+		t.Errorf("blob last modified %v ago, want it refreshed", time.Since(modified))
+	}
+
+	got, err := b.Read(ctx)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if got == nil || string(got.Data) != string(clip.Data) {
+		t.Error("Read after the refresh did not return the clip")
+	}
+}
+
+func TestS3WriteExposesExpiry(t *testing.T) {
+	ctx := context.Background()
+	fake := backendtest.NewFakeS3(t, "clips")
+	key := "yippity-clippity/" + backend.S3ObjectKey
+	expiresAt := time.Date(2026, 3, 1, 12, 10, 0, 0, time.UTC)
+
+	for _, tag := range []bool{false, true} {
+		b, err := fake.Opener(t, "yippity-clippity")()
+		if err != nil {
+			t.Fatalf("open: %v", err)
+		}
+		b.(*backend.S3Backend).SetTagExpiring(tag)
+
+		clip := backendtest.NewClip("device-a", "expiring")
+		clip.ExpiresAt = expiresAt
+		if err := b.Write(ctx, clip); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+
+		metadata, tagging, ok := fake.Metadata(key)
+		if !ok {
+			t.Fatalf("no object at %s", key)
+		}
+		if got := metadata[backend.S3ExpiresMetadata]; got != "2026-03-01T12:10:00Z" {
+			t.Errorf("tag %v: %s metadata = %q, want 2026-03-01T12:10:00Z", tag, backend.S3ExpiresMetadata, got)
+		}
+		wantTagging := ""
+		if tag {
+			wantTagging = backend.S3ExpiringTag + "=true"
+		}
+		if tagging != wantTagging {
+			t.Errorf("tag %v: tagging = %q, want %q", tag, tagging, wantTagging)
+		}
+
+		// Clips without an expiry carry neither
+		if err := b.Write(ctx, backendtest.NewClip("device-a", "lasting")); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		if metadata, tagging, _ := fake.Metadata(key); len(metadata) != 0 || tagging != "" {
+			t.Errorf("tag %v: clip without expiry has metadata %v, tagging %q", tag, metadata, tagging)
+		}
+	}
+}
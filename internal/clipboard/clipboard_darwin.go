@@ -108,6 +108,24 @@ int hasTransientData() {
     return 0;
 }
 
+// Get the frontmost application's bundle identifier
+const char* frontmostAppBundleID() {
+    NSRunningApplication *app = [[NSWorkspace sharedWorkspace] frontmostApplication];
+    if (app == nil || [app bundleIdentifier] == nil) {
+        return NULL;
+    }
+    return strdup([[app bundleIdentifier] UTF8String]);
+}
+
+// Get the frontmost application's display name
+const char* frontmostAppName() {
+    NSRunningApplication *app = [[NSWorkspace sharedWorkspace] frontmostApplication];
+    if (app == nil || [app localizedName] == nil) {
+        return NULL;
+    }
+    return strdup([[app localizedName] UTF8String]);
+}
+
 // Free memory allocated by C
 void freeMemory(void* ptr) {
     free(ptr);
@@ -182,6 +200,20 @@ func HasTransientData() bool {
 	return C.hasTransientData() == 1
 }
 
+// FrontmostApp returns the bundle ID and display name of the application
+// the user is working in. Either may be empty if it can't be determined.
+func FrontmostApp() (bundleID, name string) {
+	if cstr := C.frontmostAppBundleID(); cstr != nil {
+		bundleID = C.GoString(cstr)
+		C.freeMemory(unsafe.Pointer(cstr))
+	}
+	if cstr := C.frontmostAppName(); cstr != nil {
+		name = C.GoString(cstr)
+		C.freeMemory(unsafe.Pointer(cstr))
+	}
+	return bundleID, name
+}
+
 // Read reads the current clipboard content
 func Read() (*Content, error) {
 	// Skip transient data (password managers)
@@ -192,6 +224,10 @@ func Read() (*Content, error) {
 	hostname, _ := os.Hostname()
 	username := os.Getenv("USER")
 
+	// The pasteboard doesn't record who wrote to it, so attribute the
+	// change to the app in front when it was noticed
+	sourceApp, _ := FrontmostApp()
+
 	// Check for image first (higher priority)
 	if HasImage() {
 		data, ok := ReadImageData()
@@ -202,6 +238,7 @@ func Read() (*Content, error) {
 				Timestamp:     time.Now().UTC(),
 				SourceMachine: hostname,
 				SourceUser:    username,
+				SourceApp:     sourceApp,
 				ContentType:   ContentTypeImage,
 				MimeType:      "image/png",
 				Checksum:      hex.EncodeToString(checksum[:]),
@@ -222,6 +259,7 @@ func Read() (*Content, error) {
 				Timestamp:     time.Now().UTC(),
 				SourceMachine: hostname,
 				SourceUser:    username,
+				SourceApp:     sourceApp,
 				ContentType:   ContentTypeText,
 				MimeType:      "text/plain",
 				Checksum:      hex.EncodeToString(checksum[:]),
@@ -1,9 +1,11 @@
 package clipboard
 
 import (
-	"log"
+	"log/slog"
 	"sync"
 	"time"
+
+	"github.com/mindmorass/yippity-clippity/internal/clock"
 )
 
 // ChangeHandler is called when clipboard content changes
@@ -11,6 +13,8 @@ type ChangeHandler func(*Content)
 
 // Monitor watches for clipboard changes using polling
 type Monitor struct {
+	provider        Provider
+	clock           clock.Clock
 	interval        time.Duration
 	lastChangeCount int
 	lastChecksum    string
@@ -22,7 +26,15 @@ type Monitor struct {
 
 // NewMonitor creates a new clipboard monitor
 func NewMonitor(interval time.Duration) *Monitor {
+	return NewMonitorWithProvider(interval, System, clock.System)
+}
+
+// NewMonitorWithProvider creates a monitor for a custom clipboard, timed
+// by clk
+func NewMonitorWithProvider(interval time.Duration, p Provider, clk clock.Clock) *Monitor {
 	return &Monitor{
+		provider: p,
+		clock:    clk,
 		interval: interval,
 		stopChan: make(chan struct{}),
 	}
@@ -43,7 +55,7 @@ func (m *Monitor) Start() {
 		return
 	}
 	m.running = true
-	m.lastChangeCount = GetChangeCount()
+	m.lastChangeCount = m.provider.GetChangeCount()
 	m.stopChan = make(chan struct{})
 	m.mu.Unlock()
 
@@ -69,12 +81,12 @@ func (m *Monitor) IsRunning() bool {
 }
 
 func (m *Monitor) run() {
-	ticker := time.NewTicker(m.interval)
+	ticker := m.clock.NewTicker(m.interval)
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-ticker.C:
+		case <-ticker.C():
 			m.checkForChanges()
 		case <-m.stopChan:
 			return
@@ -82,8 +94,14 @@ func (m *Monitor) run() {
 	}
 }
 
+// Poll checks the clipboard once, calling the change handler if it
+// changed. Monitors that aren't started can be driven this way.
+func (m *Monitor) Poll() {
+	m.checkForChanges()
+}
+
 func (m *Monitor) checkForChanges() {
-	currentCount := GetChangeCount()
+	currentCount := m.provider.GetChangeCount()
 
 	m.mu.Lock()
 	lastCount := m.lastChangeCount
@@ -100,9 +118,9 @@ func (m *Monitor) checkForChanges() {
 	m.mu.Unlock()
 
 	// Read clipboard content
-	content, err := Read()
+	content, err := m.provider.Read()
 	if err != nil {
-		log.Printf("Error reading clipboard: %v", err)
+		slog.Warn("Error reading clipboard", "error", err)
 		return
 	}
 	if content == nil {
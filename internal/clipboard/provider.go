@@ -0,0 +1,33 @@
+package clipboard
+
+// Provider gives access to a clipboard. The engine uses the system
+// pasteboard; simulations substitute their own.
+type Provider interface {
+	// GetChangeCount returns a counter that changes whenever the
+	// clipboard does
+	GetChangeCount() int
+
+	// Read returns the clipboard content, or nil if it holds nothing
+	// that can be synced
+	Read() (*Content, error)
+
+	// Write replaces the clipboard content
+	Write(content *Content) bool
+}
+
+// System is the macOS pasteboard
+var System Provider = systemProvider{}
+
+type systemProvider struct{}
+
+func (systemProvider) GetChangeCount() int {
+	return GetChangeCount()
+}
+
+func (systemProvider) Read() (*Content, error) {
+	return Read()
+}
+
+func (systemProvider) Write(content *Content) bool {
+	return Write(content)
+}
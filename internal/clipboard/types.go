@@ -2,6 +2,8 @@ package clipboard
 
 import (
 	"time"
+
+	"github.com/mindmorass/yippity-clippity/internal/hlc"
 )
 
 // ContentType represents the type of clipboard content
@@ -12,17 +14,40 @@ const (
 	ContentTypeImage ContentType = "image"
 )
 
-// Content represents clipboard data with metadata
+// Content represents clipboard data with metadata.
+// Clock orders clips across devices. Timestamp is the wall clock time the
+// clip was copied, for display only.
+// FenceToken is set by backends that fence concurrent writers.
+// Signer and Signature are set when the clip is signed with a device key.
+// SourceApp is the bundle ID of the app the clip was copied from, if known.
+// ExpiresAt is set on clips that must not be applied after that time.
+// Placeholder marks a clip published without its payload, which receivers
+// fetch on demand.
+// OriginalMimeType is the MIME type an image was copied as, when it was
+// re-encoded before sending.
 type Content struct {
-	ID            string      `json:"id"`
-	Timestamp     time.Time   `json:"timestamp"`
-	SourceMachine string      `json:"source_machine"`
-	SourceUser    string      `json:"source_user"`
-	ContentType   ContentType `json:"content_type"`
-	MimeType      string      `json:"mime_type"`
-	Checksum      string      `json:"checksum"`
-	Size          int64       `json:"size"`
-	Data          []byte      `json:"-"` // Payload data, not serialized in header
+	ID               string        `json:"id"`
+	Timestamp        time.Time     `json:"timestamp"`
+	Clock            hlc.Timestamp `json:"clock"`
+	SourceMachine    string        `json:"source_machine"`
+	SourceUser       string        `json:"source_user"`
+	SourceApp        string        `json:"source_app,omitempty"`
+	ContentType      ContentType   `json:"content_type"`
+	MimeType         string        `json:"mime_type"`
+	OriginalMimeType string        `json:"original_mime_type,omitempty"`
+	Checksum         string        `json:"checksum"`
+	Size             int64         `json:"size"`
+	FenceToken       uint64        `json:"fence_token,omitempty"`
+	Signer           string        `json:"signer,omitempty"`
+	Signature        []byte        `json:"signature,omitempty"`
+	ExpiresAt        time.Time     `json:"expires_at,omitempty"`
+	Placeholder      bool          `json:"placeholder,omitempty"`
+	Data             []byte        `json:"-"` // Payload data, not serialized in header
+}
+
+// Expired returns true if the clip has an expiry that has passed
+func (c *Content) Expired(now time.Time) bool {
+	return !c.ExpiresAt.IsZero() && !now.Before(c.ExpiresAt)
 }
 
 // IsText returns true if content is text-based
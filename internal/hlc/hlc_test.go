@@ -0,0 +1,115 @@
+package hlc
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeNow returns a clock reading whose time the test sets
+func fakeNow(t time.Time) (*time.Time, func() time.Time) {
+	now := &t
+	return now, func() time.Time { return *now }
+}
+
+func TestNowIsMonotonic(t *testing.T) {
+	now, read := fakeNow(time.UnixMilli(1000))
+	c := NewClock(read)
+
+	prev := c.Now()
+	for i, step := range []time.Duration{0, 0, time.Millisecond, -time.Second, 0, 2 * time.Second} {
+		*now = now.Add(step)
+		next := c.Now()
+		if next.Compare(prev) <= 0 {
+			t.Fatalf("step %d: Now() = %s, not after %s", i, next, prev)
+		}
+		prev = next
+	}
+
+	// A wall clock that went back doesn't move timestamps back
+	if prev.Wall < 2000 {
+		t.Errorf("Now() = %s, wall time went backwards", prev)
+	}
+}
+
+func TestUpdateFromRemoteAhead(t *testing.T) {
+	_, read := fakeNow(time.UnixMilli(1000))
+	c := NewClock(read)
+	c.Now()
+
+	remote := Timestamp{Wall: 5000, Logical: 3}
+	c.Update(remote)
+
+	next := c.Now()
+	if next.Compare(remote) <= 0 {
+		t.Errorf("Now() after Update = %s, not after the remote %s", next, remote)
+	}
+	if next.Wall != remote.Wall {
+		t.Errorf("Now() wall = %d, want the remote's %d", next.Wall, remote.Wall)
+	}
+}
+
+func TestUpdateFromRemoteBehind(t *testing.T) {
+	_, read := fakeNow(time.UnixMilli(5000))
+	c := NewClock(read)
+	before := c.Now()
+
+	c.Update(Timestamp{Wall: 1000, Logical: 9})
+	if next := c.Now(); next.Compare(before) <= 0 || next.Wall != 5000 {
+		t.Errorf("Now() after an old Update = %s, want after %s at the local wall time", next, before)
+	}
+}
+
+func TestUpdateWithinSameMillisecond(t *testing.T) {
+	_, read := fakeNow(time.UnixMilli(1000))
+	c := NewClock(read)
+	c.Now()
+
+	remote := Timestamp{Wall: 1000, Logical: 7}
+	c.Update(remote)
+	if next := c.Now(); next.Compare(remote) <= 0 {
+		t.Errorf("Now() = %s, not after %s", next, remote)
+	}
+}
+
+func TestParseStringRoundTrip(t *testing.T) {
+	for _, ts := range []Timestamp{
+		{},
+		{Wall: 1700000000000},
+		{Wall: 1700000000000, Logical: 42},
+		{Wall: 1, Logical: 1<<32 - 1},
+	} {
+		got, err := Parse(ts.String())
+		if err != nil {
+			t.Errorf("Parse(%q): %v", ts.String(), err)
+			continue
+		}
+		if got != ts {
+			t.Errorf("Parse(%q) = %+v, want %+v", ts.String(), got, ts)
+		}
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	for _, s := range []string{
+		"1700000000000",
+		"abc:1",
+		"1:abc",
+		"-1:0",
+		"1:-1",
+		"1:4294967296",
+		":",
+	} {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("Parse(%q) succeeded", s)
+		}
+	}
+}
+
+func TestFromTime(t *testing.T) {
+	if !FromTime(time.Time{}).IsZero() {
+		t.Error("FromTime of the zero time isn't zero")
+	}
+	if got := FromTime(time.UnixMilli(1234)); got != (Timestamp{Wall: 1234}) {
+		t.Errorf("FromTime = %+v", got)
+	}
+}
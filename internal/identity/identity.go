@@ -0,0 +1,151 @@
+package identity
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mindmorass/yippity-clippity/internal/secrets"
+)
+
+const (
+	// SecretService is the secret store service name for the device key
+	SecretService = "com.yippityclippity.identity"
+
+	// deviceKeyAccount is the secret store account for the device key
+	deviceKeyAccount = "device_key"
+
+	// KeyFileName holds the device key when no secret store is available
+	KeyFileName = "device.key"
+)
+
+// ErrInvalidKey is returned for malformed public keys
+var ErrInvalidKey = errors.New("invalid public key")
+
+// Identity is this device's Ed25519 signing key pair
+type Identity struct {
+	Name       string
+	PublicKey  ed25519.PublicKey
+	privateKey ed25519.PrivateKey
+}
+
+// Load returns the device identity, generating and saving a new key pair
+// on first use. The private key is kept in the secret store if one is
+// given, otherwise in KeyFileName under dir.
+func Load(store secrets.Store, dir string) (*Identity, error) {
+	var seed []byte
+	var err error
+	if store != nil {
+		seed, err = loadSeedFromStore(store)
+	} else {
+		seed, err = loadSeedFromFile(filepath.Join(dir, KeyFileName))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	name, _ := os.Hostname()
+	key := ed25519.NewKeyFromSeed(seed)
+
+	return &Identity{
+		Name:       name,
+		PublicKey:  key.Public().(ed25519.PublicKey),
+		privateKey: key,
+	}, nil
+}
+
+// KeyID returns the encoded public key that identifies this device
+func (id *Identity) KeyID() string {
+	return EncodeKey(id.PublicKey)
+}
+
+// Fingerprint returns a short form of the public key for display
+func (id *Identity) Fingerprint() string {
+	return Fingerprint(id.KeyID())
+}
+
+// EncodeKey encodes a public key for config files and clip headers
+func EncodeKey(key ed25519.PublicKey) string {
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+// ParseKey decodes a public key produced by EncodeKey
+func ParseKey(s string) (ed25519.PublicKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(raw) != ed25519.PublicKeySize {
+		return nil, ErrInvalidKey
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+// Fingerprint returns a short, human-comparable digest of an encoded key,
+// e.g. "3f2a:91c0:5b7e:d814"
+func Fingerprint(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	h := hex.EncodeToString(sum[:8])
+	return h[0:4] + ":" + h[4:8] + ":" + h[8:12] + ":" + h[12:16]
+}
+
+func loadSeedFromStore(store secrets.Store) ([]byte, error) {
+	seed, err := store.Get(SecretService, deviceKeyAccount)
+	if err == nil {
+		if len(seed) != ed25519.SeedSize {
+			return nil, fmt.Errorf("device key in %s secret store is corrupted", store.Type())
+		}
+		return seed, nil
+	}
+	if !errors.Is(err, secrets.ErrNotFound) {
+		return nil, fmt.Errorf("failed to load device key: %w", err)
+	}
+
+	seed, err = newSeed()
+	if err != nil {
+		return nil, err
+	}
+	if err := store.Set(SecretService, deviceKeyAccount, seed); err != nil {
+		return nil, fmt.Errorf("failed to save device key: %w", err)
+	}
+	return seed, nil
+}
+
+func loadSeedFromFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		seed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(seed) != ed25519.SeedSize {
+			return nil, fmt.Errorf("device key %s is corrupted", path)
+		}
+		return seed, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to load device key: %w", err)
+	}
+
+	seed, err := newSeed()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	encoded := base64.StdEncoding.EncodeToString(seed) + "\n"
+	if err := os.WriteFile(path, []byte(encoded), 0600); err != nil {
+		return nil, fmt.Errorf("failed to save device key: %w", err)
+	}
+	return seed, nil
+}
+
+func newSeed() ([]byte, error) {
+	seed := make([]byte, ed25519.SeedSize)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, fmt.Errorf("failed to generate device key: %w", err)
+	}
+	return seed, nil
+}
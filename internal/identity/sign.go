@@ -0,0 +1,125 @@
+package identity
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+
+	"github.com/mindmorass/yippity-clippity/internal/clipboard"
+)
+
+// signatureContext separates clip signatures from any other use of the key
+const signatureContext = "yippity-clippity clip signature v1\x00"
+
+var (
+	ErrUnsigned     = errors.New("clip is not signed")
+	ErrBadSignature = errors.New("clip signature is invalid")
+	ErrUntrusted    = errors.New("clip is signed by an untrusted device")
+)
+
+// signedFields are the clip fields covered by a signature. The payload is
+// covered through its checksum, which is checked against the data when
+// verifying. Transport details such as compression or fencing tokens are
+// left out so backends may set them after signing.
+type signedFields struct {
+	ID            string `json:"id"`
+	Timestamp     int64  `json:"timestamp"`
+	SourceMachine string `json:"source_machine"`
+	SourceUser    string `json:"source_user"`
+	ContentType   string `json:"content_type"`
+	MimeType      string `json:"mime_type"`
+	Checksum      string `json:"checksum"`
+	Size          int64  `json:"size"`
+	Signer        string `json:"signer"`
+	ExpiresAt     int64  `json:"expires_at,omitempty"`
+	Clock         string `json:"clock,omitempty"`
+
+	SourceApp        string `json:"source_app,omitempty"`
+	OriginalMimeType string `json:"original_mime_type,omitempty"`
+	Placeholder      bool   `json:"placeholder,omitempty"`
+}
+
+// signedMessage returns the bytes a clip signature is computed over.
+// Timestamps are stored with millisecond precision, so only those are
+// signed. An expiry is signed so it can't be stripped to keep a clip alive,
+// and the clock so a clip can't be made to win conflicts. The source app
+// is signed so it can't be forged to dodge app rules, and the placeholder
+// flag so a full clip can't pass as one awaiting its payload.
+func signedMessage(content *clipboard.Content) ([]byte, error) {
+	// Clips without an expiry or clock sign the same fields as before
+	// they existed
+	var expiresAt int64
+	if !content.ExpiresAt.IsZero() {
+		expiresAt = content.ExpiresAt.UnixMilli()
+	}
+
+	fields, err := json.Marshal(signedFields{
+		ID:               content.ID,
+		Timestamp:        content.Timestamp.UnixMilli(),
+		SourceMachine:    content.SourceMachine,
+		SourceUser:       content.SourceUser,
+		ContentType:      string(content.ContentType),
+		MimeType:         content.MimeType,
+		Checksum:         content.Checksum,
+		Size:             content.Size,
+		Signer:           content.Signer,
+		ExpiresAt:        expiresAt,
+		Clock:            content.Clock.String(),
+		SourceApp:        content.SourceApp,
+		OriginalMimeType: content.OriginalMimeType,
+		Placeholder:      content.Placeholder,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(signatureContext), fields...), nil
+}
+
+// Sign signs the header and payload of content with the device key,
+// setting its Signer and Signature
+func (id *Identity) Sign(content *clipboard.Content) error {
+	content.Signer = id.KeyID()
+
+	msg, err := signedMessage(content)
+	if err != nil {
+		return err
+	}
+	content.Signature = ed25519.Sign(id.privateKey, msg)
+	return nil
+}
+
+// verifySignature checks that content carries a valid signature and that
+// its payload, if present, matches the signed checksum
+func verifySignature(content *clipboard.Content) error {
+	if content.Signer == "" || len(content.Signature) == 0 {
+		return ErrUnsigned
+	}
+
+	key, err := ParseKey(content.Signer)
+	if err != nil {
+		return ErrBadSignature
+	}
+
+	msg, err := signedMessage(content)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(key, msg, content.Signature) {
+		return ErrBadSignature
+	}
+
+	// A payload that hasn't been downloaded yet, such as a placeholder's,
+	// is checked once it has been fetched
+	if len(content.Data) == 0 && (content.Placeholder || content.Size > 0) {
+		return nil
+	}
+
+	sum := sha256.Sum256(content.Data)
+	if hex.EncodeToString(sum[:]) != content.Checksum || int64(len(content.Data)) != content.Size {
+		return ErrBadSignature
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package identity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mindmorass/yippity-clippity/internal/clipboard"
+)
+
+func testIdentity(t *testing.T) *Identity {
+	t.Helper()
+	id, err := Load(nil, t.TempDir())
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	return id
+}
+
+func testClip() *clipboard.Content {
+	data := []byte("hello")
+	checksum := sha256.Sum256(data)
+	return &clipboard.Content{
+		ID:               "clip-1",
+		Timestamp:        time.Now(),
+		SourceMachine:    "laptop",
+		SourceUser:       "me",
+		SourceApp:        "com.example.editor",
+		ContentType:      clipboard.ContentTypeImage,
+		MimeType:         "image/jpeg",
+		OriginalMimeType: "image/png",
+		Checksum:         hex.EncodeToString(checksum[:]),
+		Size:             int64(len(data)),
+		Data:             data,
+	}
+}
+
+func TestSignCoversFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(c *clipboard.Content)
+	}{
+		{"source app", func(c *clipboard.Content) { c.SourceApp = "com.example.other" }},
+		{"source app removed", func(c *clipboard.Content) { c.SourceApp = "" }},
+		{"original MIME type", func(c *clipboard.Content) { c.OriginalMimeType = "" }},
+		{"placeholder", func(c *clipboard.Content) { c.Placeholder = true; c.Data = nil }},
+		{"source machine", func(c *clipboard.Content) { c.SourceMachine = "desktop" }},
+		{"expiry", func(c *clipboard.Content) { c.ExpiresAt = time.Now().Add(time.Hour) }},
+	}
+
+	id := testIdentity(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clip := testClip()
+			if err := id.Sign(clip); err != nil {
+				t.Fatalf("Sign: %v", err)
+			}
+			if err := verifySignature(clip); err != nil {
+				t.Fatalf("verify before tampering: %v", err)
+			}
+
+			tt.tamper(clip)
+			if err := verifySignature(clip); !errors.Is(err, ErrBadSignature) {
+				t.Errorf("verify after tampering = %v, want ErrBadSignature", err)
+			}
+		})
+	}
+}
+
+func TestSignedMessageFields(t *testing.T) {
+	clip := testClip()
+	clip.Signer = "key"
+	clip.Timestamp = time.UnixMilli(1700000000000)
+
+	msg, err := signedMessage(clip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := signatureContext + `{"id":"clip-1","timestamp":1700000000000,"source_machine":"laptop","source_user":"me",` +
+		`"content_type":"image","mime_type":"image/jpeg","checksum":"` + clip.Checksum + `","size":5,"signer":"key",` +
+		`"source_app":"` + clip.SourceApp + `","original_mime_type":"` + clip.OriginalMimeType + `"}`
+	if string(msg) != want {
+		t.Errorf("signed message =\n%s\nwant\n%s", msg, want)
+	}
+}
+
+func TestVerifyClipReadWithoutPayload(t *testing.T) {
+	id := testIdentity(t)
+	clip := testClip()
+	if err := id.Sign(clip); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	// The payload is checked once it has been downloaded
+	clip.Data = nil
+	if err := verifySignature(clip); err != nil {
+		t.Errorf("verify without payload: %v", err)
+	}
+
+	clip.Data = []byte("other")
+	if err := verifySignature(clip); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("verify with a different payload = %v, want ErrBadSignature", err)
+	}
+}
@@ -0,0 +1,193 @@
+package identity
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/mindmorass/yippity-clippity/internal/clipboard"
+)
+
+// PeersFileName is the trusted peer list's file name
+const PeersFileName = "peers.json"
+
+// Peer is a device whose clips are trusted
+type Peer struct {
+	Name      string    `json:"name"`
+	PublicKey string    `json:"public_key"`
+	AddedAt   time.Time `json:"added_at"`
+}
+
+// TrustStore is the list of trusted peer devices, kept in a JSON file.
+// The device's own key is always trusted. The file is reloaded when it
+// changes, so peers added from the command line apply to the running app.
+type TrustStore struct {
+	path    string
+	self    *Identity
+	peers   []Peer
+	modTime time.Time
+	mu      sync.Mutex
+}
+
+// LoadTrustStore reads the trusted peer list at path. A missing file is
+// an empty list.
+func LoadTrustStore(path string, self *Identity) (*TrustStore, error) {
+	t := &TrustStore{
+		path: path,
+		self: self,
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t, t.load()
+}
+
+// Peers returns the trusted peers
+func (t *TrustStore) Peers() []Peer {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.reload()
+	peers := make([]Peer, len(t.peers))
+	copy(peers, t.peers)
+	return peers
+}
+
+// Add trusts a peer's public key, replacing any peer with the same key
+func (t *TrustStore) Add(name, publicKey string) error {
+	key, err := ParseKey(publicKey)
+	if err != nil {
+		return err
+	}
+	encoded := EncodeKey(key)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := t.load(); err != nil {
+		return err
+	}
+
+	peers := t.peers[:0]
+	for _, p := range t.peers {
+		if p.PublicKey != encoded {
+			peers = append(peers, p)
+		}
+	}
+	t.peers = append(peers, Peer{
+		Name:      name,
+		PublicKey: encoded,
+		AddedAt:   time.Now(),
+	})
+
+	return t.save()
+}
+
+// Remove stops trusting the peers with the given name or public key and
+// returns how many were removed
+func (t *TrustStore) Remove(nameOrKey string) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := t.load(); err != nil {
+		return 0, err
+	}
+
+	peers := t.peers[:0]
+	for _, p := range t.peers {
+		if p.Name != nameOrKey && p.PublicKey != nameOrKey {
+			peers = append(peers, p)
+		}
+	}
+	removed := len(t.peers) - len(peers)
+	if removed == 0 {
+		return 0, nil
+	}
+
+	t.peers = peers
+	return removed, t.save()
+}
+
+// Verify checks that content is validly signed by this device or a
+// trusted peer and returns the signer
+func (t *TrustStore) Verify(content *clipboard.Content) (Peer, error) {
+	if err := verifySignature(content); err != nil {
+		return Peer{}, err
+	}
+
+	if t.self != nil && content.Signer == t.self.KeyID() {
+		return Peer{Name: t.self.Name, PublicKey: content.Signer}, nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.reload()
+	for _, p := range t.peers {
+		if p.PublicKey == content.Signer {
+			return p, nil
+		}
+	}
+	return Peer{}, fmt.Errorf("%w (%s)", ErrUntrusted, Fingerprint(content.Signer))
+}
+
+// reload re-reads the file if it changed since it was last loaded.
+// Errors keep the previous list.
+func (t *TrustStore) reload() {
+	info, err := os.Stat(t.path)
+	if err != nil || info.ModTime().Equal(t.modTime) {
+		return
+	}
+	t.load()
+}
+
+func (t *TrustStore) load() error {
+	data, err := os.ReadFile(t.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			t.peers = nil
+			return nil
+		}
+		return fmt.Errorf("failed to read trusted peers: %w", err)
+	}
+
+	var peers []Peer
+	if err := json.Unmarshal(data, &peers); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", t.path, err)
+	}
+	t.peers = peers
+
+	if info, err := os.Stat(t.path); err == nil {
+		t.modTime = info.ModTime()
+	}
+	return nil
+}
+
+// save writes the peer list atomically
+func (t *TrustStore) save() error {
+	data, err := json.MarshalIndent(t.peers, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(t.path), 0700); err != nil {
+		return err
+	}
+
+	tempPath := t.path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write trusted peers: %w", err)
+	}
+	if err := os.Rename(tempPath, t.path); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to write trusted peers: %w", err)
+	}
+
+	if info, err := os.Stat(t.path); err == nil {
+		t.modTime = info.ModTime()
+	}
+	return nil
+}
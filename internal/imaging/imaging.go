@@ -0,0 +1,82 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	"image/png"
+	"math"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	// minDimension is the smallest width or height an image is scaled to
+	minDimension = 16
+
+	// shrinkStep is how much further an image is shrunk when the previous
+	// attempt was still too large
+	shrinkStep = 0.8
+)
+
+// ErrTooLarge is returned when an image can't be made small enough
+var ErrTooLarge = errors.New("image can't be downscaled below the size limit")
+
+// Downscale shrinks an image until it encodes to at most maxBytes in its
+// original format. The aspect ratio is kept.
+func Downscale(data []byte, mimeType string, maxBytes int64) ([]byte, error) {
+	if int64(len(data)) <= maxBytes {
+		return data, nil
+	}
+
+	format, ok := FormatOf(mimeType)
+	if !ok {
+		return nil, fmt.Errorf("can't downscale %s images", mimeType)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	// Encoded size grows roughly with the pixel count, so start from the
+	// ratio of the sizes and shrink further if that wasn't enough
+	scale := math.Sqrt(float64(maxBytes) / float64(len(data)))
+	bounds := src.Bounds()
+
+	for {
+		w := int(float64(bounds.Dx()) * scale)
+		h := int(float64(bounds.Dy()) * scale)
+		if w < minDimension || h < minDimension {
+			return nil, ErrTooLarge
+		}
+
+		out, err := encode(Resize(src, w, h), Options{Format: format})
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(out)) <= maxBytes {
+			return out, nil
+		}
+		scale *= shrinkStep
+	}
+}
+
+// Resize scales img to w by h pixels
+func Resize(img image.Image, w, h int) image.Image {
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, img.Bounds(), draw.Src, nil)
+	return dst
+}
+
+func encodePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	if err := encoder.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
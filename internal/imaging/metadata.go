@@ -0,0 +1,60 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+// pngSignature starts every PNG file
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// keptChunks are the ancillary PNG chunks that affect how an image looks.
+// Critical chunks are always kept; everything else, such as eXIf and
+// text chunks that may carry location or device details, is dropped.
+var keptChunks = map[string]bool{
+	"tRNS": true,
+	"gAMA": true,
+	"cHRM": true,
+	"sRGB": true,
+	"iCCP": true,
+	"sBIT": true,
+	"pHYs": true,
+}
+
+// ErrNotPNG is returned when data isn't a PNG image
+var ErrNotPNG = errors.New("not a PNG image")
+
+// StripPNGMetadata removes metadata chunks from a PNG image without
+// re-encoding it
+func StripPNGMetadata(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, ErrNotPNG
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, pngSignature...)
+
+	rest := data[len(pngSignature):]
+	for len(rest) > 0 {
+		if len(rest) < 12 {
+			return nil, ErrNotPNG
+		}
+		length := binary.BigEndian.Uint32(rest)
+		if uint64(length)+12 > uint64(len(rest)) {
+			return nil, ErrNotPNG
+		}
+		chunk := rest[:12+length]
+		rest = rest[12+length:]
+
+		name := string(chunk[4:8])
+		// Critical chunks have an uppercase first letter
+		if name[0]&0x20 == 0 || keptChunks[name] {
+			out = append(out, chunk...)
+		}
+		if name == "IEND" {
+			break
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,183 @@
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"strings"
+)
+
+// Format is an image encoding the pipeline can produce
+type Format string
+
+const (
+	FormatPNG  Format = "png"
+	FormatJPEG Format = "jpeg"
+	FormatWebP Format = "webp"
+)
+
+// MIME types of the pipeline's formats
+const (
+	MimePNG  = "image/png"
+	MimeJPEG = "image/jpeg"
+	MimeWebP = "image/webp"
+)
+
+// DefaultJPEGQuality is used when Options.JPEGQuality isn't set
+const DefaultJPEGQuality = 85
+
+// ParseFormat converts a config value to a Format
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(strings.TrimSpace(s))); f {
+	case FormatPNG, FormatJPEG, FormatWebP:
+		return f, nil
+	case "jpg":
+		return FormatJPEG, nil
+	case "":
+		return FormatPNG, nil
+	default:
+		return "", fmt.Errorf("unknown image format: %q", s)
+	}
+}
+
+// FormatOf returns the format of images with the given MIME type
+func FormatOf(mimeType string) (Format, bool) {
+	switch mimeType {
+	case MimePNG:
+		return FormatPNG, true
+	case MimeJPEG:
+		return FormatJPEG, true
+	case MimeWebP:
+		return FormatWebP, true
+	}
+	return "", false
+}
+
+// MimeType returns the MIME type of images in format f
+func (f Format) MimeType() string {
+	switch f {
+	case FormatJPEG:
+		return MimeJPEG
+	case FormatWebP:
+		return MimeWebP
+	default:
+		return MimePNG
+	}
+}
+
+// IsLossy returns true if images of the MIME type lose detail when encoded
+func IsLossy(mimeType string) bool {
+	return mimeType == MimeJPEG
+}
+
+// Options configures how images are prepared for sending
+type Options struct {
+	// MaxDimension caps the longer side in pixels. Zero keeps the size.
+	MaxDimension int
+
+	// Format is used for images larger than Threshold bytes, if it
+	// makes them smaller. PNG keeps images as they are.
+	Format    Format
+	Threshold int64
+
+	// JPEGQuality is the JPEG quality from 1 to 100
+	JPEGQuality int
+}
+
+// Process prepares a PNG image for sending: metadata is stripped, the
+// image is scaled down to MaxDimension and, above the size threshold,
+// re-encoded in the configured format. It returns the new image and its
+// MIME type.
+func Process(data []byte, opts Options) ([]byte, string, error) {
+	out, err := StripPNGMetadata(data)
+	if err != nil {
+		return nil, "", err
+	}
+
+	config, err := png.DecodeConfig(bytes.NewReader(out))
+	if err != nil {
+		return nil, "", err
+	}
+
+	var img image.Image
+	if w, h, scaled := fitDimensions(config.Width, config.Height, opts.MaxDimension); scaled {
+		if img, err = png.Decode(bytes.NewReader(out)); err != nil {
+			return nil, "", err
+		}
+		img = Resize(img, w, h)
+		if out, err = encodePNG(img); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if opts.Format == FormatPNG || opts.Format == "" || int64(len(out)) <= opts.Threshold {
+		return out, MimePNG, nil
+	}
+
+	if img == nil {
+		if img, err = png.Decode(bytes.NewReader(out)); err != nil {
+			return nil, "", err
+		}
+	}
+
+	encoded, err := encode(img, opts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Keep the PNG if re-encoding didn't help
+	if len(encoded) >= len(out) {
+		return out, MimePNG, nil
+	}
+	return encoded, opts.Format.MimeType(), nil
+}
+
+// fitDimensions scales w by h so the longer side is at most limit
+func fitDimensions(w, h, limit int) (int, int, bool) {
+	if limit <= 0 || (w <= limit && h <= limit) {
+		return w, h, false
+	}
+	if w >= h {
+		return limit, max(1, h*limit/w), true
+	}
+	return max(1, w*limit/h), limit, true
+}
+
+// encode writes img in the configured format
+func encode(img image.Image, opts Options) ([]byte, error) {
+	var buf bytes.Buffer
+
+	switch opts.Format {
+	case FormatJPEG:
+		quality := opts.JPEGQuality
+		if quality <= 0 || quality > 100 {
+			quality = DefaultJPEGQuality
+		}
+		if err := jpeg.Encode(&buf, flatten(img), &jpeg.Options{Quality: quality}); err != nil {
+			return nil, err
+		}
+
+	case FormatWebP:
+		if err := EncodeWebPLossless(&buf, img); err != nil {
+			return nil, err
+		}
+
+	default:
+		return encodePNG(img)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// flatten composites img onto white, as JPEG has no transparency
+func flatten(img image.Image) image.Image {
+	bounds := img.Bounds()
+	dst := image.NewRGBA(bounds)
+	draw.Draw(dst, bounds, image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, bounds, img, bounds.Min, draw.Over)
+	return dst
+}
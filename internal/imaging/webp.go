@@ -0,0 +1,579 @@
+package imaging
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"image"
+	"image/draw"
+	"io"
+)
+
+// Lossless WebP (VP8L) encoder. It applies the subtract-green transform
+// and LZ77 backward references with a single set of prefix codes, which
+// is enough to beat PNG on typical screenshots without the complexity of
+// the full libwebp encoder.
+// See https://developers.google.com/speed/webp/docs/webp_lossless_bitstream_specification
+
+const (
+	vp8lSignature = 0x2f
+
+	// Alphabet sizes of the five prefix codes
+	numLiteralCodes  = 256
+	numLengthCodes   = 24
+	numDistanceCodes = 40
+
+	// maxCodeLength limits the pixel prefix codes; code length codes
+	// are limited to 7 bits
+	maxCodeLength       = 15
+	maxCodeLengthLength = 7
+
+	// LZ77 parameters
+	minMatch      = 3
+	maxMatch      = 4096
+	maxDistance   = 1 << 20
+	hashBits      = 16
+	maxChainDepth = 32
+
+	// distanceCodeOffset is added to plain distances; smaller distance
+	// codes refer to the 2D neighborhood
+	distanceCodeOffset = 120
+)
+
+// codeLengthOrder is the order code length code lengths are written in
+var codeLengthOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// EncodeWebPLossless writes img as a lossless WebP image
+func EncodeWebPLossless(w io.Writer, img image.Image) error {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width < 1 || height < 1 || width > 1<<14 || height > 1<<14 {
+		return ErrTooLarge
+	}
+
+	nrgba, ok := img.(*image.NRGBA)
+	if !ok || nrgba.Rect.Min != (image.Point{}) || nrgba.Stride != 4*width {
+		nrgba = image.NewNRGBA(image.Rect(0, 0, width, height))
+		draw.Draw(nrgba, nrgba.Bounds(), img, bounds.Min, draw.Src)
+	}
+
+	// Pack pixels as ARGB with green subtracted from red and blue
+	pixels := make([]uint32, width*height)
+	hasAlpha := false
+	for i := range pixels {
+		r, g, b, a := nrgba.Pix[4*i], nrgba.Pix[4*i+1], nrgba.Pix[4*i+2], nrgba.Pix[4*i+3]
+		if a != 0xff {
+			hasAlpha = true
+		}
+		pixels[i] = uint32(a)<<24 | uint32(r-g)<<16 | uint32(g)<<8 | uint32(b-g)
+	}
+
+	var bw bitWriter
+	bw.writeBits(vp8lSignature, 8)
+	bw.writeBits(uint32(width-1), 14)
+	bw.writeBits(uint32(height-1), 14)
+	bw.writeBool(hasAlpha)
+	bw.writeBits(0, 3) // version
+
+	// Subtract-green transform, then no more transforms
+	bw.writeBool(true)
+	bw.writeBits(2, 2)
+	bw.writeBool(false)
+
+	bw.writeBool(false) // no color cache
+	bw.writeBool(false) // no meta prefix codes
+
+	encodeImageData(&bw, pixels, width)
+	data := bw.bytes()
+
+	// RIFF container with a single VP8L chunk
+	chunkSize := len(data)
+	padded := chunkSize + chunkSize&1
+	header := make([]byte, 20)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(4+8+padded))
+	copy(header[8:], "WEBP")
+	copy(header[12:], "VP8L")
+	binary.LittleEndian.PutUint32(header[16:], uint32(chunkSize))
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if chunkSize&1 == 1 {
+		_, err := w.Write([]byte{0})
+		return err
+	}
+	return nil
+}
+
+// symbol is a literal pixel or a backward reference
+type symbol struct {
+	pixel    uint32
+	length   int // 0 for a literal
+	distance int // distance code, before prefix coding
+}
+
+// encodeImageData writes the prefix codes and entropy-coded pixels
+func encodeImageData(bw *bitWriter, pixels []uint32, width int) {
+	symbols := findMatches(pixels, width)
+
+	green := make([]int, numLiteralCodes+numLengthCodes)
+	red := make([]int, numLiteralCodes)
+	blue := make([]int, numLiteralCodes)
+	alpha := make([]int, numLiteralCodes)
+	dist := make([]int, numDistanceCodes)
+
+	for _, s := range symbols {
+		if s.length == 0 {
+			green[s.pixel>>8&0xff]++
+			red[s.pixel>>16&0xff]++
+			blue[s.pixel&0xff]++
+			alpha[s.pixel>>24]++
+			continue
+		}
+		code, _, _ := prefixEncode(s.length)
+		green[numLiteralCodes+code]++
+		code, _, _ = prefixEncode(s.distance)
+		dist[code]++
+	}
+
+	codes := make([]prefixCode, 5)
+	for i, freq := range [][]int{green, red, blue, alpha, dist} {
+		codes[i] = newPrefixCode(freq, maxCodeLength)
+		codes[i].writeHeader(bw)
+	}
+
+	for _, s := range symbols {
+		if s.length == 0 {
+			codes[0].write(bw, int(s.pixel>>8&0xff))
+			codes[1].write(bw, int(s.pixel>>16&0xff))
+			codes[2].write(bw, int(s.pixel&0xff))
+			codes[3].write(bw, int(s.pixel>>24))
+			continue
+		}
+		code, extraBits, extra := prefixEncode(s.length)
+		codes[0].write(bw, numLiteralCodes+code)
+		bw.writeBits(extra, extraBits)
+		code, extraBits, extra = prefixEncode(s.distance)
+		codes[4].write(bw, code)
+		bw.writeBits(extra, extraBits)
+	}
+}
+
+// findMatches turns pixels into literals and backward references using
+// greedy matching over a hash chain
+func findMatches(pixels []uint32, width int) []symbol {
+	n := len(pixels)
+	head := make([]int32, 1<<hashBits)
+	for i := range head {
+		head[i] = -1
+	}
+	prev := make([]int32, n)
+
+	hash := func(i int) uint32 {
+		h := pixels[i]*0x9e3779b1 ^ pixels[i+1]*0x85ebca6b ^ pixels[i+2]*0xc2b2ae35
+		return h >> (32 - hashBits)
+	}
+	insert := func(i int) {
+		if i+minMatch > n {
+			return
+		}
+		h := hash(i)
+		prev[i] = head[h]
+		head[h] = int32(i)
+	}
+
+	symbols := make([]symbol, 0, n/2)
+	for i := 0; i < n; {
+		bestLen, bestDist := 0, 0
+		if i+minMatch <= n {
+			limit := n - i
+			if limit > maxMatch {
+				limit = maxMatch
+			}
+			candidate := head[hash(i)]
+			for depth := 0; candidate >= 0 && depth < maxChainDepth; depth++ {
+				d := i - int(candidate)
+				if d > maxDistance-distanceCodeOffset {
+					break
+				}
+				l := 0
+				for l < limit && pixels[int(candidate)+l] == pixels[i+l] {
+					l++
+				}
+				if l > bestLen {
+					bestLen, bestDist = l, d
+					if l == limit {
+						break
+					}
+				}
+				candidate = prev[candidate]
+			}
+		}
+
+		if bestLen >= minMatch {
+			symbols = append(symbols, symbol{length: bestLen, distance: distanceCode(bestDist, width)})
+			for j := 0; j < bestLen; j++ {
+				insert(i + j)
+			}
+			i += bestLen
+			continue
+		}
+
+		symbols = append(symbols, symbol{pixel: pixels[i]})
+		insert(i)
+		i++
+	}
+	return symbols
+}
+
+// distanceCode maps a linear distance to a distance code. The pixel above
+// and the pixel to the left have short neighborhood codes.
+func distanceCode(d, width int) int {
+	switch d {
+	case width:
+		return 1
+	case 1:
+		return 2
+	}
+	return d + distanceCodeOffset
+}
+
+// prefixEncode splits a length or distance code value into its prefix
+// symbol and extra bits
+func prefixEncode(v int) (code int, extraBits uint, extra uint32) {
+	d := v - 1
+	if d < 4 {
+		return d, 0, 0
+	}
+	h := 31
+	for d>>uint(h) == 0 {
+		h--
+	}
+	second := (d >> uint(h-1)) & 1
+	extraBits = uint(h - 1)
+	return 2*h + second, extraBits, uint32(d) & (1<<extraBits - 1)
+}
+
+// prefixCode is a canonical Huffman code
+type prefixCode struct {
+	lengths []int
+	codes   []uint32
+
+	// single is the only symbol of a code that needs no bits, or -1
+	single int
+}
+
+// newPrefixCode builds a length-limited code for the given frequencies.
+// An alphabet with at most one used symbol below 256 gets a code that
+// takes no bits; otherwise the code has at least two symbols so decoders
+// see a complete code.
+func newPrefixCode(freq []int, limit int) prefixCode {
+	used, last := 0, 0
+	for s, f := range freq {
+		if f > 0 {
+			used++
+			last = s
+		}
+	}
+
+	pc := prefixCode{
+		lengths: make([]int, len(freq)),
+		codes:   make([]uint32, len(freq)),
+		single:  -1,
+	}
+	switch {
+	case used == 0:
+		pc.single = 0
+		return pc
+	case used == 1 && last < 256:
+		pc.single = last
+		return pc
+	case used == 1:
+		// Pair the symbol with symbol 0 for a complete one-bit code
+		pc.lengths[0], pc.lengths[last] = 1, 1
+		pc.assignCodes()
+		return pc
+	}
+
+	pc.lengths = huffmanLengths(freq, limit)
+	pc.assignCodes()
+	return pc
+}
+
+// usedSymbols returns the symbols with a non-zero length
+func (pc *prefixCode) usedSymbols() []int {
+	var used []int
+	for s, l := range pc.lengths {
+		if l > 0 {
+			used = append(used, s)
+		}
+	}
+	return used
+}
+
+// assignCodes assigns canonical codes, bit-reversed for LSB-first output
+func (pc *prefixCode) assignCodes() {
+	var count [maxCodeLength + 2]int
+	for _, l := range pc.lengths {
+		count[l]++
+	}
+	count[0] = 0
+
+	var next [maxCodeLength + 2]uint32
+	code := uint32(0)
+	for l := 1; l <= maxCodeLength; l++ {
+		code = (code + uint32(count[l-1])) << 1
+		next[l] = code
+	}
+
+	for s, l := range pc.lengths {
+		if l == 0 {
+			continue
+		}
+		pc.codes[s] = reverseBits(next[l], l)
+		next[l]++
+	}
+}
+
+// write writes a symbol. Symbols of a single-symbol code take no bits.
+func (pc *prefixCode) write(bw *bitWriter, s int) {
+	bw.writeBits(pc.codes[s], uint(pc.lengths[s]))
+}
+
+// writeHeader writes the code, using the simple form when possible
+func (pc *prefixCode) writeHeader(bw *bitWriter) {
+	if pc.single >= 0 {
+		bw.writeBool(true)
+		bw.writeBits(0, 1)
+		if pc.single < 2 {
+			bw.writeBits(0, 1)
+			bw.writeBits(uint32(pc.single), 1)
+		} else {
+			bw.writeBits(1, 1)
+			bw.writeBits(uint32(pc.single), 8)
+		}
+		return
+	}
+
+	used := pc.usedSymbols()
+	if len(used) == 2 && used[1] < 256 {
+		bw.writeBool(true)
+		bw.writeBits(1, 1)
+		if used[0] < 2 {
+			bw.writeBits(0, 1)
+			bw.writeBits(uint32(used[0]), 1)
+		} else {
+			bw.writeBits(1, 1)
+			bw.writeBits(uint32(used[0]), 8)
+		}
+		bw.writeBits(uint32(used[1]), 8)
+		return
+	}
+
+	// Normal code: run-length encode the code lengths, then code those
+	// with the code length code
+	type token struct {
+		code  int
+		extra uint32
+		bits  uint
+	}
+	var tokens []token
+	lengths := pc.lengths
+	for i := 0; i < len(lengths); {
+		l := lengths[i]
+		run := 1
+		for i+run < len(lengths) && lengths[i+run] == l {
+			run++
+		}
+		i += run
+
+		if l == 0 {
+			for run > 0 {
+				switch {
+				case run >= 11:
+					k := min(run, 138)
+					tokens = append(tokens, token{18, uint32(k - 11), 7})
+					run -= k
+				case run >= 3:
+					tokens = append(tokens, token{17, uint32(run - 3), 3})
+					run = 0
+				default:
+					tokens = append(tokens, token{0, 0, 0})
+					run--
+				}
+			}
+			continue
+		}
+
+		tokens = append(tokens, token{l, 0, 0})
+		run--
+		for run >= 3 {
+			k := min(run, 6)
+			tokens = append(tokens, token{16, uint32(k - 3), 2})
+			run -= k
+		}
+		for ; run > 0; run-- {
+			tokens = append(tokens, token{l, 0, 0})
+		}
+	}
+
+	freq := make([]int, len(codeLengthOrder))
+	for _, t := range tokens {
+		freq[t.code]++
+	}
+	lengthCode := newPrefixCode(freq, maxCodeLengthLength)
+	if lengthCode.single >= 0 {
+		// One distinct token: give it a partner so the code is complete
+		other := 0
+		if tokens[0].code == 0 {
+			other = 1
+		}
+		lengthCode.lengths[tokens[0].code] = 1
+		lengthCode.lengths[other] = 1
+		lengthCode.single = -1
+		lengthCode.assignCodes()
+	}
+
+	numCodes := len(codeLengthOrder)
+	for numCodes > 4 && lengthCode.lengths[codeLengthOrder[numCodes-1]] == 0 {
+		numCodes--
+	}
+
+	bw.writeBool(false)
+	bw.writeBits(uint32(numCodes-4), 4)
+	for i := 0; i < numCodes; i++ {
+		bw.writeBits(uint32(lengthCode.lengths[codeLengthOrder[i]]), 3)
+	}
+	bw.writeBool(false) // code lengths for the whole alphabet follow
+
+	for _, t := range tokens {
+		lengthCode.write(bw, t.code)
+		bw.writeBits(t.extra, t.bits)
+	}
+}
+
+// huffmanLengths computes code lengths no longer than limit. If the
+// optimal code is too deep, rare symbols are made more common until it
+// fits.
+func huffmanLengths(freq []int, limit int) []int {
+	counts := make([]int, len(freq))
+	copy(counts, freq)
+
+	for minCount := 1; ; minCount *= 2 {
+		lengths := buildHuffman(counts)
+		deepest := 0
+		for _, l := range lengths {
+			deepest = max(deepest, l)
+		}
+		if deepest <= limit {
+			return lengths
+		}
+		for i, f := range counts {
+			if f > 0 && f < minCount {
+				counts[i] = minCount
+			}
+		}
+	}
+}
+
+// huffmanNode is a node of the Huffman tree being built
+type huffmanNode struct {
+	count       int
+	symbol      int
+	left, right *huffmanNode
+}
+
+type nodeHeap []*huffmanNode
+
+func (h nodeHeap) Len() int { return len(h) }
+func (h nodeHeap) Less(i, j int) bool {
+	if h[i].count != h[j].count {
+		return h[i].count < h[j].count
+	}
+	return h[i].symbol < h[j].symbol
+}
+func (h nodeHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *nodeHeap) Push(x any)   { *h = append(*h, x.(*huffmanNode)) }
+func (h *nodeHeap) Pop() any {
+	old := *h
+	n := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return n
+}
+
+// buildHuffman returns optimal code lengths for symbols with a count
+func buildHuffman(counts []int) []int {
+	h := &nodeHeap{}
+	for s, c := range counts {
+		if c > 0 {
+			heap.Push(h, &huffmanNode{count: c, symbol: s})
+		}
+	}
+
+	// Internal nodes sort after leaves with the same count
+	next := len(counts)
+	for h.Len() > 1 {
+		a := heap.Pop(h).(*huffmanNode)
+		b := heap.Pop(h).(*huffmanNode)
+		heap.Push(h, &huffmanNode{count: a.count + b.count, symbol: next, left: a, right: b})
+		next++
+	}
+
+	lengths := make([]int, len(counts))
+	var walk func(n *huffmanNode, depth int)
+	walk = func(n *huffmanNode, depth int) {
+		if n.left == nil {
+			lengths[n.symbol] = depth
+			return
+		}
+		walk(n.left, depth+1)
+		walk(n.right, depth+1)
+	}
+	walk(heap.Pop(h).(*huffmanNode), 0)
+	return lengths
+}
+
+func reverseBits(v uint32, n int) uint32 {
+	var r uint32
+	for i := 0; i < n; i++ {
+		r = r<<1 | v&1
+		v >>= 1
+	}
+	return r
+}
+
+// bitWriter packs bits least significant first
+type bitWriter struct {
+	buf   []byte
+	acc   uint64
+	nbits uint
+}
+
+func (bw *bitWriter) writeBits(v uint32, n uint) {
+	bw.acc |= uint64(v) << bw.nbits
+	bw.nbits += n
+	for bw.nbits >= 8 {
+		bw.buf = append(bw.buf, byte(bw.acc))
+		bw.acc >>= 8
+		bw.nbits -= 8
+	}
+}
+
+func (bw *bitWriter) writeBool(b bool) {
+	if b {
+		bw.writeBits(1, 1)
+	} else {
+		bw.writeBits(0, 1)
+	}
+}
+
+func (bw *bitWriter) bytes() []byte {
+	if bw.nbits > 0 {
+		bw.buf = append(bw.buf, byte(bw.acc))
+		bw.acc, bw.nbits = 0, 0
+	}
+	return bw.buf
+}
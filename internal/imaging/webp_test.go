@@ -0,0 +1,102 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+// testImages returns images that exercise the encoder's literal, cache
+// and backward reference paths
+func testImages() map[string]image.Image {
+	rng := rand.New(rand.NewSource(1))
+	images := make(map[string]image.Image)
+
+	single := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	single.Set(0, 0, color.NRGBA{R: 200, G: 10, B: 30, A: 255})
+	images["1x1"] = single
+
+	noise := image.NewNRGBA(image.Rect(0, 0, 97, 61))
+	rng.Read(noise.Pix)
+	images["noise with alpha"] = noise
+
+	gradient := image.NewNRGBA(image.Rect(0, 0, 256, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 256; x++ {
+			gradient.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y * 4), B: uint8(255 - x), A: 255})
+		}
+	}
+	images["gradient"] = gradient
+
+	// Flat areas and repeated rows, like a screenshot of text
+	screen := image.NewNRGBA(image.Rect(0, 0, 640, 120))
+	for y := 0; y < 120; y++ {
+		for x := 0; x < 640; x++ {
+			c := color.NRGBA{R: 250, G: 250, B: 250, A: 255}
+			if (y/6)%3 == 1 && (x/5)%4 != 0 {
+				c = color.NRGBA{R: 20, G: 20, B: 30, A: 255}
+			}
+			screen.Set(x, y, c)
+		}
+	}
+	images["screenshot"] = screen
+
+	// A run far longer than the longest backward reference
+	flat := image.NewNRGBA(image.Rect(0, 0, 300, 50))
+	for i := range flat.Pix {
+		flat.Pix[i] = 0x7f
+	}
+	images["flat"] = flat
+
+	transparent := image.NewNRGBA(image.Rect(0, 0, 33, 17))
+	for i := 0; i < len(transparent.Pix); i += 4 {
+		transparent.Pix[i] = uint8(i)
+		transparent.Pix[i+1] = uint8(i >> 3)
+		transparent.Pix[i+2] = uint8(i >> 5)
+		transparent.Pix[i+3] = uint8(rng.Intn(2) * 255)
+	}
+	images["transparent"] = transparent
+
+	tall := image.NewRGBA(image.Rect(5, 5, 8, 400))
+	rng.Read(tall.Pix)
+	for i := 3; i < len(tall.Pix); i += 4 {
+		tall.Pix[i] = 255
+	}
+	images["offset RGBA"] = tall
+
+	return images
+}
+
+func TestWebPRoundTrip(t *testing.T) {
+	for name, img := range testImages() {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := EncodeWebPLossless(&buf, img); err != nil {
+				t.Fatalf("EncodeWebPLossless: %v", err)
+			}
+
+			decoded, err := webp.Decode(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("x/image/webp can't decode the image: %v", err)
+			}
+
+			bounds := img.Bounds()
+			if decoded.Bounds().Dx() != bounds.Dx() || decoded.Bounds().Dy() != bounds.Dy() {
+				t.Fatalf("decoded size = %v, want %v", decoded.Bounds().Size(), bounds.Size())
+			}
+			for y := 0; y < bounds.Dy(); y++ {
+				for x := 0; x < bounds.Dx(); x++ {
+					want := color.NRGBAModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y))
+					got := color.NRGBAModel.Convert(decoded.At(decoded.Bounds().Min.X+x, decoded.Bounds().Min.Y+y))
+					if got != want {
+						t.Fatalf("pixel (%d, %d) = %v, want %v", x, y, got, want)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const (
+	// FileName is the name of the current log file. Rotated files get a
+	// timestamp added to the name.
+	FileName = "yippity-clippity.log"
+
+	// MaxSizeMB is the size a log file is rotated at
+	MaxSizeMB = 10
+
+	// MaxBackups is how many rotated log files are kept
+	MaxBackups = 5
+
+	// MaxAgeDays is how long rotated log files are kept
+	MaxAgeDays = 30
+)
+
+// Attribute keys shared by log records
+const (
+	KeyDevice  = "device"
+	KeyBackend = "backend"
+	KeyClip    = "clip_id"
+	KeyError   = "error"
+)
+
+// Format is how log records are written
+type Format string
+
+const (
+	FormatText Format = "text"
+	FormatJSON Format = "json"
+)
+
+// ParseFormat parses a log format from the config file
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(strings.TrimSpace(s))); f {
+	case FormatText, FormatJSON:
+		return f, nil
+	case "":
+		return FormatText, nil
+	default:
+		return "", fmt.Errorf("unknown log format: %q", s)
+	}
+}
+
+// ParseLevel parses a log level from the config file: "debug", "info",
+// "warn" or "error"
+func ParseLevel(s string) (slog.Level, error) {
+	if strings.TrimSpace(s) == "" {
+		return slog.LevelInfo, nil
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(s))); err != nil {
+		return slog.LevelInfo, fmt.Errorf("unknown log level: %q", s)
+	}
+	return level, nil
+}
+
+// Options configures logging
+type Options struct {
+	Level  slog.Level
+	Format Format
+
+	// Dir is the directory holding the log files
+	Dir string
+}
+
+// Setup makes the default logger write to a size-rotated file in
+// opts.Dir and to stderr. Output of the standard log package goes
+// through it too. The returned closer closes the log file.
+func Setup(opts Options) (io.Closer, error) {
+	if err := os.MkdirAll(opts.Dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	file := &lumberjack.Logger{
+		Filename:   filepath.Join(opts.Dir, FileName),
+		MaxSize:    MaxSizeMB,
+		MaxBackups: MaxBackups,
+		MaxAge:     MaxAgeDays,
+	}
+	w := io.MultiWriter(file, ignoreErrors{os.Stderr})
+
+	handlerOpts := &slog.HandlerOptions{Level: opts.Level}
+	var handler slog.Handler
+	if opts.Format == FormatJSON {
+		handler = slog.NewJSONHandler(w, handlerOpts)
+	} else {
+		handler = slog.NewTextHandler(w, handlerOpts)
+	}
+
+	slog.SetDefault(slog.New(handler))
+	return file, nil
+}
+
+// ignoreErrors is a writer whose failures are ignored, so a closed
+// stderr doesn't stop records reaching the writers after it
+type ignoreErrors struct {
+	w io.Writer
+}
+
+func (w ignoreErrors) Write(p []byte) (int, error) {
+	w.w.Write(p)
+	return len(p), nil
+}
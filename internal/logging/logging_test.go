@@ -0,0 +1,128 @@
+package logging
+
+import (
+	"encoding/json"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		in   string
+		want slog.Level
+	}{
+		{"", slog.LevelInfo},
+		{"debug", slog.LevelDebug},
+		{"info", slog.LevelInfo},
+		{" WARN ", slog.LevelWarn},
+		{"error", slog.LevelError},
+	}
+	for _, tt := range tests {
+		got, err := ParseLevel(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseLevel(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Error("ParseLevel(\"loud\") succeeded, want an error")
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Format
+	}{
+		{"", FormatText},
+		{"text", FormatText},
+		{" JSON ", FormatJSON},
+	}
+	for _, tt := range tests {
+		got, err := ParseFormat(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseFormat(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Error("ParseFormat(\"xml\") succeeded, want an error")
+	}
+}
+
+// setup runs Setup with stderr closed, restoring the default logger and
+// stderr when the test ends, and returns the path of the log file
+func setup(t *testing.T, opts Options) string {
+	t.Helper()
+	stderr, err := os.CreateTemp(t.TempDir(), "stderr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr.Close()
+
+	defaultLogger, defaultStderr := slog.Default(), os.Stderr
+	t.Cleanup(func() {
+		slog.SetDefault(defaultLogger)
+		os.Stderr = defaultStderr
+	})
+	os.Stderr = stderr
+
+	opts.Dir = filepath.Join(t.TempDir(), "logs")
+	closer, err := Setup(opts)
+	if err != nil {
+		t.Fatalf("Setup: %v", err)
+	}
+	t.Cleanup(func() { closer.Close() })
+	return filepath.Join(opts.Dir, FileName)
+}
+
+func readLog(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+	return string(data)
+}
+
+func TestSetupText(t *testing.T) {
+	path := setup(t, Options{Level: slog.LevelInfo, Format: FormatText})
+
+	slog.Debug("too detailed")
+	slog.Info("clip written", KeyClip, "clip-1")
+	log.Print("from the log package")
+
+	// Records reach the file even though stderr can't be written
+	got := readLog(t, path)
+	if strings.Contains(got, "too detailed") {
+		t.Errorf("debug record logged at info level:\n%s", got)
+	}
+	if !strings.Contains(got, `msg="clip written" clip_id=clip-1`) {
+		t.Errorf("log missing the info record:\n%s", got)
+	}
+	if !strings.Contains(got, "from the log package") {
+		t.Errorf("log missing the log package output:\n%s", got)
+	}
+
+	if info, err := os.Stat(filepath.Dir(path)); err != nil || info.Mode().Perm() != 0700 {
+		t.Errorf("log directory mode = %v, %v, want 0700", info.Mode().Perm(), err)
+	}
+}
+
+func TestSetupJSON(t *testing.T) {
+	path := setup(t, Options{Level: slog.LevelDebug, Format: FormatJSON})
+
+	slog.Debug("polled", KeyBackend, "local")
+
+	var record map[string]any
+	if err := json.Unmarshal([]byte(readLog(t, path)), &record); err != nil {
+		t.Fatalf("log is not one JSON record: %v", err)
+	}
+	if record["level"] != "DEBUG" || record["msg"] != "polled" || record[KeyBackend] != "local" {
+		t.Errorf("record = %v, want a debug record from the local backend", record)
+	}
+}
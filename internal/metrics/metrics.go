@@ -0,0 +1,130 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const namespace = "yippity_clippity"
+
+// Backend operation names
+const (
+	OpWrite      = "write"
+	OpRead       = "read"
+	OpReadHeader = "read_header"
+	OpModTime    = "mod_time"
+	OpFetch      = "fetch"
+	OpRemove     = "remove"
+	OpCollect    = "collect_garbage"
+)
+
+// Backend operation outcomes
+const (
+	OutcomeOK       = "ok"
+	OutcomeNotFound = "not_found"
+	OutcomeConflict = "conflict"
+	OutcomeLocked   = "locked"
+	OutcomeError    = "error"
+)
+
+// Transfer directions
+const (
+	Sent     = "sent"
+	Received = "received"
+)
+
+// Registry holds every metric the app exports
+var Registry = prometheus.NewRegistry()
+
+var (
+	backendOps = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "backend_operations_total",
+		Help:      "Backend operations by backend, operation and outcome.",
+	}, []string{"backend", "op", "outcome"})
+
+	backendDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "backend_operation_duration_seconds",
+		Help:      "How long backend operations took.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"backend", "op"})
+
+	backendBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "backend_bytes_total",
+		Help:      "Bytes of stored clips and blobs sent to and received from the backend.",
+	}, []string{"backend", "direction"})
+
+	lockContention = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "backend_lock_contention_total",
+		Help:      "Writes that failed because another device held or took over the lock.",
+	}, []string{"backend"})
+
+	polls = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "watcher_polls_total",
+		Help:      "Checks of the shared location for remote changes.",
+	})
+
+	pollInterval = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "watcher_poll_interval_seconds",
+		Help:      "Current adaptive polling interval.",
+	})
+
+	applyLatency = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "sync_apply_latency_seconds",
+		Help:      "Time from a clip being copied on its source device to being applied locally.",
+		Buckets:   prometheus.ExponentialBuckets(0.05, 2, 12),
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		backendOps,
+		backendDuration,
+		backendBytes,
+		lockContention,
+		polls,
+		pollInterval,
+		applyLatency,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// ObserveBackend records a backend operation that took d and ended with
+// outcome
+func ObserveBackend(backend, op string, d time.Duration, outcome string) {
+	backendOps.WithLabelValues(backend, op, outcome).Inc()
+	backendDuration.WithLabelValues(backend, op).Observe(d.Seconds())
+	if outcome == OutcomeLocked {
+		lockContention.WithLabelValues(backend).Inc()
+	}
+}
+
+// AddBytes records n bytes moved in direction by a backend
+func AddBytes(backend, direction string, n int64) {
+	backendBytes.WithLabelValues(backend, direction).Add(float64(n))
+}
+
+// ObservePoll records a check of the shared location
+func ObservePoll() {
+	polls.Inc()
+}
+
+// SetPollInterval records the current polling interval
+func SetPollInterval(d time.Duration) {
+	pollInterval.Set(d.Seconds())
+}
+
+// ObserveApplyLatency records how long a clip took from being copied to
+// being applied. Negative values from clock skew count as zero.
+func ObserveApplyLatency(d time.Duration) {
+	applyLatency.Observe(max(d, 0).Seconds())
+}
@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// scrape fetches the metrics from a server started by Serve
+func scrape(t *testing.T) string {
+	t.Helper()
+	server, err := Serve("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+
+	resp, err := http.Get("http://" + server.Addr + Path)
+	if err != nil {
+		t.Fatalf("GET %s: %v", Path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s: status %d", Path, resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read metrics: %v", err)
+	}
+	return string(body)
+}
+
+func TestServeExportsMetrics(t *testing.T) {
+	ObserveBackend("test", OpWrite, 20*time.Millisecond, OutcomeOK)
+	ObserveBackend("test", OpRead, time.Millisecond, OutcomeNotFound)
+	ObserveBackend("test", OpWrite, time.Millisecond, OutcomeLocked)
+	AddBytes("test", Sent, 300)
+	AddBytes("test", Received, 120)
+	ObservePoll()
+	ObservePoll()
+	SetPollInterval(1500 * time.Millisecond)
+	ObserveApplyLatency(200 * time.Millisecond)
+	ObserveApplyLatency(-time.Second)
+
+	body := scrape(t)
+	for _, want := range []string{
+		`yippity_clippity_backend_operations_total{backend="test",op="write",outcome="ok"} 1`,
+		`yippity_clippity_backend_operations_total{backend="test",op="read",outcome="not_found"} 1`,
+		`yippity_clippity_backend_operations_total{backend="test",op="write",outcome="locked"} 1`,
+		`yippity_clippity_backend_operation_duration_seconds_count{backend="test",op="write"} 2`,
+		`yippity_clippity_backend_bytes_total{backend="test",direction="sent"} 300`,
+		`yippity_clippity_backend_bytes_total{backend="test",direction="received"} 120`,
+		`yippity_clippity_backend_lock_contention_total{backend="test"} 1`,
+		`yippity_clippity_watcher_polls_total 2`,
+		`yippity_clippity_watcher_poll_interval_seconds 1.5`,
+		`yippity_clippity_sync_apply_latency_seconds_count 2`,
+		`yippity_clippity_sync_apply_latency_seconds_sum 0.2`,
+		`go_goroutines `,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %q", want)
+		}
+	}
+}
+
+func TestServeRejectsNonLoopback(t *testing.T) {
+	for _, addr := range []string{"0.0.0.0:9464", ":9464", "192.0.2.1:9464", "example.com:9464", "127.0.0.1"} {
+		if server, err := Serve(addr); err == nil {
+			server.Close()
+			t.Errorf("Serve(%q) succeeded, want an error", addr)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package notify
+
+import (
+	"errors"
+	"log/slog"
+)
+
+// AppName is shown as the sender of notifications
+const AppName = "Yippity-Clippity"
+
+// ErrUnsupported is returned when the platform has no notifier
+var ErrUnsupported = errors.New("notifications not supported on this platform")
+
+// Notifier shows desktop notifications
+type Notifier interface {
+	Notify(title, message string) error
+}
+
+// Nop discards notifications. Use it in tests.
+type Nop struct{}
+
+// Notify does nothing
+func (Nop) Notify(title, message string) error {
+	return nil
+}
+
+// Default returns the native notifier for this platform, or Nop if there
+// is none
+func Default() Notifier {
+	n, err := openPlatformNotifier()
+	if err != nil {
+		slog.Warn("Desktop notifications unavailable", "error", err)
+		return Nop{}
+	}
+	return n
+}
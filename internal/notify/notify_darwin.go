@@ -0,0 +1,109 @@
+//go:build darwin
+
+package notify
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework Foundation -framework UserNotifications
+
+#include <stdlib.h>
+#import <Foundation/Foundation.h>
+#import <UserNotifications/UserNotifications.h>
+
+// Show banners even while the menu is open, which counts as the app being
+// in the foreground
+@interface NotificationDelegate : NSObject <UNUserNotificationCenterDelegate>
+@end
+
+@implementation NotificationDelegate
+- (void)userNotificationCenter:(UNUserNotificationCenter*)center
+       willPresentNotification:(UNNotification*)notification
+         withCompletionHandler:(void (^)(UNNotificationPresentationOptions))completionHandler {
+    completionHandler(UNNotificationPresentationOptionBanner | UNNotificationPresentationOptionList);
+}
+@end
+
+static NotificationDelegate* notificationDelegate = nil;
+
+// The notification center can only be used from an app bundle
+int inAppBundle(void) {
+    return [[NSBundle mainBundle] bundleIdentifier] != nil;
+}
+
+void requestNotificationAuthorization(void) {
+    UNUserNotificationCenter* center = [UNUserNotificationCenter currentNotificationCenter];
+    if (notificationDelegate == nil) {
+        notificationDelegate = [[NotificationDelegate alloc] init];
+        center.delegate = notificationDelegate;
+    }
+    [center requestAuthorizationWithOptions:UNAuthorizationOptionAlert
+                          completionHandler:^(BOOL granted, NSError* error) {}];
+}
+
+void postNotification(const char* title, const char* body) {
+    @autoreleasepool {
+        UNMutableNotificationContent* content = [[UNMutableNotificationContent alloc] init];
+        content.title = [NSString stringWithUTF8String:title];
+        content.body = [NSString stringWithUTF8String:body];
+
+        UNNotificationRequest* request =
+            [UNNotificationRequest requestWithIdentifier:[[NSUUID UUID] UUIDString]
+                                                 content:content
+                                                 trigger:nil];
+        [[UNUserNotificationCenter currentNotificationCenter] addNotificationRequest:request
+                                                               withCompletionHandler:nil];
+    }
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"unsafe"
+)
+
+// UserNotifier posts notifications through the macOS notification center
+type UserNotifier struct{}
+
+// NewUserNotifier asks the user to allow notifications the first time it
+// runs. It only works when running from the app bundle.
+func NewUserNotifier() (*UserNotifier, error) {
+	if C.inAppBundle() == 0 {
+		return nil, fmt.Errorf("not running from an app bundle")
+	}
+	C.requestNotificationAuthorization()
+	return &UserNotifier{}, nil
+}
+
+// Notify shows a notification. Notifications the user turned off in
+// System Settings are dropped silently.
+func (n *UserNotifier) Notify(title, message string) error {
+	cTitle := C.CString(title)
+	defer C.free(unsafe.Pointer(cTitle))
+	cMessage := C.CString(message)
+	defer C.free(unsafe.Pointer(cMessage))
+
+	C.postNotification(cTitle, cMessage)
+	return nil
+}
+
+// ScriptNotifier shows notifications with AppleScript, for runs outside
+// the app bundle such as go run
+type ScriptNotifier struct{}
+
+// Notify shows a notification
+func (ScriptNotifier) Notify(title, message string) error {
+	script := fmt.Sprintf("display notification %s with title %s", strconv.Quote(message), strconv.Quote(title))
+	return exec.Command("osascript", "-e", script).Run()
+}
+
+// openPlatformNotifier prefers the notification center and falls back to
+// AppleScript
+func openPlatformNotifier() (Notifier, error) {
+	if n, err := NewUserNotifier(); err == nil {
+		return n, nil
+	}
+	return ScriptNotifier{}, nil
+}
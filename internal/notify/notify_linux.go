@@ -0,0 +1,69 @@
+//go:build linux
+
+package notify
+
+import (
+	"fmt"
+	"os/exec"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// Desktop Notifications D-Bus API (https://specifications.freedesktop.org/notification-spec/)
+const (
+	fdoBusName = "org.freedesktop.Notifications"
+	fdoPath    = "/org/freedesktop/Notifications"
+	fdoIface   = "org.freedesktop.Notifications"
+
+	// expireDefault lets the notification server pick the timeout
+	expireDefault = int32(-1)
+)
+
+// DBusNotifier sends notifications to the desktop's notification server
+// over the D-Bus session bus
+type DBusNotifier struct {
+	conn *dbus.Conn
+}
+
+// NewDBusNotifier connects to the session bus
+func NewDBusNotifier() (*DBusNotifier, error) {
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("connect to session bus failed: %w", err)
+	}
+	return &DBusNotifier{conn: conn}, nil
+}
+
+// Notify shows a notification
+func (n *DBusNotifier) Notify(title, message string) error {
+	call := n.conn.Object(fdoBusName, fdoPath).Call(fdoIface+".Notify", 0,
+		AppName, uint32(0), "", title, message, []string{}, map[string]dbus.Variant{}, expireDefault)
+	if call.Err != nil {
+		return fmt.Errorf("notify over D-Bus failed: %w", call.Err)
+	}
+	return nil
+}
+
+// NotifySendNotifier runs notify-send, for systems without a session bus
+// the app can reach
+type NotifySendNotifier struct {
+	path string
+}
+
+// Notify shows a notification
+func (n *NotifySendNotifier) Notify(title, message string) error {
+	return exec.Command(n.path, "--app-name="+AppName, title, message).Run()
+}
+
+// openPlatformNotifier prefers D-Bus and falls back to notify-send
+func openPlatformNotifier() (Notifier, error) {
+	n, err := NewDBusNotifier()
+	if err == nil {
+		return n, nil
+	}
+	path, lookErr := exec.LookPath("notify-send")
+	if lookErr != nil {
+		return nil, fmt.Errorf("%v, and notify-send not found", err)
+	}
+	return &NotifySendNotifier{path: path}, nil
+}
@@ -0,0 +1,7 @@
+//go:build !darwin && !linux
+
+package notify
+
+func openPlatformNotifier() (Notifier, error) {
+	return nil, ErrUnsupported
+}
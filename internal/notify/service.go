@@ -0,0 +1,157 @@
+package notify
+
+import (
+	"fmt"
+	"log/slog"
+	gosync "sync"
+
+	"github.com/mindmorass/yippity-clippity/internal/backend"
+	"github.com/mindmorass/yippity-clippity/internal/clipboard"
+	"github.com/mindmorass/yippity-clippity/internal/sync"
+)
+
+// Events selects what the user is notified about
+type Events struct {
+	// ClipReceived notifies when a clip from another device is applied
+	// or waits to be fetched
+	ClipReceived bool
+
+	// WriteFailed notifies when publishing a clip fails. Repeated
+	// failures notify once until a write succeeds again.
+	WriteFailed bool
+
+	// AuthExpired notifies when the backend rejects its credentials
+	AuthExpired bool
+
+	// UpdateAvailable notifies once about each new release
+	UpdateAvailable bool
+
+	// Conflicts notifies when a conflict kept a clip in the history
+	Conflicts bool
+}
+
+// Service turns sync engine events into notifications
+type Service struct {
+	notifier Notifier
+	events   Events
+
+	// Notices that were shown and shouldn't repeat
+	writeFailing  bool
+	authExpired   bool
+	latestVersion string
+	mu            gosync.Mutex
+
+	// Notifications being shown
+	sending gosync.WaitGroup
+}
+
+// NewService creates a service showing the selected events with notifier
+func NewService(notifier Notifier, events Events) *Service {
+	if notifier == nil {
+		notifier = Nop{}
+	}
+	return &Service{
+		notifier: notifier,
+		events:   events,
+	}
+}
+
+// Attach subscribes the service to an engine's events. Call the returned
+// function to stop.
+func (s *Service) Attach(engine *sync.Engine) (detach func()) {
+	return engine.Subscribe(s.handle)
+}
+
+func (s *Service) handle(event sync.Event) {
+	switch event.Type {
+	case sync.EventApplied:
+		s.recovered()
+		if s.events.ClipReceived {
+			s.send("Clip received", fmt.Sprintf("Copied %s from %s", describe(event.Clip), event.Clip.SourceMachine))
+		}
+
+	case sync.EventSkipped:
+		if event.Reason == sync.SkipDeferred && s.events.ClipReceived {
+			s.send("Large clip available", fmt.Sprintf("Choose Fetch in the menu to copy %s from %s", describe(event.Clip), event.Clip.SourceMachine))
+		}
+
+	case sync.EventPublished:
+		s.recovered()
+
+	case sync.EventError:
+		s.failed(event)
+
+	case sync.EventConflict:
+		if s.events.Conflicts {
+			s.send("Clipboard conflict", fmt.Sprintf("Kept the %s from %s in history. Choose Restore in the menu to use it.", event.Loser.ContentType, event.Loser.SourceMachine))
+		}
+	}
+}
+
+// failed notifies about a failed operation, once per run of failures
+func (s *Service) failed(event sync.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case backend.IsAuthError(event.Err):
+		if s.events.AuthExpired && !s.authExpired {
+			s.send("Sign-in expired", "The shared location rejected this device's credentials. Sign in again from the menu to keep syncing.")
+		}
+		s.authExpired = true
+
+	case event.Op == sync.OpPublish:
+		if s.events.WriteFailed && !s.writeFailing {
+			s.send("Clipboard not synced", fmt.Sprintf("Writing to the shared location failed: %v", event.Err))
+		}
+		s.writeFailing = true
+	}
+}
+
+// recovered re-arms the failure notices after a successful sync
+func (s *Service) recovered() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writeFailing = false
+	s.authExpired = false
+}
+
+// UpdateAvailable notifies about a new release, once per version
+func (s *Service) UpdateAvailable(version string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.events.UpdateAvailable || version == s.latestVersion {
+		return
+	}
+	s.latestVersion = version
+	s.send("Update available", fmt.Sprintf("Yippity-Clippity %s is available. Choose Update in the menu to download it.", version))
+}
+
+// Error tells the user an action they took failed. It is always shown.
+func (s *Service) Error(title string, err error) {
+	s.send(title, err.Error())
+}
+
+// send shows a notification without blocking the caller
+func (s *Service) send(title, message string) {
+	s.sending.Add(1)
+	go func() {
+		defer s.sending.Done()
+		if err := s.notifier.Notify(title, message); err != nil {
+			slog.Warn("Failed to show notification", "error", err)
+		}
+	}()
+}
+
+// wait waits until the notifications sent so far were shown
+func (s *Service) wait() {
+	s.sending.Wait()
+}
+
+// describe names a clip's content without revealing it
+func describe(content *clipboard.Content) string {
+	if content.IsImage() {
+		return "an image"
+	}
+	return "text"
+}
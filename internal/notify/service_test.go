@@ -0,0 +1,218 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	gosync "sync"
+	"testing"
+	"time"
+
+	"github.com/mindmorass/yippity-clippity/internal/backend"
+	"github.com/mindmorass/yippity-clippity/internal/clipboard"
+	"github.com/mindmorass/yippity-clippity/internal/sync"
+)
+
+// secret is clip data that must never be shown
+const secret = "correct horse battery staple"
+
+// notice is a notification a recorder was asked to show
+type notice struct {
+	title, message string
+}
+
+// recorder is a Notifier remembering what it showed
+type recorder struct {
+	notices []notice
+	mu      gosync.Mutex
+}
+
+func (r *recorder) Notify(title, message string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.notices = append(r.notices, notice{title, message})
+	return nil
+}
+
+// titles returns the titles shown by s so far, sorted, as notifications
+// are shown concurrently
+func (r *recorder) titles(s *Service) []string {
+	s.wait()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var titles []string
+	for _, n := range r.notices {
+		titles = append(titles, n.title)
+	}
+	sort.Strings(titles)
+	return titles
+}
+
+var allEvents = Events{
+	ClipReceived:    true,
+	WriteFailed:     true,
+	AuthExpired:     true,
+	UpdateAvailable: true,
+	Conflicts:       true,
+}
+
+func testClip(machine string) *clipboard.Content {
+	return &clipboard.Content{
+		ID:            machine + "-1",
+		Timestamp:     time.Now(),
+		SourceMachine: machine,
+		ContentType:   clipboard.ContentTypeText,
+		Data:          []byte(secret),
+		Size:          int64(len(secret)),
+	}
+}
+
+var (
+	applied   = sync.Event{Type: sync.EventApplied, Clip: testClip("laptop")}
+	deferred  = sync.Event{Type: sync.EventSkipped, Reason: sync.SkipDeferred, Clip: testClip("laptop")}
+	published = sync.Event{Type: sync.EventPublished, Clip: testClip("desktop")}
+	writeFail = sync.Event{Type: sync.EventError, Op: sync.OpPublish, Err: errors.New("disk full")}
+	authFail  = sync.Event{Type: sync.EventError, Op: sync.OpPublish, Err: fmt.Errorf("write failed: %w", backend.ErrAuthExpired)}
+	conflict  = sync.Event{Type: sync.EventConflict, Clip: testClip("desktop"), Loser: testClip("laptop")}
+)
+
+func TestServiceToggles(t *testing.T) {
+	tests := []struct {
+		name   string
+		toggle func(e *Events)
+		notify func(s *Service)
+		want   []string
+	}{
+		{
+			name:   "clip received",
+			toggle: func(e *Events) { e.ClipReceived = false },
+			notify: func(s *Service) { s.handle(applied); s.handle(deferred) },
+			want:   []string{"Clip received", "Large clip available"},
+		},
+		{
+			name:   "write failed",
+			toggle: func(e *Events) { e.WriteFailed = false },
+			notify: func(s *Service) { s.handle(writeFail) },
+			want:   []string{"Clipboard not synced"},
+		},
+		{
+			name:   "auth expired",
+			toggle: func(e *Events) { e.AuthExpired = false },
+			notify: func(s *Service) { s.handle(authFail) },
+			want:   []string{"Sign-in expired"},
+		},
+		{
+			name:   "update available",
+			toggle: func(e *Events) { e.UpdateAvailable = false },
+			notify: func(s *Service) { s.UpdateAvailable("1.2.0") },
+			want:   []string{"Update available"},
+		},
+		{
+			name:   "conflicts",
+			toggle: func(e *Events) { e.Conflicts = false },
+			notify: func(s *Service) { s.handle(conflict) },
+			want:   []string{"Clipboard conflict"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &recorder{}
+			s := NewService(r, allEvents)
+			tt.notify(s)
+			if got := r.titles(s); strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+				t.Errorf("enabled: notified %q, want %q", got, tt.want)
+			}
+
+			events := allEvents
+			tt.toggle(&events)
+			r = &recorder{}
+			s = NewService(r, events)
+			tt.notify(s)
+			if got := r.titles(s); len(got) != 0 {
+				t.Errorf("disabled: notified %q, want nothing", got)
+			}
+		})
+	}
+}
+
+func TestServiceFailuresNotifyOnceUntilRecovery(t *testing.T) {
+	tests := []struct {
+		name    string
+		failure sync.Event
+		success sync.Event
+		title   string
+	}{
+		{"write failed", writeFail, published, "Clipboard not synced"},
+		{"auth expired", authFail, applied, "Sign-in expired"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &recorder{}
+			events := Events{WriteFailed: true, AuthExpired: true}
+			s := NewService(r, events)
+
+			s.handle(tt.failure)
+			s.handle(tt.failure)
+			s.handle(tt.failure)
+			if got := r.titles(s); len(got) != 1 || got[0] != tt.title {
+				t.Fatalf("after repeated failures notified %q, want one %q", got, tt.title)
+			}
+
+			s.handle(tt.success)
+			s.handle(tt.failure)
+			if got := r.titles(s); len(got) != 2 || got[1] != tt.title {
+				t.Errorf("after recovering and failing again notified %q, want a second %q", got, tt.title)
+			}
+		})
+	}
+}
+
+func TestServiceUpdateOncePerVersion(t *testing.T) {
+	r := &recorder{}
+	s := NewService(r, Events{UpdateAvailable: true})
+
+	s.UpdateAvailable("1.2.0")
+	s.UpdateAvailable("1.2.0")
+	if got := r.titles(s); len(got) != 1 {
+		t.Fatalf("notified %q for one version, want one notification", got)
+	}
+
+	s.UpdateAvailable("1.3.0")
+	if got := r.titles(s); len(got) != 2 {
+		t.Errorf("notified %q for two versions, want two notifications", got)
+	}
+}
+
+func TestServiceNeverShowsClipData(t *testing.T) {
+	r := &recorder{}
+	s := NewService(r, allEvents)
+
+	image := testClip("laptop")
+	image.ContentType = clipboard.ContentTypeImage
+	for _, event := range []sync.Event{
+		applied,
+		deferred,
+		{Type: sync.EventApplied, Clip: image},
+		conflict,
+		writeFail,
+		authFail,
+	} {
+		s.handle(event)
+	}
+	s.UpdateAvailable("1.2.0")
+
+	s.wait()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.notices) != 7 {
+		t.Errorf("got %d notifications, want 7", len(r.notices))
+	}
+	for _, n := range r.notices {
+		if strings.Contains(n.title+n.message, secret) {
+			t.Errorf("notification %q: %q shows the clip data", n.title, n.message)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package pairing
+
+import (
+	"crypto/rand"
+	"errors"
+	"strings"
+)
+
+const (
+	// codeAlphabet is Crockford's base32, which avoids I, L, O and U
+	codeAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+	// sessionLen is the number of code characters naming the session.
+	// They are visible on the backend, so they aren't part of the secret.
+	sessionLen = 4
+
+	// secretLen is the number of code characters used as the password
+	secretLen = 8
+
+	// CodeURIPrefix prefixes the pairing code in QR codes
+	CodeURIPrefix = "yippity-clippity://pair/"
+)
+
+// ErrInvalidCode is returned for codes that can't be parsed
+var ErrInvalidCode = errors.New("invalid pairing code")
+
+// code is a one-time pairing code, displayed as XXXX-XXXX-XXXX
+type code struct {
+	session string
+	secret  string
+}
+
+func newCode() (code, error) {
+	raw := make([]byte, sessionLen+secretLen)
+	if _, err := rand.Read(raw); err != nil {
+		return code{}, err
+	}
+
+	chars := make([]byte, len(raw))
+	for i, b := range raw {
+		chars[i] = codeAlphabet[b%byte(len(codeAlphabet))]
+	}
+
+	return code{
+		session: string(chars[:sessionLen]),
+		secret:  string(chars[sessionLen:]),
+	}, nil
+}
+
+// parseCode accepts a code as displayed or typed, with or without dashes
+// and spaces, in any case, or as a QR code URI
+func parseCode(s string) (code, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), CodeURIPrefix)
+
+	var chars []byte
+	for _, c := range strings.ToUpper(s) {
+		switch c {
+		case '-', ' ':
+			continue
+		case 'O':
+			c = '0'
+		case 'I', 'L':
+			c = '1'
+		}
+		if !strings.ContainsRune(codeAlphabet, c) {
+			return code{}, ErrInvalidCode
+		}
+		chars = append(chars, byte(c))
+	}
+
+	if len(chars) != sessionLen+secretLen {
+		return code{}, ErrInvalidCode
+	}
+
+	return code{
+		session: string(chars[:sessionLen]),
+		secret:  string(chars[sessionLen:]),
+	}, nil
+}
+
+// String formats the code for display
+func (c code) String() string {
+	return c.session + "-" + c.secret[:4] + "-" + c.secret[4:]
+}
+
+// objectName returns the backend object name for a handshake message
+func (c code) objectName(msg string) string {
+	return "pairing-" + strings.ToLower(c.session) + "-" + msg
+}
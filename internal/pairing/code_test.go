@@ -0,0 +1,69 @@
+package pairing
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNewCodeUsesCrockfordAlphabet(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		c, err := newCode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(c.session) != sessionLen || len(c.secret) != secretLen {
+			t.Fatalf("code %s has the wrong length", c)
+		}
+		for _, r := range c.session + c.secret {
+			if !strings.ContainsRune(codeAlphabet, r) {
+				t.Fatalf("code %s contains %q", c, r)
+			}
+		}
+	}
+}
+
+func TestCodeFormatAndParse(t *testing.T) {
+	c := code{session: "AB12", secret: "CDEF3456"}
+	if got := c.String(); got != "AB12-CDEF-3456" {
+		t.Errorf("String() = %q, want AB12-CDEF-3456", got)
+	}
+
+	tests := []struct {
+		in   string
+		want code
+	}{
+		{"AB12-CDEF-3456", c},
+		{"ab12cdef3456", c},
+		{" ab12 cdef 3456 ", c},
+		{CodeURIPrefix + "AB12-CDEF-3456", c},
+		// O, I and L are read as the digits they look like
+		{"ABI2-CDEF-3456", c},
+		{"ABL2-CDEF-3456", c},
+		{"AB12-CDEF-345O", code{session: "AB12", secret: "CDEF3450"}},
+	}
+	for _, tt := range tests {
+		got, err := parseCode(tt.in)
+		if err != nil {
+			t.Errorf("parseCode(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseCode(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseCodeRejectsInvalid(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"AB12-CDEF-345",   // too short
+		"AB12-CDEF-34567", // too long
+		"AB12-CDEF-345U",  // U isn't in the alphabet
+		"AB12-CDEF-345*",
+	} {
+		if _, err := parseCode(in); !errors.Is(err, ErrInvalidCode) {
+			t.Errorf("parseCode(%q) error = %v, want ErrInvalidCode", in, err)
+		}
+	}
+}
@@ -0,0 +1,328 @@
+package pairing
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mindmorass/yippity-clippity/internal/backend"
+	"github.com/mindmorass/yippity-clippity/internal/identity"
+)
+
+// Pairing runs over the shared backend as three messages:
+//
+//	a: initiator -> joiner  SPAKE2 message
+//	b: joiner -> initiator  SPAKE2 message, confirmation, sealed joiner identity
+//	c: initiator -> joiner  confirmation, sealed initiator identity
+//
+// The initiator only reveals its identity after checking the joiner's
+// confirmation, and each session accepts a single attempt.
+
+const (
+	// Timeout is how long a pairing code stays valid
+	Timeout = 5 * time.Minute
+
+	// PollInterval is how often the backend is checked for the peer's message
+	PollInterval = time.Second
+
+	// protocolVersion is the pairing message format version
+	protocolVersion = 1
+)
+
+var (
+	ErrSessionNotFound = errors.New("no pairing session for this code, it may have expired")
+	ErrTimeout         = errors.New("pairing timed out")
+	ErrRejected        = errors.New("pairing was rejected by the other device")
+)
+
+// message is a pairing message stored on the backend
+type message struct {
+	Version  int    `json:"version"`
+	PAKE     []byte `json:"pake,omitempty"`
+	Confirm  []byte `json:"confirm,omitempty"`
+	Identity []byte `json:"identity,omitempty"` // Sealed peerInfo
+	Error    string `json:"error,omitempty"`
+}
+
+// peerInfo is the device identity exchanged once the handshake succeeds
+type peerInfo struct {
+	Name      string `json:"name"`
+	PublicKey string `json:"public_key"`
+}
+
+// Session is a pairing session started by an existing device
+type Session struct {
+	store backend.ObjectStore
+	self  *identity.Identity
+	code  code
+	pake  *spake2
+}
+
+// Start creates a pairing session and publishes its first message. Show
+// Code to the user, then call Wait.
+func Start(ctx context.Context, store backend.ObjectStore, self *identity.Identity) (*Session, error) {
+	c, err := newCode()
+	if err != nil {
+		return nil, err
+	}
+
+	pake, err := newSPAKE2(true, []byte(c.secret), initiatorID(c), joinerID(c))
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Session{
+		store: store,
+		self:  self,
+		code:  c,
+		pake:  pake,
+	}
+
+	if err := s.put(ctx, "a", message{PAKE: pake.Message()}); err != nil {
+		return nil, fmt.Errorf("failed to publish pairing session: %w", err)
+	}
+	return s, nil
+}
+
+// Code returns the one-time code to enter on the new device
+func (s *Session) Code() string {
+	return s.code.String()
+}
+
+// URI returns the code as a URI for QR codes
+func (s *Session) URI() string {
+	return CodeURIPrefix + s.code.String()
+}
+
+// Wait waits for the new device to join and returns its identity. The
+// session is removed from the backend when Wait returns.
+func (s *Session) Wait(ctx context.Context) (identity.Peer, error) {
+	defer s.Cancel()
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	msg, err := poll(ctx, s.store, s.code.objectName("b"))
+	if err != nil {
+		return identity.Peer{}, err
+	}
+
+	keys, err := s.pake.Finish(msg.PAKE)
+	if err == nil && !hmac.Equal(msg.Confirm, keys.confirmB) {
+		err = ErrHandshake
+	}
+	if err != nil {
+		// Tell the joiner, and burn the session so the code can't be retried
+		s.put(ctx, "c", message{Error: err.Error()})
+		return identity.Peer{}, err
+	}
+
+	peer, err := openIdentity(keys.Ke, msg.Identity, s.code)
+	if err != nil {
+		s.put(ctx, "c", message{Error: err.Error()})
+		return identity.Peer{}, err
+	}
+
+	sealed, err := sealIdentity(keys.Ke, s.self, s.code)
+	if err != nil {
+		return identity.Peer{}, err
+	}
+	if err := s.put(ctx, "c", message{Confirm: keys.confirmA, Identity: sealed}); err != nil {
+		return identity.Peer{}, err
+	}
+
+	return peer, nil
+}
+
+// Cancel removes the session's messages from the backend. The joiner's
+// final message is left for it to collect.
+func (s *Session) Cancel() {
+	ctx := context.Background()
+	s.store.DeleteObject(ctx, s.code.objectName("a"))
+	s.store.DeleteObject(ctx, s.code.objectName("b"))
+}
+
+func (s *Session) put(ctx context.Context, name string, msg message) error {
+	return putMessage(ctx, s.store, s.code.objectName(name), msg)
+}
+
+// Join pairs this device with the device showing the code and returns
+// that device's identity
+func Join(ctx context.Context, store backend.ObjectStore, self *identity.Identity, codeText string) (identity.Peer, error) {
+	c, err := parseCode(codeText)
+	if err != nil {
+		return identity.Peer{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	first, err := getMessage(ctx, store, c.objectName("a"))
+	if errors.Is(err, backend.ErrNotFound) {
+		return identity.Peer{}, ErrSessionNotFound
+	}
+	if err != nil {
+		return identity.Peer{}, err
+	}
+
+	pake, err := newSPAKE2(false, []byte(c.secret), initiatorID(c), joinerID(c))
+	if err != nil {
+		return identity.Peer{}, err
+	}
+	keys, err := pake.Finish(first.PAKE)
+	if err != nil {
+		return identity.Peer{}, err
+	}
+
+	sealed, err := sealIdentity(keys.Ke, self, c)
+	if err != nil {
+		return identity.Peer{}, err
+	}
+	err = putMessage(ctx, store, c.objectName("b"), message{
+		PAKE:     pake.Message(),
+		Confirm:  keys.confirmB,
+		Identity: sealed,
+	})
+	if err != nil {
+		return identity.Peer{}, err
+	}
+
+	reply, err := poll(ctx, store, c.objectName("c"))
+	store.DeleteObject(context.Background(), c.objectName("c"))
+	if err != nil {
+		return identity.Peer{}, err
+	}
+	if reply.Error != "" {
+		return identity.Peer{}, fmt.Errorf("%w: %s", ErrRejected, reply.Error)
+	}
+	if !hmac.Equal(reply.Confirm, keys.confirmA) {
+		return identity.Peer{}, ErrHandshake
+	}
+
+	return openIdentity(keys.Ke, reply.Identity, c)
+}
+
+// poll waits for a message to appear on the backend
+func poll(ctx context.Context, store backend.ObjectStore, name string) (*message, error) {
+	ticker := time.NewTicker(PollInterval)
+	defer ticker.Stop()
+
+	for {
+		msg, err := getMessage(ctx, store, name)
+		if err == nil {
+			return msg, nil
+		}
+		if !errors.Is(err, backend.ErrNotFound) {
+			return nil, err
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, ErrTimeout
+			}
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func getMessage(ctx context.Context, store backend.ObjectStore, name string) (*message, error) {
+	data, err := store.GetObject(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var msg message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, ErrHandshake
+	}
+	if msg.Version != protocolVersion {
+		return nil, fmt.Errorf("unsupported pairing protocol version %d", msg.Version)
+	}
+	return &msg, nil
+}
+
+func putMessage(ctx context.Context, store backend.ObjectStore, name string, msg message) error {
+	msg.Version = protocolVersion
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return store.PutObject(ctx, name, data)
+}
+
+// sealIdentity encrypts this device's identity with the handshake key
+func sealIdentity(key []byte, self *identity.Identity, c code) ([]byte, error) {
+	plaintext, err := json.Marshal(peerInfo{
+		Name:      self.Name,
+		PublicKey: self.KeyID(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, []byte(c.session)), nil
+}
+
+// openIdentity decrypts the peer's identity
+func openIdentity(key, sealed []byte, c code) (identity.Peer, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return identity.Peer{}, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return identity.Peer{}, ErrHandshake
+	}
+
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, []byte(c.session))
+	if err != nil {
+		return identity.Peer{}, ErrHandshake
+	}
+
+	var info peerInfo
+	if err := json.Unmarshal(plaintext, &info); err != nil {
+		return identity.Peer{}, ErrHandshake
+	}
+	if _, err := identity.ParseKey(info.PublicKey); err != nil {
+		return identity.Peer{}, err
+	}
+
+	return identity.Peer{
+		Name:      info.Name,
+		PublicKey: info.PublicKey,
+		AddedAt:   time.Now(),
+	}, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func initiatorID(c code) []byte {
+	return []byte("yippity-clippity initiator " + c.session)
+}
+
+func joinerID(c code) []byte {
+	return []byte("yippity-clippity joiner " + c.session)
+}
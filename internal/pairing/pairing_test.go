@@ -0,0 +1,266 @@
+package pairing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mindmorass/yippity-clippity/internal/backend"
+	"github.com/mindmorass/yippity-clippity/internal/identity"
+)
+
+// memStore is an in-memory ObjectStore. tamper, if set, may change each
+// message as it is stored.
+type memStore struct {
+	objects map[string][]byte
+	tamper  func(name string, data []byte) []byte
+	mu      sync.Mutex
+}
+
+func newMemStore() *memStore {
+	return &memStore{objects: make(map[string][]byte)}
+}
+
+func (s *memStore) PutObject(ctx context.Context, name string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tamper != nil {
+		data = s.tamper(name, data)
+	}
+	s.objects[name] = append([]byte(nil), data...)
+	return nil
+}
+
+func (s *memStore) GetObject(ctx context.Context, name string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.objects[name]
+	if !ok {
+		return nil, backend.ErrNotFound
+	}
+	return append([]byte(nil), data...), nil
+}
+
+func (s *memStore) DeleteObject(ctx context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.objects, name)
+	return nil
+}
+
+func (s *memStore) names() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var names []string
+	for name := range s.objects {
+		names = append(names, name)
+	}
+	return names
+}
+
+func testDevice(t *testing.T, name string) (*identity.Identity, *identity.TrustStore) {
+	t.Helper()
+	dir := t.TempDir()
+	id, err := identity.Load(nil, dir)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	id.Name = name
+
+	trust, err := identity.LoadTrustStore(filepath.Join(dir, identity.PeersFileName), id)
+	if err != nil {
+		t.Fatalf("LoadTrustStore: %v", err)
+	}
+	return id, trust
+}
+
+// result is how pairing ended on one device
+type result struct {
+	peer identity.Peer
+	err  error
+}
+
+// pair runs a pairing session between two devices over store and returns
+// the result on each. The joiner enters the code returned by enter, given
+// the displayed code.
+func pair(t *testing.T, store *memStore, host, joiner *identity.Identity, enter func(string) string) (hosted, joined result) {
+	t.Helper()
+	ctx := context.Background()
+
+	session, err := Start(ctx, store, host)
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		peer, err := Join(ctx, store, joiner, enter(session.Code()))
+		done <- result{peer, err}
+	}()
+
+	peer, err := session.Wait(ctx)
+	return result{peer, err}, <-done
+}
+
+func TestPairingRoundTrip(t *testing.T) {
+	t.Parallel()
+	store := newMemStore()
+	host, hostTrust := testDevice(t, "host")
+	joiner, joinerTrust := testDevice(t, "joiner")
+
+	hosted, joined := pair(t, store, host, joiner, func(c string) string { return c })
+	if hosted.err != nil {
+		t.Fatalf("Wait: %v", hosted.err)
+	}
+	if joined.err != nil {
+		t.Fatalf("Join: %v", joined.err)
+	}
+	hostPeer, joinerPeer := hosted.peer, joined.peer
+
+	if hostPeer.Name != "joiner" || hostPeer.PublicKey != joiner.KeyID() {
+		t.Errorf("host paired with %s %s, want joiner %s", hostPeer.Name, hostPeer.PublicKey, joiner.KeyID())
+	}
+	if joinerPeer.Name != "host" || joinerPeer.PublicKey != host.KeyID() {
+		t.Errorf("joiner paired with %s %s, want host %s", joinerPeer.Name, joinerPeer.PublicKey, host.KeyID())
+	}
+
+	if err := hostTrust.Add(hostPeer.Name, hostPeer.PublicKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := joinerTrust.Add(joinerPeer.Name, joinerPeer.PublicKey); err != nil {
+		t.Fatal(err)
+	}
+	if peers := hostTrust.Peers(); len(peers) != 1 || peers[0].PublicKey != joiner.KeyID() {
+		t.Errorf("host trusts %v, want the joiner's key", peers)
+	}
+	if peers := joinerTrust.Peers(); len(peers) != 1 || peers[0].PublicKey != host.KeyID() {
+		t.Errorf("joiner trusts %v, want the host's key", peers)
+	}
+
+	if names := store.names(); len(names) != 0 {
+		t.Errorf("pairing objects left on the backend: %v", names)
+	}
+}
+
+func TestPairingWrongCode(t *testing.T) {
+	t.Parallel()
+	store := newMemStore()
+	host, _ := testDevice(t, "host")
+	joiner, _ := testDevice(t, "joiner")
+
+	// Same session, different secret
+	wrong := func(c string) string {
+		parsed, err := parseCode(c)
+		if err != nil {
+			t.Errorf("parseCode(%q): %v", c, err)
+		}
+		secret := []byte(parsed.secret)
+		secret[0] = codeAlphabet[(strings.IndexByte(codeAlphabet, secret[0])+1)%len(codeAlphabet)]
+		return code{session: parsed.session, secret: string(secret)}.String()
+	}
+
+	hosted, joined := pair(t, store, host, joiner, wrong)
+	if !errors.Is(hosted.err, ErrHandshake) {
+		t.Errorf("Wait error = %v, want ErrHandshake", hosted.err)
+	}
+	if !errors.Is(joined.err, ErrRejected) {
+		t.Errorf("Join error = %v, want ErrRejected", joined.err)
+	}
+}
+
+func TestPairingRejectsTamperedMessages(t *testing.T) {
+	tests := []struct {
+		name    string
+		msg     string
+		tamper  func(m *message)
+		hostErr error
+		joinErr error
+	}{
+		{
+			name:    "joiner identity",
+			msg:     "b",
+			tamper:  func(m *message) { m.Identity[len(m.Identity)-1] ^= 1 },
+			hostErr: ErrHandshake,
+			joinErr: ErrRejected,
+		},
+		{
+			name:    "joiner confirmation",
+			msg:     "b",
+			tamper:  func(m *message) { m.Confirm[0] ^= 1 },
+			hostErr: ErrHandshake,
+			joinErr: ErrRejected,
+		},
+		{
+			name:    "host confirmation",
+			msg:     "c",
+			tamper:  func(m *message) { m.Confirm[0] ^= 1 },
+			joinErr: ErrHandshake,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			store := newMemStore()
+			store.tamper = func(name string, data []byte) []byte {
+				if !strings.HasSuffix(name, "-"+tt.msg) {
+					return data
+				}
+				var m message
+				if err := json.Unmarshal(data, &m); err != nil || m.Error != "" {
+					return data
+				}
+				tt.tamper(&m)
+				data, _ = json.Marshal(m)
+				return data
+			}
+			host, _ := testDevice(t, "host")
+			joiner, _ := testDevice(t, "joiner")
+
+			hosted, joined := pair(t, store, host, joiner, func(c string) string { return c })
+			if tt.hostErr != nil && !errors.Is(hosted.err, tt.hostErr) {
+				t.Errorf("Wait error = %v, want %v", hosted.err, tt.hostErr)
+			}
+			if !errors.Is(joined.err, tt.joinErr) {
+				t.Errorf("Join error = %v, want %v", joined.err, tt.joinErr)
+			}
+		})
+	}
+}
+
+func TestPairingRejectsReplayedMessage(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	store := newMemStore()
+	host, _ := testDevice(t, "host")
+	joiner, _ := testDevice(t, "joiner")
+
+	// Record the joiner's message from a completed session
+	var recorded []byte
+	store.tamper = func(name string, data []byte) []byte {
+		if strings.HasSuffix(name, "-b") {
+			recorded = data
+		}
+		return data
+	}
+	if hosted, joined := pair(t, store, host, joiner, func(c string) string { return c }); hosted.err != nil || joined.err != nil {
+		t.Fatalf("first pairing: %v, %v", hosted.err, joined.err)
+	}
+	store.tamper = nil
+
+	// Replay it into a new session
+	session, err := Start(ctx, store, host)
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if err := store.PutObject(ctx, session.code.objectName("b"), recorded); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := session.Wait(ctx); !errors.Is(err, ErrHandshake) {
+		t.Errorf("Wait with a replayed message = %v, want ErrHandshake", err)
+	}
+}
@@ -0,0 +1,214 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	// fileStoreVersion is the encrypted file format version
+	fileStoreVersion = 1
+
+	// pbkdf2Iterations is the key derivation work factor
+	pbkdf2Iterations = 600000
+
+	saltSize = 16
+	keySize  = 32
+)
+
+// fileEnvelope is the on-disk representation of the encrypted store
+type fileEnvelope struct {
+	Version    int    `json:"version"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// FileStore keeps secrets in a file encrypted with AES-256-GCM.
+// The key is derived from a passphrase with PBKDF2-SHA256.
+type FileStore struct {
+	path       string
+	passphrase string
+	mu         sync.Mutex
+}
+
+// NewFileStore creates an encrypted file store
+func NewFileStore(path, passphrase string) *FileStore {
+	return &FileStore{
+		path:       path,
+		passphrase: passphrase,
+	}
+}
+
+// Type returns the store type
+func (s *FileStore) Type() StoreType {
+	return StoreFile
+}
+
+// Get returns a secret from the file
+func (s *FileStore) Get(service, account string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	data, ok := items[itemKey(service, account)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return data, nil
+}
+
+// Set stores a secret in the file
+func (s *FileStore) Set(service, account string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	items[itemKey(service, account)] = data
+	return s.save(items)
+}
+
+// Delete removes a secret from the file
+func (s *FileStore) Delete(service, account string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	key := itemKey(service, account)
+	if _, ok := items[key]; !ok {
+		return nil
+	}
+	delete(items, key)
+	return s.save(items)
+}
+
+// load decrypts all items. A missing file is an empty store.
+func (s *FileStore) load() (map[string][]byte, error) {
+	raw, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return make(map[string][]byte), nil
+		}
+		return nil, fmt.Errorf("read secret store failed: %w", err)
+	}
+
+	var env fileEnvelope
+	if err := json.Unmarshal(raw, &env); err != nil {
+		return nil, ErrBadPassphrase
+	}
+	if env.Version != fileStoreVersion {
+		return nil, fmt.Errorf("unsupported secret store version: %d", env.Version)
+	}
+
+	gcm, err := s.cipher(env.Salt, env.Iterations)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := gcm.Open(nil, env.Nonce, env.Ciphertext, nil)
+	if err != nil {
+		return nil, ErrBadPassphrase
+	}
+
+	items := make(map[string][]byte)
+	if err := json.Unmarshal(plaintext, &items); err != nil {
+		return nil, ErrBadPassphrase
+	}
+	return items, nil
+}
+
+// save encrypts all items with a fresh salt and nonce and writes the
+// file atomically
+func (s *FileStore) save(items map[string][]byte) error {
+	plaintext, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+
+	gcm, err := s.cipher(salt, pbkdf2Iterations)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	raw, err := json.Marshal(fileEnvelope{
+		Version:    fileStoreVersion,
+		Iterations: pbkdf2Iterations,
+		Salt:       salt,
+		Nonce:      nonce,
+		Ciphertext: gcm.Seal(nil, nonce, plaintext, nil),
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+
+	tempPath := s.path + ".tmp"
+	if err := os.WriteFile(tempPath, raw, 0600); err != nil {
+		return fmt.Errorf("write secret store failed: %w", err)
+	}
+	if err := os.Rename(tempPath, s.path); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("rename secret store failed: %w", err)
+	}
+	return nil
+}
+
+// cipher derives the AES-GCM cipher for the given salt
+func (s *FileStore) cipher(salt []byte, iterations int) (cipher.AEAD, error) {
+	if s.passphrase == "" {
+		return nil, ErrNoPassphrase
+	}
+	if iterations <= 0 {
+		return nil, errors.New("invalid key derivation parameters")
+	}
+
+	key, err := pbkdf2.Key(sha256.New, s.passphrase, salt, iterations, keySize)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func itemKey(service, account string) string {
+	return service + "/" + account
+}
@@ -0,0 +1,76 @@
+package secrets
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultFileName)
+	store := NewFileStore(path, "correct horse")
+
+	if err := store.Set("svc", "token", []byte("s3cr3t")); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	// A new store reads what the first one wrote
+	got, err := NewFileStore(path, "correct horse").Get("svc", "token")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if !bytes.Equal(got, []byte("s3cr3t")) {
+		t.Errorf("Get = %q, want s3cr3t", got)
+	}
+
+	// The file doesn't hold the secret in the clear
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(raw, []byte("s3cr3t")) {
+		t.Error("secret store file contains the plaintext secret")
+	}
+
+	if err := store.Delete("svc", "token"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Get("svc", "token"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete = %v, want ErrNotFound", err)
+	}
+}
+
+func TestFileStoreWrongPassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultFileName)
+	if err := NewFileStore(path, "correct horse").Set("svc", "token", []byte("s3cr3t")); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	if _, err := NewFileStore(path, "battery staple").Get("svc", "token"); !errors.Is(err, ErrBadPassphrase) {
+		t.Errorf("Get with the wrong passphrase = %v, want ErrBadPassphrase", err)
+	}
+	if _, err := NewFileStore(path, "").Get("svc", "token"); !errors.Is(err, ErrNoPassphrase) {
+		t.Errorf("Get without a passphrase = %v, want ErrNoPassphrase", err)
+	}
+}
+
+func TestFileStoreMissingKey(t *testing.T) {
+	store := NewFileStore(filepath.Join(t.TempDir(), DefaultFileName), "correct horse")
+
+	// A missing file is an empty store
+	if _, err := store.Get("svc", "token"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get from a new store = %v, want ErrNotFound", err)
+	}
+
+	if err := store.Set("svc", "token", []byte("s3cr3t")); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if _, err := store.Get("svc", "other"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of another account = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete("svc", "other"); err != nil {
+		t.Errorf("Delete of a missing secret = %v", err)
+	}
+}
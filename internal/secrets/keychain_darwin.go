@@ -1,6 +1,6 @@
 //go:build darwin
 
-package backend
+package secrets
 
 import (
 	"fmt"
@@ -8,8 +8,25 @@ import (
 	"github.com/keybase/go-keychain"
 )
 
-// loadFromKeychain retrieves data from the macOS Keychain
-func loadFromKeychain(service, account string) ([]byte, error) {
+// KeychainStore keeps secrets in the macOS Keychain as generic passwords
+type KeychainStore struct{}
+
+// NewKeychainStore creates a Keychain-backed store
+func NewKeychainStore() *KeychainStore {
+	return &KeychainStore{}
+}
+
+func openKeychainStore() (Store, error) {
+	return NewKeychainStore(), nil
+}
+
+// Type returns the store type
+func (s *KeychainStore) Type() StoreType {
+	return StoreKeychain
+}
+
+// Get retrieves data from the macOS Keychain
+func (s *KeychainStore) Get(service, account string) ([]byte, error) {
 	query := keychain.NewItem()
 	query.SetSecClass(keychain.SecClassGenericPassword)
 	query.SetService(service)
@@ -19,20 +36,23 @@ func loadFromKeychain(service, account string) ([]byte, error) {
 
 	results, err := keychain.QueryItem(query)
 	if err != nil {
+		if err == keychain.ErrorItemNotFound {
+			return nil, ErrNotFound
+		}
 		return nil, fmt.Errorf("keychain query failed: %w", err)
 	}
 
 	if len(results) == 0 {
-		return nil, fmt.Errorf("no keychain item found for %s/%s", service, account)
+		return nil, ErrNotFound
 	}
 
 	return results[0].Data, nil
 }
 
-// saveToKeychain stores data in the macOS Keychain
-func saveToKeychain(service, account string, data []byte) error {
+// Set stores data in the macOS Keychain
+func (s *KeychainStore) Set(service, account string, data []byte) error {
 	// First try to delete any existing item
-	_ = deleteFromKeychain(service, account)
+	_ = s.Delete(service, account)
 
 	item := keychain.NewItem()
 	item.SetSecClass(keychain.SecClassGenericPassword)
@@ -50,8 +70,8 @@ func saveToKeychain(service, account string, data []byte) error {
 	return nil
 }
 
-// deleteFromKeychain removes data from the macOS Keychain
-func deleteFromKeychain(service, account string) error {
+// Delete removes data from the macOS Keychain
+func (s *KeychainStore) Delete(service, account string) error {
 	item := keychain.NewItem()
 	item.SetSecClass(keychain.SecClassGenericPassword)
 	item.SetService(service)
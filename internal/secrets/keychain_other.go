@@ -0,0 +1,7 @@
+//go:build !darwin
+
+package secrets
+
+func openKeychainStore() (Store, error) {
+	return nil, ErrUnsupported
+}
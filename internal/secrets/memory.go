@@ -0,0 +1,50 @@
+package secrets
+
+import "sync"
+
+// MemoryStore keeps secrets in memory only. Nothing survives the
+// process, so it suits tests and throwaway sessions.
+type MemoryStore struct {
+	items map[string][]byte
+	mu    sync.Mutex
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{items: make(map[string][]byte)}
+}
+
+// Type returns the store type
+func (s *MemoryStore) Type() StoreType {
+	return StoreMemory
+}
+
+// Get returns a copy of a stored secret
+func (s *MemoryStore) Get(service, account string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, ok := s.items[itemKey(service, account)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), data...), nil
+}
+
+// Set stores a copy of a secret
+func (s *MemoryStore) Set(service, account string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.items[itemKey(service, account)] = append([]byte(nil), data...)
+	return nil
+}
+
+// Delete removes a secret
+func (s *MemoryStore) Delete(service, account string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.items, itemKey(service, account))
+	return nil
+}
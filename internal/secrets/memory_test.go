@@ -0,0 +1,47 @@
+package secrets
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+	if store.Type() != StoreMemory {
+		t.Errorf("Type = %s, want %s", store.Type(), StoreMemory)
+	}
+
+	if _, err := store.Get("svc", "token"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get from a new store = %v, want ErrNotFound", err)
+	}
+
+	secret := []byte("s3cr3t")
+	if err := store.Set("svc", "token", secret); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	// The store keeps its own copy
+	secret[0] = 'x'
+	got, err := store.Get("svc", "token")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if !bytes.Equal(got, []byte("s3cr3t")) {
+		t.Errorf("Get = %q, want s3cr3t", got)
+	}
+	got[0] = 'x'
+	if again, _ := store.Get("svc", "token"); !bytes.Equal(again, []byte("s3cr3t")) {
+		t.Errorf("changing a returned secret changed the store: %q", again)
+	}
+
+	if err := store.Delete("svc", "token"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Get("svc", "token"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete("svc", "token"); err != nil {
+		t.Errorf("Delete of a missing secret = %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// StoreType identifies a secret store implementation
+type StoreType string
+
+const (
+	StoreAuto          StoreType = "auto"
+	StoreKeychain      StoreType = "keychain"
+	StoreSecretService StoreType = "secret-service"
+	StoreFile          StoreType = "file"
+	StoreMemory        StoreType = "memory"
+)
+
+const (
+	// PassphraseEnv is the environment variable holding the passphrase
+	// for the encrypted file store
+	PassphraseEnv = "YIPPITY_CLIPPITY_SECRETS_PASSPHRASE"
+
+	// DefaultFileName is the encrypted file store's file name
+	DefaultFileName = "secrets.enc"
+)
+
+// Common errors
+var (
+	ErrNotFound      = errors.New("secret not found")
+	ErrUnsupported   = errors.New("secret store not supported on this platform")
+	ErrNoPassphrase  = errors.New("no passphrase set for encrypted secret store")
+	ErrBadPassphrase = errors.New("wrong passphrase or corrupted secret store")
+)
+
+// Store saves small secrets such as OAuth tokens and API keys.
+// Secrets are addressed by a service name and an account within it.
+type Store interface {
+	// Get returns the secret, or ErrNotFound if none is stored
+	Get(service, account string) ([]byte, error)
+
+	// Set stores or replaces the secret
+	Set(service, account string, data []byte) error
+
+	// Delete removes the secret. Deleting a missing secret is not an error.
+	Delete(service, account string) error
+
+	// Type returns the store type
+	Type() StoreType
+}
+
+// Options configures which secret store to open
+type Options struct {
+	Type StoreType
+
+	// FilePath is the encrypted file store location
+	FilePath string
+
+	// Passphrase unlocks the encrypted file store
+	Passphrase string
+}
+
+// Open returns the requested secret store. With StoreAuto it uses the
+// platform store (Keychain or Secret Service) when available and falls
+// back to the encrypted file store if a passphrase is set.
+func Open(opts Options) (Store, error) {
+	if opts.Passphrase == "" {
+		opts.Passphrase = os.Getenv(PassphraseEnv)
+	}
+
+	switch opts.Type {
+	case StoreAuto, "":
+		store, err := openPlatformStore()
+		if err == nil {
+			return store, nil
+		}
+		if opts.Passphrase == "" {
+			return nil, fmt.Errorf("platform secret store unavailable (%v) and %s not set", err, PassphraseEnv)
+		}
+		return openFileStore(opts)
+
+	case StoreKeychain:
+		return openKeychainStore()
+
+	case StoreSecretService:
+		return openSecretServiceStore()
+
+	case StoreFile:
+		return openFileStore(opts)
+
+	default:
+		return nil, fmt.Errorf("unknown secret store type: %s", opts.Type)
+	}
+}
+
+// openPlatformStore opens the native secret store for this OS
+func openPlatformStore() (Store, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		return openKeychainStore()
+	case "linux":
+		return openSecretServiceStore()
+	default:
+		return nil, ErrUnsupported
+	}
+}
+
+func openFileStore(opts Options) (Store, error) {
+	if opts.Passphrase == "" {
+		return nil, ErrNoPassphrase
+	}
+	path := opts.FilePath
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			home = "."
+		}
+		path = filepath.Join(home, ".yippity-clippity", DefaultFileName)
+	}
+	return NewFileStore(path, opts.Passphrase), nil
+}
@@ -0,0 +1,226 @@
+//go:build linux
+
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// Secret Service D-Bus API (https://specifications.freedesktop.org/secret-service/)
+const (
+	ssBusName           = "org.freedesktop.secrets"
+	ssServicePath       = "/org/freedesktop/secrets"
+	ssDefaultCollection = "/org/freedesktop/secrets/aliases/default"
+
+	ssServiceIface    = "org.freedesktop.Secret.Service"
+	ssCollectionIface = "org.freedesktop.Secret.Collection"
+	ssItemIface       = "org.freedesktop.Secret.Item"
+	ssPromptIface     = "org.freedesktop.Secret.Prompt"
+
+	// PromptTimeout is how long to wait for the user to answer an unlock prompt
+	PromptTimeout = 2 * time.Minute
+)
+
+// ssSecret mirrors the Secret Service (oayays) secret struct
+type ssSecret struct {
+	Session     dbus.ObjectPath
+	Parameters  []byte
+	Value       []byte
+	ContentType string
+}
+
+// SecretServiceStore keeps secrets in the freedesktop Secret Service
+// (GNOME Keyring, KWallet) over the D-Bus session bus
+type SecretServiceStore struct {
+	conn    *dbus.Conn
+	session dbus.ObjectPath
+}
+
+// NewSecretServiceStore connects to the Secret Service on the session bus
+func NewSecretServiceStore() (*SecretServiceStore, error) {
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("connect to session bus failed: %w", err)
+	}
+
+	var output dbus.Variant
+	var session dbus.ObjectPath
+	err = conn.Object(ssBusName, ssServicePath).
+		Call(ssServiceIface+".OpenSession", 0, "plain", dbus.MakeVariant("")).
+		Store(&output, &session)
+	if err != nil {
+		return nil, fmt.Errorf("open Secret Service session failed: %w", err)
+	}
+
+	return &SecretServiceStore{
+		conn:    conn,
+		session: session,
+	}, nil
+}
+
+func openSecretServiceStore() (Store, error) {
+	return NewSecretServiceStore()
+}
+
+// Type returns the store type
+func (s *SecretServiceStore) Type() StoreType {
+	return StoreSecretService
+}
+
+// Get retrieves a secret from the Secret Service
+func (s *SecretServiceStore) Get(service, account string) ([]byte, error) {
+	item, err := s.findItem(service, account)
+	if err != nil {
+		return nil, err
+	}
+
+	var secret ssSecret
+	err = s.conn.Object(ssBusName, item).
+		Call(ssItemIface+".GetSecret", 0, s.session).
+		Store(&secret)
+	if err != nil {
+		return nil, fmt.Errorf("get secret failed: %w", err)
+	}
+
+	return secret.Value, nil
+}
+
+// Set stores a secret in the default collection, replacing any existing one
+func (s *SecretServiceStore) Set(service, account string, data []byte) error {
+	collection := s.conn.Object(ssBusName, ssDefaultCollection)
+	if err := s.unlock(ssDefaultCollection); err != nil {
+		return err
+	}
+
+	props := map[string]dbus.Variant{
+		ssItemIface + ".Label":      dbus.MakeVariant(fmt.Sprintf("Yippity-Clippity (%s)", service)),
+		ssItemIface + ".Attributes": dbus.MakeVariant(attributes(service, account)),
+	}
+	secret := ssSecret{
+		Session:     s.session,
+		Value:       data,
+		ContentType: "application/octet-stream",
+	}
+
+	var item, prompt dbus.ObjectPath
+	err := collection.Call(ssCollectionIface+".CreateItem", 0, props, secret, true).
+		Store(&item, &prompt)
+	if err != nil {
+		return fmt.Errorf("create secret failed: %w", err)
+	}
+
+	return s.prompt(prompt)
+}
+
+// Delete removes a secret from the Secret Service
+func (s *SecretServiceStore) Delete(service, account string) error {
+	item, err := s.findItem(service, account)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var prompt dbus.ObjectPath
+	err = s.conn.Object(ssBusName, item).
+		Call(ssItemIface+".Delete", 0).
+		Store(&prompt)
+	if err != nil {
+		return fmt.Errorf("delete secret failed: %w", err)
+	}
+
+	return s.prompt(prompt)
+}
+
+// findItem returns the unlocked item for service/account
+func (s *SecretServiceStore) findItem(service, account string) (dbus.ObjectPath, error) {
+	var unlocked, locked []dbus.ObjectPath
+	err := s.conn.Object(ssBusName, ssServicePath).
+		Call(ssServiceIface+".SearchItems", 0, attributes(service, account)).
+		Store(&unlocked, &locked)
+	if err != nil {
+		return "", fmt.Errorf("search secrets failed: %w", err)
+	}
+
+	if len(unlocked) > 0 {
+		return unlocked[0], nil
+	}
+	if len(locked) == 0 {
+		return "", ErrNotFound
+	}
+
+	if err := s.unlock(locked[0]); err != nil {
+		return "", err
+	}
+	return locked[0], nil
+}
+
+// unlock unlocks an item or collection, prompting the user if needed
+func (s *SecretServiceStore) unlock(object dbus.ObjectPath) error {
+	var unlocked []dbus.ObjectPath
+	var prompt dbus.ObjectPath
+	err := s.conn.Object(ssBusName, ssServicePath).
+		Call(ssServiceIface+".Unlock", 0, []dbus.ObjectPath{object}).
+		Store(&unlocked, &prompt)
+	if err != nil {
+		return fmt.Errorf("unlock failed: %w", err)
+	}
+
+	return s.prompt(prompt)
+}
+
+// prompt shows a Secret Service prompt and waits for it to complete.
+// The path "/" means no prompt is required.
+func (s *SecretServiceStore) prompt(prompt dbus.ObjectPath) error {
+	if prompt == "/" || prompt == "" {
+		return nil
+	}
+
+	match := []dbus.MatchOption{
+		dbus.WithMatchObjectPath(prompt),
+		dbus.WithMatchInterface(ssPromptIface),
+		dbus.WithMatchMember("Completed"),
+	}
+	if err := s.conn.AddMatchSignal(match...); err != nil {
+		return err
+	}
+	defer s.conn.RemoveMatchSignal(match...)
+
+	signals := make(chan *dbus.Signal, 1)
+	s.conn.Signal(signals)
+	defer s.conn.RemoveSignal(signals)
+
+	if err := s.conn.Object(ssBusName, prompt).Call(ssPromptIface+".Prompt", 0, "").Err; err != nil {
+		return fmt.Errorf("prompt failed: %w", err)
+	}
+
+	timeout := time.After(PromptTimeout)
+	for {
+		select {
+		case sig := <-signals:
+			if sig.Path != prompt || sig.Name != ssPromptIface+".Completed" {
+				continue
+			}
+			if len(sig.Body) > 0 {
+				if dismissed, ok := sig.Body[0].(bool); ok && dismissed {
+					return errors.New("secret store prompt dismissed")
+				}
+			}
+			return nil
+		case <-timeout:
+			return errors.New("timed out waiting for secret store prompt")
+		}
+	}
+}
+
+func attributes(service, account string) map[string]string {
+	return map[string]string{
+		"service": service,
+		"account": account,
+	}
+}
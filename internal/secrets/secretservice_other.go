@@ -0,0 +1,7 @@
+//go:build !linux
+
+package secrets
+
+func openSecretServiceStore() (Store, error) {
+	return nil, ErrUnsupported
+}
@@ -0,0 +1,198 @@
+package sensitive
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Built-in rule names, as used in the config file
+const (
+	RulePrivateKey = "private_key"
+	RuleAWS        = "aws"
+	RuleGitHub     = "github"
+	RuleSlack      = "slack"
+	RuleJWT        = "jwt"
+	RuleCreditCard = "credit_card"
+)
+
+// DefaultActions are the actions for the built-in rules when the config
+// file doesn't set them
+var DefaultActions = map[string]Action{
+	RulePrivateKey: ActionBlock,
+	RuleAWS:        ActionBlock,
+	RuleGitHub:     ActionBlock,
+	RuleSlack:      ActionBlock,
+	RuleJWT:        ActionConfirm,
+	RuleCreditCard: ActionRedact,
+}
+
+// RegexDetector matches text against a regular expression, optionally
+// validating each match
+type RegexDetector struct {
+	name     string
+	re       *regexp.Regexp
+	validate func(match string) bool
+}
+
+// NewRegexDetector compiles a user-defined pattern
+func NewRegexDetector(name, pattern string) (*RegexDetector, error) {
+	if name == "" {
+		return nil, fmt.Errorf("sensitive pattern %q has no name", pattern)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sensitive pattern %s: %w", name, err)
+	}
+	return &RegexDetector{name: name, re: re}, nil
+}
+
+// Name returns the detector name
+func (d *RegexDetector) Name() string {
+	return d.name
+}
+
+// Find returns the offsets of every valid match
+func (d *RegexDetector) Find(text string) [][2]int {
+	var matches [][2]int
+	for _, loc := range d.re.FindAllStringIndex(text, -1) {
+		if d.validate != nil && !d.validate(text[loc[0]:loc[1]]) {
+			continue
+		}
+		matches = append(matches, [2]int{loc[0], loc[1]})
+	}
+	return matches
+}
+
+// builtin returns a detector for a built-in rule
+func builtin(name string, validate func(string) bool, patterns ...string) *RegexDetector {
+	re := patterns[0]
+	for _, p := range patterns[1:] {
+		re += "|" + p
+	}
+	return &RegexDetector{
+		name:     name,
+		re:       regexp.MustCompile(re),
+		validate: validate,
+	}
+}
+
+// Builtin returns the detector for a built-in rule, or nil if the name
+// isn't one
+func Builtin(name string) Detector {
+	switch name {
+	case RulePrivateKey:
+		// The whole PEM block when it's complete, otherwise the header
+		return builtin(name, nil,
+			`(?s)-----BEGIN (?:[A-Z0-9]+ )*PRIVATE KEY(?: BLOCK)?-----.*?-----END (?:[A-Z0-9]+ )*PRIVATE KEY(?: BLOCK)?-----`,
+			`-----BEGIN (?:[A-Z0-9]+ )*PRIVATE KEY(?: BLOCK)?-----`,
+		)
+	case RuleAWS:
+		return builtin(name, nil,
+			`\b(?:AKIA|ASIA|AGPA|AIDA|AROA|ANPA|ANVA)[0-9A-Z]{16}\b`,
+			`(?i)aws_?secret_?access_?key["']?\s*[:=]\s*["']?[A-Za-z0-9/+=]{40}\b`,
+		)
+	case RuleGitHub:
+		return builtin(name, nil,
+			`\bgh[pousr]_[A-Za-z0-9]{36,255}\b`,
+			`\bgithub_pat_[A-Za-z0-9_]{82}\b`,
+		)
+	case RuleSlack:
+		return builtin(name, nil,
+			`\bxox[abposr]-[A-Za-z0-9-]{10,}`,
+			`\bxapp-\d-[A-Za-z0-9-]{10,}`,
+			`https://hooks\.slack\.com/services/T[A-Z0-9]+/B[A-Z0-9]+/[A-Za-z0-9]+`,
+		)
+	case RuleJWT:
+		return builtin(name, nil,
+			`\beyJ[A-Za-z0-9_-]{5,}\.eyJ[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]+`,
+		)
+	case RuleCreditCard:
+		// Runs of digits, 4-4-4-4(-3) groups, or Amex and Diners 4-6-5
+		// and 4-6-4 groups. Matches must also have a card network's
+		// prefix and length and pass the Luhn check.
+		return builtin(name, cardNumberValid,
+			`\b\d{13,19}\b`,
+			`\b\d{4}[ -]\d{4}[ -]\d{4}[ -]\d{4}(?:[ -]\d{3})?\b`,
+			`\b\d{4}[ -]\d{6}[ -]\d{4,5}\b`,
+		)
+	}
+	return nil
+}
+
+// cardNetwork is the issuer prefix range and valid lengths of a card
+// network's numbers
+type cardNetwork struct {
+	low, high string
+	lengths   []int
+}
+
+// cardNetworks lists the issuer prefixes of the major card networks
+var cardNetworks = []cardNetwork{
+	{"4", "4", []int{13, 16, 19}},                 // Visa
+	{"51", "55", []int{16}},                       // Mastercard
+	{"2221", "2720", []int{16}},                   // Mastercard
+	{"34", "34", []int{15}},                       // American Express
+	{"37", "37", []int{15}},                       // American Express
+	{"6011", "6011", []int{16, 17, 18, 19}},       // Discover
+	{"644", "649", []int{16, 17, 18, 19}},         // Discover
+	{"65", "65", []int{16, 17, 18, 19}},           // Discover
+	{"300", "305", []int{14, 15, 16, 17, 18, 19}}, // Diners Club
+	{"36", "36", []int{14, 15, 16, 17, 18, 19}},   // Diners Club
+	{"38", "39", []int{16, 17, 18, 19}},           // Diners Club
+	{"3528", "3589", []int{16, 17, 18, 19}},       // JCB
+	{"62", "62", []int{16, 17, 18, 19}},           // UnionPay
+}
+
+// cardNumberValid reports whether s looks like a real card number: its
+// separators are all the same, and its digits have a card network's
+// prefix and length and pass the Luhn check
+func cardNumberValid(s string) bool {
+	var digits []byte
+	var sep rune
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			digits = append(digits, byte(r))
+		case sep == 0:
+			sep = r
+		case r != sep:
+			return false
+		}
+	}
+	return knownCardNetwork(string(digits)) && luhnValid(digits)
+}
+
+// knownCardNetwork reports whether number has the prefix and length of a
+// card network's numbers
+func knownCardNetwork(number string) bool {
+	for _, n := range cardNetworks {
+		prefix := number[:min(len(n.low), len(number))]
+		if prefix < n.low || prefix > n.high {
+			continue
+		}
+		for _, l := range n.lengths {
+			if len(number) == l {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// luhnValid reports whether the ASCII digits pass the Luhn check
+func luhnValid(digits []byte) bool {
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
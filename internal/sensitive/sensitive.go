@@ -0,0 +1,204 @@
+package sensitive
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mindmorass/yippity-clippity/internal/clipboard"
+)
+
+// Action is what happens to a clip when a rule matches
+type Action string
+
+const (
+	// ActionOff disables a rule
+	ActionOff Action = "off"
+
+	// ActionRedact replaces the matched text before upload
+	ActionRedact Action = "redact"
+
+	// ActionConfirm asks the user before uploading
+	ActionConfirm Action = "confirm"
+
+	// ActionBlock never uploads the clip
+	ActionBlock Action = "block"
+)
+
+// ParseAction parses an action name from the config file
+func ParseAction(s string) (Action, error) {
+	switch a := Action(strings.ToLower(strings.TrimSpace(s))); a {
+	case ActionOff, ActionRedact, ActionConfirm, ActionBlock:
+		return a, nil
+	case "":
+		return ActionOff, nil
+	default:
+		return "", fmt.Errorf("unknown sensitive content action: %q", s)
+	}
+}
+
+// severity orders actions so the strictest matching rule wins
+func (a Action) severity() int {
+	switch a {
+	case ActionRedact:
+		return 1
+	case ActionConfirm:
+		return 2
+	case ActionBlock:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// Detector finds sensitive text
+type Detector interface {
+	// Name identifies the detector in logs and redaction markers
+	Name() string
+
+	// Find returns the [start, end) byte offsets of each match in text
+	Find(text string) [][2]int
+}
+
+// Rule pairs a detector with the action taken when it matches
+type Rule struct {
+	Detector Detector
+	Action   Action
+}
+
+// Finding is a rule that matched a clip
+type Finding struct {
+	Rule    string
+	Action  Action
+	Matches int
+}
+
+// Result is the outcome of checking a clip
+type Result struct {
+	// Action is the strictest action of all findings, or ActionOff if
+	// nothing matched
+	Action Action
+
+	Findings []Finding
+
+	// Redacted is the clip with every redact match replaced. It is nil
+	// unless a redact rule matched.
+	Redacted *clipboard.Content
+}
+
+// Names returns the names of the matching rules
+func (r Result) Names() []string {
+	names := make([]string, len(r.Findings))
+	for i, f := range r.Findings {
+		names[i] = f.Rule
+	}
+	return names
+}
+
+// Pipeline runs a set of rules against outgoing clips
+type Pipeline struct {
+	rules []Rule
+}
+
+// NewPipeline creates a pipeline. Rules with ActionOff are dropped.
+func NewPipeline(rules ...Rule) *Pipeline {
+	p := &Pipeline{}
+	for _, rule := range rules {
+		if rule.Detector != nil && rule.Action.severity() > 0 {
+			p.rules = append(p.rules, rule)
+		}
+	}
+	return p
+}
+
+// Add appends a rule to the pipeline
+func (p *Pipeline) Add(rule Rule) {
+	if rule.Detector != nil && rule.Action.severity() > 0 {
+		p.rules = append(p.rules, rule)
+	}
+}
+
+// Len returns the number of active rules
+func (p *Pipeline) Len() int {
+	return len(p.rules)
+}
+
+// Check runs every rule against a clip. Only text clips are inspected.
+func (p *Pipeline) Check(content *clipboard.Content) Result {
+	result := Result{Action: ActionOff}
+	if p == nil || content == nil || !content.IsText() {
+		return result
+	}
+
+	text := string(content.Data)
+	var redact []span
+
+	for _, rule := range p.rules {
+		matches := rule.Detector.Find(text)
+		if len(matches) == 0 {
+			continue
+		}
+
+		result.Findings = append(result.Findings, Finding{
+			Rule:    rule.Detector.Name(),
+			Action:  rule.Action,
+			Matches: len(matches),
+		})
+		if rule.Action.severity() > result.Action.severity() {
+			result.Action = rule.Action
+		}
+		if rule.Action == ActionRedact {
+			for _, m := range matches {
+				redact = append(redact, span{m[0], m[1], rule.Detector.Name()})
+			}
+		}
+	}
+
+	if len(redact) > 0 {
+		result.Redacted = redacted(content, redactText(text, redact))
+	}
+	return result
+}
+
+// span is a match to be redacted
+type span struct {
+	start, end int
+	rule       string
+}
+
+// redactText replaces each span with a marker naming the rule.
+// Overlapping spans are merged into the first one.
+func redactText(text string, spans []span) string {
+	sort.Slice(spans, func(i, j int) bool {
+		return spans[i].start < spans[j].start
+	})
+
+	var sb strings.Builder
+	pos := 0
+	for _, s := range spans {
+		if s.start < pos {
+			// Extend the previous marker over the overlap
+			pos = max(pos, s.end)
+			continue
+		}
+		sb.WriteString(text[pos:s.start])
+		sb.WriteString("[REDACTED ")
+		sb.WriteString(s.rule)
+		sb.WriteString("]")
+		pos = s.end
+	}
+	sb.WriteString(text[pos:])
+	return sb.String()
+}
+
+// redacted returns a copy of content carrying the redacted text
+func redacted(content *clipboard.Content, text string) *clipboard.Content {
+	out := *content
+	out.Data = []byte(text)
+	out.Size = int64(len(out.Data))
+	checksum := sha256.Sum256(out.Data)
+	out.Checksum = hex.EncodeToString(checksum[:])
+	return &out
+}
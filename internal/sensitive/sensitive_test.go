@@ -0,0 +1,131 @@
+package sensitive
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/mindmorass/yippity-clippity/internal/clipboard"
+)
+
+func textClip(text string) *clipboard.Content {
+	checksum := sha256.Sum256([]byte(text))
+	return &clipboard.Content{
+		ID:          "clip",
+		ContentType: clipboard.ContentTypeText,
+		MimeType:    "text/plain",
+		Checksum:    hex.EncodeToString(checksum[:]),
+		Size:        int64(len(text)),
+		Data:        []byte(text),
+	}
+}
+
+func mustRegex(t *testing.T, name, pattern string) Detector {
+	t.Helper()
+	d, err := NewRegexDetector(name, pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestCheckStrictestActionWins(t *testing.T) {
+	p := NewPipeline(
+		Rule{Detector: mustRegex(t, "a", `alpha`), Action: ActionRedact},
+		Rule{Detector: mustRegex(t, "b", `beta`), Action: ActionBlock},
+		Rule{Detector: mustRegex(t, "c", `gamma`), Action: ActionConfirm},
+		Rule{Detector: mustRegex(t, "off", `alpha`), Action: ActionOff},
+	)
+	if p.Len() != 3 {
+		t.Errorf("Len = %d, want 3 without the rule that is off", p.Len())
+	}
+
+	tests := []struct {
+		text  string
+		want  Action
+		rules int
+	}{
+		{"nothing here", ActionOff, 0},
+		{"alpha", ActionRedact, 1},
+		{"alpha gamma", ActionConfirm, 2},
+		{"gamma beta alpha", ActionBlock, 3},
+	}
+	for _, tt := range tests {
+		result := p.Check(textClip(tt.text))
+		if result.Action != tt.want {
+			t.Errorf("Check(%q).Action = %s, want %s", tt.text, result.Action, tt.want)
+		}
+		if len(result.Findings) != tt.rules {
+			t.Errorf("Check(%q) found %v, want %d rules", tt.text, result.Names(), tt.rules)
+		}
+	}
+}
+
+func TestCheckRedacts(t *testing.T) {
+	p := NewPipeline(
+		Rule{Detector: Builtin(RuleCreditCard), Action: ActionRedact},
+		Rule{Detector: mustRegex(t, "digits", `1111 1111`), Action: ActionRedact},
+		Rule{Detector: mustRegex(t, "word", `secret`), Action: ActionRedact},
+	)
+
+	clip := textClip("card 4111 1111 1111 1111, secret and secret")
+	result := p.Check(clip)
+	if result.Action != ActionRedact {
+		t.Fatalf("Action = %s, want redact", result.Action)
+	}
+	if result.Redacted == nil {
+		t.Fatal("no redacted clip")
+	}
+
+	// The digits rule's match overlaps the card number and is merged
+	// into its marker
+	want := "card [REDACTED credit_card], [REDACTED word] and [REDACTED word]"
+	if got := string(result.Redacted.Data); got != want {
+		t.Errorf("redacted text = %q, want %q", got, want)
+	}
+
+	sum := sha256.Sum256(result.Redacted.Data)
+	if result.Redacted.Checksum != hex.EncodeToString(sum[:]) {
+		t.Error("redacted clip has the checksum of the original")
+	}
+	if result.Redacted.Size != int64(len(want)) {
+		t.Errorf("redacted Size = %d, want %d", result.Redacted.Size, len(want))
+	}
+	if string(clip.Data) != "card 4111 1111 1111 1111, secret and secret" {
+		t.Error("Check changed the original clip")
+	}
+}
+
+func TestCheckOnlyInspectsText(t *testing.T) {
+	p := NewPipeline(Rule{Detector: mustRegex(t, "any", `.`), Action: ActionBlock})
+
+	image := textClip("not really an image")
+	image.ContentType = clipboard.ContentTypeImage
+	if result := p.Check(image); result.Action != ActionOff {
+		t.Errorf("image clip Action = %s, want off", result.Action)
+	}
+
+	var nilPipeline *Pipeline
+	if result := nilPipeline.Check(textClip("x")); result.Action != ActionOff {
+		t.Errorf("nil pipeline Action = %s, want off", result.Action)
+	}
+}
+
+func TestRedactTextOverlaps(t *testing.T) {
+	tests := []struct {
+		name  string
+		spans []span
+		want  string
+	}{
+		{"separate", []span{{0, 3, "a"}, {4, 8, "b"}}, "[REDACTED a] [REDACTED b] 89"},
+		{"overlapping", []span{{0, 5, "a"}, {3, 8, "b"}}, "[REDACTED a] 89"},
+		{"contained", []span{{0, 8, "a"}, {2, 4, "b"}}, "[REDACTED a] 89"},
+		{"unsorted", []span{{4, 8, "b"}, {0, 3, "a"}}, "[REDACTED a] [REDACTED b] 89"},
+		{"adjacent", []span{{0, 3, "a"}, {3, 8, "b"}}, "[REDACTED a][REDACTED b] 89"},
+	}
+	for _, tt := range tests {
+		if got := redactText("012 4567 89", tt.spans); got != tt.want {
+			t.Errorf("%s: redactText = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
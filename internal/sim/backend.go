@@ -0,0 +1,214 @@
+package sim
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mindmorass/yippity-clippity/internal/backend"
+	"github.com/mindmorass/yippity-clippity/internal/clipboard"
+	"github.com/mindmorass/yippity-clippity/internal/clock"
+	"github.com/mindmorass/yippity-clippity/internal/storage"
+)
+
+// BackendMemory is the type of the in-memory backend
+const BackendMemory backend.BackendType = "memory"
+
+// Backend is an in-memory shared location. Clips are stored in the .clip
+// format, and modification times come from the simulated clock, standing
+// in for the storage server's clock.
+type Backend struct {
+	clock    clock.Clock
+	location string
+	data     []byte
+	checksum string
+	id       string
+	modTime  time.Time
+	err      error
+	writes   int
+	reads    int
+	mu       sync.Mutex
+}
+
+// NewBackend creates an empty in-memory backend
+func NewBackend(clk clock.Clock) *Backend {
+	return &Backend{clock: clk, location: "memory://shared"}
+}
+
+// SetError makes every operation fail with err, simulating an outage.
+// Nil ends the outage.
+func (b *Backend) SetError(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.err = err
+}
+
+// Writes returns how many clips have been written
+func (b *Backend) Writes() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.writes
+}
+
+// Reads returns how many times a clip was read with its payload
+func (b *Backend) Reads() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.reads
+}
+
+// Write stores clipboard content
+func (b *Backend) Write(ctx context.Context, content *clipboard.Content) error {
+	data, err := storage.Encode(content)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.err != nil {
+		return b.err
+	}
+
+	// Every write must look newer to pollers, even within one tick
+	modTime := b.clock.Now()
+	if !modTime.After(b.modTime) {
+		modTime = b.modTime.Add(time.Nanosecond)
+	}
+
+	b.data = data
+	b.checksum = content.Checksum
+	b.id = content.ID
+	b.modTime = modTime
+	b.writes++
+	return nil
+}
+
+// Read retrieves clipboard content. It returns nil if nothing was
+// written yet.
+func (b *Backend) Read(ctx context.Context) (*clipboard.Content, error) {
+	b.mu.Lock()
+	data, err := b.data, b.err
+	if data != nil && err == nil {
+		b.reads++
+	}
+	b.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, nil
+	}
+	return storage.Decode(data)
+}
+
+// ReadHeader retrieves clipboard content without its payload. It returns
+// nil if nothing was written yet.
+func (b *Backend) ReadHeader(ctx context.Context) (*clipboard.Content, error) {
+	b.mu.Lock()
+	data, err := b.data, b.err
+	b.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, nil
+	}
+	header, _, err := storage.ReadHeader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if header.Placeholder {
+		return storage.PlaceholderContent(header)
+	}
+	return storage.ContentFromHeader(header, nil)
+}
+
+// GetModTime returns when the clip was last written
+func (b *Backend) GetModTime(ctx context.Context) (time.Time, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.err != nil {
+		return time.Time{}, b.err
+	}
+	if b.data == nil {
+		return time.Time{}, backend.ErrNotFound
+	}
+	return b.modTime, nil
+}
+
+// GetChecksum returns the checksum of the current clip
+func (b *Backend) GetChecksum(ctx context.Context) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.err != nil {
+		return "", b.err
+	}
+	if b.data == nil {
+		return "", backend.ErrNotFound
+	}
+	return b.checksum, nil
+}
+
+// Exists returns true if a clip was written
+func (b *Backend) Exists(ctx context.Context) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.err == nil && b.data != nil
+}
+
+// RemoveClip deletes the current clip if it is still the clip with id
+func (b *Backend) RemoveClip(ctx context.Context, id string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.err != nil {
+		return b.err
+	}
+	if b.data == nil {
+		return nil
+	}
+	if b.id != id {
+		return backend.ErrConflict
+	}
+	b.data = nil
+	b.checksum = ""
+	b.id = ""
+	return nil
+}
+
+// Init does nothing; the backend is always ready
+func (b *Backend) Init(ctx context.Context) error {
+	return nil
+}
+
+// Close does nothing
+func (b *Backend) Close() error {
+	return nil
+}
+
+// Type returns the backend type
+func (b *Backend) Type() backend.BackendType {
+	return BackendMemory
+}
+
+// GetLocation returns the backend's location
+func (b *Backend) GetLocation() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.location
+}
+
+// SetLocation updates the backend's location
+func (b *Backend) SetLocation(location string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.location = location
+	return nil
+}
@@ -0,0 +1,109 @@
+package sim
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	"github.com/mindmorass/yippity-clippity/internal/clipboard"
+	"github.com/mindmorass/yippity-clippity/internal/clock"
+)
+
+// Clipboard is an in-memory clipboard for one simulated device. Like the
+// system pasteboard, it only keeps the payload; every Read describes it
+// as a fresh copy on this device.
+type Clipboard struct {
+	device      string
+	clock       clock.Clock
+	changeCount int
+	reads       int
+	contentType clipboard.ContentType
+	mimeType    string
+	data        []byte
+	mu          sync.Mutex
+}
+
+// NewClipboard creates an empty clipboard for device
+func NewClipboard(device string, clk clock.Clock) *Clipboard {
+	return &Clipboard{device: device, clock: clk}
+}
+
+// Copy puts text on the clipboard, as if the user copied it
+func (c *Clipboard) Copy(text string) {
+	c.CopyData(clipboard.ContentTypeText, "text/plain", []byte(text))
+}
+
+// CopyData puts any payload on the clipboard
+func (c *Clipboard) CopyData(contentType clipboard.ContentType, mimeType string, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.contentType = contentType
+	c.mimeType = mimeType
+	c.data = append([]byte(nil), data...)
+	c.changeCount++
+}
+
+// Text returns the clipboard text, or "" if it holds something else
+func (c *Clipboard) Text() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.contentType != clipboard.ContentTypeText {
+		return ""
+	}
+	return string(c.data)
+}
+
+// Checksum returns the checksum of the clipboard payload, or "" if it is
+// empty
+func (c *Clipboard) Checksum() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.data == nil {
+		return ""
+	}
+	return checksum(c.data)
+}
+
+// GetChangeCount returns a counter that changes on every copy and write
+func (c *Clipboard) GetChangeCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.changeCount
+}
+
+// Read returns the clipboard content as a new clip from this device
+func (c *Clipboard) Read() (*clipboard.Content, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.data == nil {
+		return nil, nil
+	}
+
+	c.reads++
+	data := append([]byte(nil), c.data...)
+	return &clipboard.Content{
+		ID:            fmt.Sprintf("%s-%d", c.device, c.reads),
+		Timestamp:     c.clock.Now().UTC(),
+		SourceMachine: c.device,
+		SourceUser:    "sim",
+		ContentType:   c.contentType,
+		MimeType:      c.mimeType,
+		Checksum:      checksum(data),
+		Size:          int64(len(data)),
+		Data:          data,
+	}, nil
+}
+
+// Write replaces the clipboard content, as the engine does when applying
+// a remote clip
+func (c *Clipboard) Write(content *clipboard.Content) bool {
+	c.CopyData(content.ContentType, content.MimeType, content.Data)
+	return true
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
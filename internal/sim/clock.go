@@ -0,0 +1,110 @@
+package sim
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mindmorass/yippity-clippity/internal/clock"
+)
+
+// Clock is a clock that only moves when advanced. Tickers fire during
+// Advance, so code timed by the clock runs at simulated speed.
+type Clock struct {
+	now     time.Time
+	tickers []*ticker
+	mu      sync.Mutex
+}
+
+// NewClock creates a clock stopped at start
+func NewClock(start time.Time) *Clock {
+	return &Clock{now: start}
+}
+
+// Now returns the simulated time
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d, firing every ticker that falls
+// due. Like time.Ticker, a ticker whose tick wasn't received drops the
+// next one.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+	for _, t := range c.tickers {
+		if t.stopped || t.next.After(c.now) {
+			continue
+		}
+		select {
+		case t.c <- c.now:
+		default:
+		}
+		for !t.next.After(c.now) {
+			t.next = t.next.Add(t.period)
+		}
+	}
+}
+
+// NewTicker returns a ticker that fires every d of simulated time
+func (c *Clock) NewTicker(d time.Duration) clock.Ticker {
+	if d <= 0 {
+		panic("sim: non-positive ticker interval")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &ticker{
+		clock:  c,
+		c:      make(chan time.Time, 1),
+		period: d,
+		next:   c.now.Add(d),
+	}
+	c.tickers = append(c.tickers, t)
+	return t
+}
+
+// Skewed returns a view of the clock that runs offset ahead of it, for a
+// device whose wall clock is wrong. Tickers are shared with c.
+func (c *Clock) Skewed(offset time.Duration) clock.Clock {
+	return skewedClock{c, offset}
+}
+
+type skewedClock struct {
+	*Clock
+	offset time.Duration
+}
+
+func (s skewedClock) Now() time.Time {
+	return s.Clock.Now().Add(s.offset)
+}
+
+type ticker struct {
+	clock   *Clock
+	c       chan time.Time
+	period  time.Duration
+	next    time.Time
+	stopped bool
+}
+
+func (t *ticker) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *ticker) Reset(d time.Duration) {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	t.period = d
+	t.next = t.clock.now.Add(d)
+	t.stopped = false
+}
+
+func (t *ticker) Stop() {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	t.stopped = true
+}
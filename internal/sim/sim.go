@@ -0,0 +1,125 @@
+package sim
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mindmorass/yippity-clippity/internal/clock"
+	"github.com/mindmorass/yippity-clippity/internal/sync"
+)
+
+// DefaultStep is how far the clock moves per Step, about the engine's
+// polling interval
+const DefaultStep = 100 * time.Millisecond
+
+// Start is when every simulation starts, so runs are repeatable
+var Start = time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+// Device is a simulated machine running a sync engine
+type Device struct {
+	Name      string
+	Clock     clock.Clock
+	Clipboard *Clipboard
+	Engine    *sync.Engine
+}
+
+// Sim runs several sync engines against one shared in-memory backend.
+// Engines don't run background loops; each Step advances the clock and
+// polls every device in the order they were added, so a scenario runs
+// the same way every time.
+type Sim struct {
+	Clock   *Clock
+	Backend *Backend
+	Devices []*Device
+}
+
+// New creates a simulation with devices of the given names, all with
+// accurate clocks
+func New(names ...string) *Sim {
+	clk := NewClock(Start)
+	s := &Sim{
+		Clock:   clk,
+		Backend: NewBackend(clk),
+	}
+	for _, name := range names {
+		s.AddDevice(name, 0)
+	}
+	return s
+}
+
+// AddDevice adds a running device whose wall clock is off by skew
+func (s *Sim) AddDevice(name string, skew time.Duration) *Device {
+	clk := s.Clock.Skewed(skew)
+	cb := NewClipboard(name, clk)
+	engine := sync.NewEngineWithOptions(s.Backend, sync.EngineOptions{
+		Clipboard:     cb,
+		Clock:         clk,
+		DeviceName:    name,
+		ManualPolling: true,
+	})
+	engine.Start()
+
+	d := &Device{
+		Name:      name,
+		Clock:     clk,
+		Clipboard: cb,
+		Engine:    engine,
+	}
+	s.Devices = append(s.Devices, d)
+	return d
+}
+
+// Device returns the device with the given name, or nil
+func (s *Sim) Device(name string) *Device {
+	for _, d := range s.Devices {
+		if d.Name == name {
+			return d
+		}
+	}
+	return nil
+}
+
+// Step advances the clock by d and polls every device once
+func (s *Sim) Step(d time.Duration) {
+	s.Clock.Advance(d)
+	for _, device := range s.Devices {
+		device.Engine.Poll()
+	}
+}
+
+// Run takes n steps of DefaultStep
+func (s *Sim) Run(n int) {
+	for i := 0; i < n; i++ {
+		s.Step(DefaultStep)
+	}
+}
+
+// Settle steps until every device holds the shared clip, for at most n
+// steps. It returns an error describing the difference if they don't.
+func (s *Sim) Settle(n int) error {
+	for i := 0; i < n; i++ {
+		if s.Converged() == nil {
+			return nil
+		}
+		s.Step(DefaultStep)
+	}
+	return s.Converged()
+}
+
+// Converged returns nil if every device's clipboard holds the clip in
+// the shared location, or an error naming a device that doesn't
+func (s *Sim) Converged() error {
+	s.Backend.mu.Lock()
+	shared := s.Backend.checksum
+	s.Backend.mu.Unlock()
+
+	if shared == "" {
+		return fmt.Errorf("shared location is empty")
+	}
+	for _, d := range s.Devices {
+		if got := d.Clipboard.Checksum(); got != shared {
+			return fmt.Errorf("%s has clip %.12s, shared location has %.12s", d.Name, got, shared)
+		}
+	}
+	return nil
+}
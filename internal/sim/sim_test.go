@@ -0,0 +1,71 @@
+package sim
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSimultaneousCopiesConverge(t *testing.T) {
+	s := New("a", "b", "c")
+	s.Device("a").Clipboard.Copy("from a")
+	s.Device("b").Clipboard.Copy("from b")
+	s.Device("c").Clipboard.Copy("from c")
+
+	if err := s.Settle(50); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSkewedClocksConverge(t *testing.T) {
+	s := New("a")
+	s.AddDevice("fast", 5*time.Minute)
+	s.AddDevice("slow", -5*time.Minute)
+
+	s.Device("fast").Clipboard.Copy("fast first")
+	if err := s.Settle(50); err != nil {
+		t.Fatalf("fast device's clip: %v", err)
+	}
+
+	// Copied after the fast device's clip arrived, so it orders after
+	// it however far behind the slow clock is
+	s.Run(5)
+	s.Device("slow").Clipboard.Copy("slow second")
+	if err := s.Settle(50); err != nil {
+		t.Fatalf("slow device's clip: %v", err)
+	}
+	for _, d := range s.Devices {
+		if got := d.Clipboard.Text(); got != "slow second" {
+			t.Errorf("%s has %q, want slow second", d.Name, got)
+		}
+	}
+}
+
+func TestOutageRecovery(t *testing.T) {
+	s := New("a", "b")
+	s.Device("a").Clipboard.Copy("before")
+	if err := s.Settle(50); err != nil {
+		t.Fatalf("before outage: %v", err)
+	}
+
+	s.Backend.SetError(errors.New("shared location unavailable"))
+	writes := s.Backend.Writes()
+	s.Device("b").Clipboard.Copy("during")
+	s.Run(20)
+	if s.Backend.Writes() != writes {
+		t.Fatal("write succeeded during outage")
+	}
+	if got := s.Device("a").Clipboard.Text(); got != "before" {
+		t.Errorf("a has %q during outage, want before", got)
+	}
+
+	s.Backend.SetError(nil)
+	s.Run(5)
+	s.Device("b").Clipboard.Copy("after")
+	if err := s.Settle(50); err != nil {
+		t.Fatalf("after outage: %v", err)
+	}
+	if got := s.Device("a").Clipboard.Text(); got != "after" {
+		t.Errorf("a has %q after outage, want after", got)
+	}
+}
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"errors"
+	"io"
+
+	"github.com/mindmorass/yippity-clippity/internal/clipboard"
+)
+
+const (
+	// BlobDir is the directory holding content-addressed payloads
+	BlobDir = "blobs"
+
+	// BlobVersion is the first format version that supports manifests
+	BlobVersion uint32 = 3
+)
+
+// ErrBlobPayload is returned when decoding a manifest as a full clip
+var ErrBlobPayload = errors.New("payload is stored in a separate blob")
+
+// UsesBlobs returns true if clips written with opts are split into a
+// manifest and a blob
+func UsesBlobs(opts Options) bool {
+	return formatVersion(opts) >= BlobVersion
+}
+
+// ValidBlobName returns true if name is a lowercase hex SHA-256 digest
+func ValidBlobName(name string) bool {
+	if len(name) != 64 {
+		return false
+	}
+	for _, c := range name {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// EncodeManifest serializes the header of content with no payload. The
+// payload is referenced by its checksum and stored as a blob.
+func EncodeManifest(content *clipboard.Content) ([]byte, error) {
+	if content == nil {
+		return nil, errors.New("content is nil")
+	}
+	if !ValidBlobName(content.Checksum) {
+		return nil, ErrInvalidHeader
+	}
+
+	header := ManifestHeader(content)
+	return encodePreamble(header, BlobVersion)
+}
+
+// ManifestHeader returns the manifest header for content, referencing its
+// payload blob by checksum
+func ManifestHeader(content *clipboard.Content) FileHeader {
+	header := newFileHeader(content, CompressionNone)
+	header.Blob = content.Checksum
+	return header
+}
+
+// EncodeBlobTo streams the payload of content to w as a blob. Blobs use
+// the .clip format with only the payload fields set, so identical data
+// from different devices encodes to interchangeable blobs.
+func EncodeBlobTo(w io.Writer, content *clipboard.Content, opts Options) error {
+	if content == nil {
+		return errors.New("content is nil")
+	}
+	return EncodeTo(w, blobContent(content), opts)
+}
+
+// EncodeBlobReader returns the blob for content as a seekable reader
+// along with its length
+func EncodeBlobReader(content *clipboard.Content, opts Options) (io.ReadSeeker, int64, error) {
+	if content == nil {
+		return nil, 0, errors.New("content is nil")
+	}
+	return EncodeReader(blobContent(content), opts)
+}
+
+// PlaceholderContent returns the clip described by a placeholder manifest,
+// without its payload
+func PlaceholderContent(header *FileHeader) (*clipboard.Content, error) {
+	if !header.Placeholder || header.Blob == "" {
+		return nil, ErrInvalidHeader
+	}
+	return ContentFromHeader(header, nil)
+}
+
+// DecodeBlobFrom reads a blob from r and verifies it holds the payload
+// the manifest header refers to
+func DecodeBlobFrom(r io.Reader, header *FileHeader) ([]byte, error) {
+	content, err := DecodeFrom(r)
+	if err != nil {
+		return nil, err
+	}
+	if content.Checksum != header.Blob || content.Size != header.Size {
+		return nil, ErrChecksumMismatch
+	}
+	return content.Data, nil
+}
+
+// blobContent strips everything but the payload fields from content
+func blobContent(content *clipboard.Content) *clipboard.Content {
+	return &clipboard.Content{
+		ContentType: content.ContentType,
+		MimeType:    content.MimeType,
+		Checksum:    content.Checksum,
+		Size:        content.Size,
+		Data:        content.Data,
+	}
+}
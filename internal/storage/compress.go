@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression identifies the payload compression of a version 2 file
+type Compression string
+
+const (
+	CompressionNone Compression = ""
+	CompressionGzip Compression = "gzip"
+	CompressionZstd Compression = "zstd"
+)
+
+const (
+	// MinCompressSize is the smallest payload worth compressing
+	MinCompressSize = 1024
+
+	// minCompressSavings is the fraction a payload must shrink by for the
+	// compressed form to be stored
+	minCompressSavings = 0.1
+)
+
+// ParseCompression converts a config value to a Compression
+func ParseCompression(s string) (Compression, error) {
+	switch strings.ToLower(s) {
+	case "", "none":
+		return CompressionNone, nil
+	case "gzip":
+		return CompressionGzip, nil
+	case "zstd":
+		return CompressionZstd, nil
+	default:
+		return CompressionNone, fmt.Errorf("unknown compression: %s", s)
+	}
+}
+
+// Options controls how clips are written
+type Options struct {
+	// Version is the format version to write. Version 1 can be read by
+	// every release; stay on it until all devices understand version 2.
+	Version uint32
+
+	// Compression is the payload compression for version 2 files
+	Compression Compression
+}
+
+// DefaultOptions returns the options used by Encode. They write the
+// oldest version, so every device can read the result.
+func DefaultOptions() Options {
+	return Options{
+		Version:     OldestVersion,
+		Compression: CompressionZstd,
+	}
+}
+
+// shouldCompress returns true if compressing the payload may pay off.
+// Formats that are already compressed are skipped.
+func shouldCompress(content []byte, mimeType string, opts Options) bool {
+	if opts.Version < 2 || opts.Compression == CompressionNone {
+		return false
+	}
+	if len(content) < MinCompressSize {
+		return false
+	}
+	switch mimeType {
+	case "image/png", "image/jpeg", "image/gif", "image/webp", "image/heic":
+		return false
+	}
+	return true
+}
+
+// compress compresses data, returning nil if the result isn't
+// meaningfully smaller than the input
+func compress(data []byte, c Compression) ([]byte, error) {
+	var buf bytes.Buffer
+
+	switch c {
+	case CompressionGzip:
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(data); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+	case CompressionZstd:
+		zw, err := zstd.NewWriter(&buf, zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		if _, err := zw.Write(data); err != nil {
+			zw.Close()
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown compression: %s", c)
+	}
+
+	if float64(buf.Len()) > float64(len(data))*(1-minCompressSavings) {
+		return nil, nil
+	}
+	return buf.Bytes(), nil
+}
+
+// newCompressor wraps w to compress a payload as it is written
+func newCompressor(w io.Writer, c Compression) (io.WriteCloser, error) {
+	switch c {
+	case CompressionGzip:
+		return gzip.NewWriter(w), nil
+	case CompressionZstd:
+		return zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+	default:
+		return nil, fmt.Errorf("unknown compression: %s", c)
+	}
+}
+
+// newDecompressor wraps r to decompress a payload
+func newDecompressor(r io.Reader, c Compression) (io.ReadCloser, error) {
+	switch c {
+	case CompressionNone:
+		return io.NopCloser(r), nil
+	case CompressionGzip:
+		return gzip.NewReader(r)
+	case CompressionZstd:
+		zr, err := zstd.NewReader(r,
+			zstd.WithDecoderConcurrency(1),
+			zstd.WithDecoderMaxMemory(MaxPayloadSize),
+		)
+		if err != nil {
+			return nil, err
+		}
+		return zr.IOReadCloser(), nil
+	default:
+		return nil, fmt.Errorf("unknown compression: %s", c)
+	}
+}
@@ -2,9 +2,7 @@ package storage
 
 import (
 	"bytes"
-	"crypto/sha256"
 	"encoding/binary"
-	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"io"
@@ -18,7 +16,13 @@ const (
 	MagicBytes = "YCLP"
 
 	// CurrentVersion is the current file format version
-	CurrentVersion uint32 = 1
+	// Version 2 adds optional payload compression
+	// Version 3 adds manifests whose payload is stored as a separate blob
+	CurrentVersion uint32 = 3
+
+	// OldestVersion is the format version every release can read. It is
+	// written unless a newer version is asked for.
+	OldestVersion uint32 = 1
 
 	// MaxHeaderSize limits header size to prevent memory issues
 	MaxHeaderSize = 1024 * 1024 // 1 MB
@@ -38,49 +42,130 @@ var (
 
 // FileHeader represents the JSON metadata in the file header
 type FileHeader struct {
-	ID            string `json:"id"`
-	Timestamp     string `json:"timestamp"`
-	SourceMachine string `json:"source_machine"`
-	SourceUser    string `json:"source_user"`
-	ContentType   string `json:"content_type"`
-	MimeType      string `json:"mime_type"`
-	Checksum      string `json:"checksum"`
-	Size          int64  `json:"size"`
+	ID               string `json:"id"`
+	Timestamp        string `json:"timestamp"`
+	Clock            string `json:"clock,omitempty"`
+	SourceMachine    string `json:"source_machine"`
+	SourceUser       string `json:"source_user"`
+	SourceApp        string `json:"source_app,omitempty"`
+	ContentType      string `json:"content_type"`
+	MimeType         string `json:"mime_type"`
+	OriginalMimeType string `json:"original_mime_type,omitempty"`
+	Checksum         string `json:"checksum"`
+	Size             int64  `json:"size"`
+	FenceToken       uint64 `json:"fence_token,omitempty"`
+	Compression      string `json:"compression,omitempty"` // Version 2 only
+	Blob             string `json:"blob,omitempty"`        // Version 3 only
+	Signer           string `json:"signer,omitempty"`
+	Signature        []byte `json:"signature,omitempty"`
+	ExpiresAt        string `json:"expires_at,omitempty"`
+	Placeholder      bool   `json:"placeholder,omitempty"` // Version 3 only
 }
 
 // Encode serializes clipboard content to the .clip format
 func Encode(content *clipboard.Content) ([]byte, error) {
+	return EncodeWithOptions(content, DefaultOptions())
+}
+
+// EncodeWithOptions serializes clipboard content using the given format
+// version and compression. The checksum always covers the uncompressed
+// payload, and compression is only used when it pays off.
+func EncodeWithOptions(content *clipboard.Content, opts Options) ([]byte, error) {
 	if content == nil {
 		return nil, errors.New("content is nil")
 	}
 
-	// Create header
-	header := FileHeader{
-		ID:            content.ID,
-		Timestamp:     content.Timestamp.Format("2006-01-02T15:04:05.000Z07:00"),
-		SourceMachine: content.SourceMachine,
-		SourceUser:    content.SourceUser,
-		ContentType:   string(content.ContentType),
-		MimeType:      content.MimeType,
-		Checksum:      content.Checksum,
-		Size:          content.Size,
+	payload, compression, err := preparePayload(content, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	preamble, err := encodePreamble(newFileHeader(content, compression), formatVersion(opts))
+	if err != nil {
+		return nil, err
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, len(preamble)+len(payload)))
+	buf.Write(preamble)
+	buf.Write(payload)
+
+	return buf.Bytes(), nil
+}
+
+// Decode deserializes the .clip format to clipboard content
+func Decode(data []byte) (*clipboard.Content, error) {
+	if len(data) < 12 {
+		return nil, ErrInvalidMagic
+	}
+	return DecodeFrom(bytes.NewReader(data))
+}
+
+// formatVersion returns the version to write for the options
+func formatVersion(opts Options) uint32 {
+	switch {
+	case opts.Version == 0:
+		return OldestVersion
+	case opts.Version > CurrentVersion:
+		return CurrentVersion
+	}
+	return opts.Version
+}
+
+// preparePayload returns the payload bytes to store, compressed if that
+// pays off
+func preparePayload(content *clipboard.Content, opts Options) ([]byte, Compression, error) {
+	if !shouldCompress(content.Data, content.MimeType, opts) {
+		return content.Data, CompressionNone, nil
 	}
 
+	compressed, err := compress(content.Data, opts.Compression)
+	if err != nil {
+		return nil, CompressionNone, err
+	}
+	if compressed == nil {
+		return content.Data, CompressionNone, nil
+	}
+	return compressed, opts.Compression, nil
+}
+
+// newFileHeader builds the header for content
+func newFileHeader(content *clipboard.Content, compression Compression) FileHeader {
+	return FileHeader{
+		ID:               content.ID,
+		Timestamp:        content.Timestamp.Format("2006-01-02T15:04:05.000Z07:00"),
+		Clock:            content.Clock.String(),
+		SourceMachine:    content.SourceMachine,
+		SourceUser:       content.SourceUser,
+		SourceApp:        content.SourceApp,
+		ContentType:      string(content.ContentType),
+		MimeType:         content.MimeType,
+		OriginalMimeType: content.OriginalMimeType,
+		Checksum:         content.Checksum,
+		Size:             content.Size,
+		FenceToken:       content.FenceToken,
+		Compression:      string(compression),
+		Signer:           content.Signer,
+		Signature:        content.Signature,
+		ExpiresAt:        formatTime(content.ExpiresAt),
+		Placeholder:      content.Placeholder,
+	}
+}
+
+// encodePreamble returns everything before the payload:
+// 4 (magic) + 4 (version) + 4 (header length) + header
+func encodePreamble(header FileHeader, version uint32) ([]byte, error) {
 	headerBytes, err := json.Marshal(header)
 	if err != nil {
 		return nil, err
 	}
 
-	// Calculate total size
-	// 4 (magic) + 4 (version) + 4 (header length) + header + payload
-	totalSize := 12 + len(headerBytes) + len(content.Data)
-	buf := bytes.NewBuffer(make([]byte, 0, totalSize))
+	buf := bytes.NewBuffer(make([]byte, 0, 12+len(headerBytes)))
 
 	// Write magic bytes
 	buf.WriteString(MagicBytes)
 
 	// Write version (big-endian)
-	if err := binary.Write(buf, binary.BigEndian, CurrentVersion); err != nil {
+	if err := binary.Write(buf, binary.BigEndian, version); err != nil {
 		return nil, err
 	}
 
@@ -92,92 +177,85 @@ func Encode(content *clipboard.Content) ([]byte, error) {
 	// Write header
 	buf.Write(headerBytes)
 
-	// Write payload
-	buf.Write(content.Data)
-
 	return buf.Bytes(), nil
 }
 
-// Decode deserializes the .clip format to clipboard content
-func Decode(data []byte) (*clipboard.Content, error) {
-	if len(data) < 12 {
-		return nil, ErrInvalidMagic
-	}
-
-	reader := bytes.NewReader(data)
-
+// ReadHeader reads the magic bytes, version and JSON header from r,
+// leaving r positioned at the start of the payload
+func ReadHeader(r io.Reader) (*FileHeader, uint32, error) {
 	// Read and verify magic bytes
 	magic := make([]byte, 4)
-	if _, err := io.ReadFull(reader, magic); err != nil {
-		return nil, err
+	if _, err := io.ReadFull(r, magic); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, 0, ErrInvalidMagic
+		}
+		return nil, 0, err
 	}
 	if string(magic) != MagicBytes {
-		return nil, ErrInvalidMagic
+		return nil, 0, ErrInvalidMagic
 	}
 
 	// Read version
 	var version uint32
-	if err := binary.Read(reader, binary.BigEndian, &version); err != nil {
-		return nil, err
+	if err := binary.Read(r, binary.BigEndian, &version); err != nil {
+		return nil, 0, err
 	}
 	if version > CurrentVersion {
-		return nil, ErrInvalidVersion
+		return nil, 0, ErrInvalidVersion
 	}
 
 	// Read header length
 	var headerLen uint32
-	if err := binary.Read(reader, binary.BigEndian, &headerLen); err != nil {
-		return nil, err
+	if err := binary.Read(r, binary.BigEndian, &headerLen); err != nil {
+		return nil, 0, err
 	}
 	if headerLen > MaxHeaderSize {
-		return nil, ErrHeaderTooLarge
+		return nil, 0, ErrHeaderTooLarge
 	}
 
 	// Read header
 	headerBytes := make([]byte, headerLen)
-	if _, err := io.ReadFull(reader, headerBytes); err != nil {
-		return nil, err
+	if _, err := io.ReadFull(r, headerBytes); err != nil {
+		return nil, 0, err
 	}
 
 	var header FileHeader
 	if err := json.Unmarshal(headerBytes, &header); err != nil {
-		return nil, ErrInvalidHeader
+		return nil, 0, ErrInvalidHeader
 	}
 
 	// Validate payload size
+	if header.Size < 0 {
+		return nil, 0, ErrInvalidHeader
+	}
 	if header.Size > MaxPayloadSize {
-		return nil, ErrPayloadTooLarge
+		return nil, 0, ErrPayloadTooLarge
 	}
 
-	// Read payload
-	payload := make([]byte, header.Size)
-	if _, err := io.ReadFull(reader, payload); err != nil {
-		return nil, err
+	// The blob name ends up in a path, only accept a plain hash, and it
+	// must name the payload the header describes
+	if header.Blob != "" && (!ValidBlobName(header.Blob) || header.Blob != header.Checksum) {
+		return nil, 0, ErrInvalidHeader
 	}
 
-	// Verify checksum
-	checksum := sha256.Sum256(payload)
-	if hex.EncodeToString(checksum[:]) != header.Checksum {
-		return nil, ErrChecksumMismatch
-	}
+	return &header, version, nil
+}
 
-	// Parse timestamp
-	timestamp, err := parseTimestamp(header.Timestamp)
-	if err != nil {
-		return nil, err
+// Expiry returns when the clip expires, or the zero time if it doesn't
+func (h *FileHeader) Expiry() (time.Time, error) {
+	if h.ExpiresAt == "" {
+		return time.Time{}, nil
 	}
+	return parseTimestamp(h.ExpiresAt)
+}
 
-	return &clipboard.Content{
-		ID:            header.ID,
-		Timestamp:     timestamp,
-		SourceMachine: header.SourceMachine,
-		SourceUser:    header.SourceUser,
-		ContentType:   clipboard.ContentType(header.ContentType),
-		MimeType:      header.MimeType,
-		Checksum:      header.Checksum,
-		Size:          header.Size,
-		Data:          payload,
-	}, nil
+// formatTime formats an optional header timestamp. The zero time is left
+// empty.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02T15:04:05.000Z07:00")
 }
 
 func parseTimestamp(s string) (time.Time, error) {
@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mindmorass/yippity-clippity/internal/clipboard"
+)
+
+func testContent(text string) *clipboard.Content {
+	checksum := sha256.Sum256([]byte(text))
+	return &clipboard.Content{
+		ID:            "clip-1",
+		Timestamp:     time.Now(),
+		SourceMachine: "test",
+		ContentType:   clipboard.ContentTypeText,
+		MimeType:      "text/plain",
+		Checksum:      hex.EncodeToString(checksum[:]),
+		Size:          int64(len(text)),
+		Data:          []byte(text),
+	}
+}
+
+func TestWrittenVersion(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		want uint32
+	}{
+		{"defaults", DefaultOptions(), OldestVersion},
+		{"zero value", Options{}, OldestVersion},
+		{"zero version with compression", Options{Compression: CompressionZstd}, OldestVersion},
+		{"version 2", Options{Version: 2, Compression: CompressionZstd}, 2},
+		{"too new", Options{Version: CurrentVersion + 1}, CurrentVersion},
+	}
+
+	content := testContent(strings.Repeat("compressible ", 200))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := EncodeWithOptions(content, tt.opts)
+			if err != nil {
+				t.Fatalf("EncodeWithOptions: %v", err)
+			}
+			_, version, err := ReadHeader(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("ReadHeader: %v", err)
+			}
+			if version != tt.want {
+				t.Errorf("written version = %d, want %d", version, tt.want)
+			}
+
+			decoded, err := Decode(data)
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if !bytes.Equal(decoded.Data, content.Data) {
+				t.Error("payload changed in round trip")
+			}
+		})
+	}
+}
+
+func TestEncodeWritesOldestVersion(t *testing.T) {
+	data, err := Encode(testContent("hello"))
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if _, version, _ := ReadHeader(bytes.NewReader(data)); version != OldestVersion {
+		t.Errorf("Encode wrote version %d, want %d", version, OldestVersion)
+	}
+}
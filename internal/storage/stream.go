@@ -0,0 +1,199 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+
+	"github.com/mindmorass/yippity-clippity/internal/clipboard"
+	"github.com/mindmorass/yippity-clippity/internal/hlc"
+)
+
+// maxInitialBuffer caps the up-front allocation when decoding a payload.
+// The declared size comes from the (untrusted) header, so larger payloads
+// grow the buffer as data actually arrives.
+const maxInitialBuffer = 8 * 1024 * 1024 // 8 MB
+
+// EncodeTo streams clipboard content in the .clip format to w.
+// Unlike EncodeWithOptions the payload is compressed on the fly without a
+// trial run, so it is compressed whenever the size and type heuristics
+// allow. The checksum is verified against the data as it is written.
+func EncodeTo(w io.Writer, content *clipboard.Content, opts Options) error {
+	if content == nil {
+		return errors.New("content is nil")
+	}
+
+	compression := CompressionNone
+	if shouldCompress(content.Data, content.MimeType, opts) {
+		compression = opts.Compression
+	}
+
+	preamble, err := encodePreamble(newFileHeader(content, compression), formatVersion(opts))
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(preamble); err != nil {
+		return err
+	}
+
+	hasher := sha256.New()
+	payload := io.TeeReader(bytes.NewReader(content.Data), hasher)
+
+	if compression == CompressionNone {
+		if _, err := io.Copy(w, payload); err != nil {
+			return err
+		}
+	} else {
+		zw, err := newCompressor(w, compression)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(zw, payload); err != nil {
+			zw.Close()
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+	}
+
+	return verifyChecksum(hasher, content.Checksum)
+}
+
+// EncodeReader returns the encoded clip as a seekable reader along with its
+// length, for uploads that need a known Content-Length. Uncompressed
+// payloads are read straight from content.Data instead of being copied.
+func EncodeReader(content *clipboard.Content, opts Options) (io.ReadSeeker, int64, error) {
+	if content == nil {
+		return nil, 0, errors.New("content is nil")
+	}
+
+	payload, compression, err := preparePayload(content, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	preamble, err := encodePreamble(newFileHeader(content, compression), formatVersion(opts))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	size := int64(len(preamble) + len(payload))
+	return io.NewSectionReader(concatReaderAt{preamble, payload}, 0, size), size, nil
+}
+
+// DecodeFrom reads clipboard content in the .clip format from r.
+// The payload is decompressed and hashed as it is read, and the buffer
+// grows with the data received rather than the size the header declares.
+// Manifests whose payload lives in a blob return ErrBlobPayload.
+func DecodeFrom(r io.Reader) (*clipboard.Content, error) {
+	header, version, err := ReadHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	return DecodePayload(r, header, version)
+}
+
+// DecodePayload reads the inline payload that follows header in r
+func DecodePayload(r io.Reader, header *FileHeader, version uint32) (*clipboard.Content, error) {
+	if header.Blob != "" {
+		return nil, ErrBlobPayload
+	}
+
+	// Version 1 files are never compressed
+	compression := Compression(header.Compression)
+	if version < 2 {
+		compression = CompressionNone
+	}
+
+	// Read payload, decompressing up to the declared size
+	payloadReader, err := newDecompressor(r, compression)
+	if err != nil {
+		return nil, err
+	}
+	defer payloadReader.Close()
+
+	hasher := sha256.New()
+	buf := bytes.NewBuffer(make([]byte, 0, min(header.Size, maxInitialBuffer)))
+	n, err := io.Copy(buf, io.TeeReader(io.LimitReader(payloadReader, header.Size), hasher))
+	if err != nil {
+		return nil, err
+	}
+	if n != header.Size {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	// Verify checksum
+	if err := verifyChecksum(hasher, header.Checksum); err != nil {
+		return nil, err
+	}
+
+	return ContentFromHeader(header, buf.Bytes())
+}
+
+// ContentFromHeader builds clipboard content from a header and its
+// already verified payload
+func ContentFromHeader(header *FileHeader, data []byte) (*clipboard.Content, error) {
+	timestamp, err := parseTimestamp(header.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+	expiresAt, err := header.Expiry()
+	if err != nil {
+		return nil, err
+	}
+	clock, err := hlc.Parse(header.Clock)
+	if err != nil {
+		return nil, err
+	}
+
+	return &clipboard.Content{
+		ID:               header.ID,
+		Timestamp:        timestamp,
+		Clock:            clock,
+		SourceMachine:    header.SourceMachine,
+		SourceUser:       header.SourceUser,
+		SourceApp:        header.SourceApp,
+		ContentType:      clipboard.ContentType(header.ContentType),
+		MimeType:         header.MimeType,
+		OriginalMimeType: header.OriginalMimeType,
+		Checksum:         header.Checksum,
+		Size:             header.Size,
+		FenceToken:       header.FenceToken,
+		Signer:           header.Signer,
+		Signature:        header.Signature,
+		ExpiresAt:        expiresAt,
+		Placeholder:      header.Placeholder,
+		Data:             data,
+	}, nil
+}
+
+func verifyChecksum(h hash.Hash, checksum string) error {
+	if hex.EncodeToString(h.Sum(nil)) != checksum {
+		return ErrChecksumMismatch
+	}
+	return nil
+}
+
+// concatReaderAt reads two byte slices as one
+type concatReaderAt [2][]byte
+
+func (c concatReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n := 0
+	for _, part := range c {
+		if off >= int64(len(part)) {
+			off -= int64(len(part))
+			continue
+		}
+		copied := copy(p[n:], part[off:])
+		n += copied
+		off = 0
+		if n == len(p) {
+			return n, nil
+		}
+	}
+	return n, io.EOF
+}
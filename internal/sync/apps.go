@@ -0,0 +1,99 @@
+package sync
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// AppFilter decides which apps' clips may be synced, by bundle ID.
+// Denied apps are never synced. If the allow list isn't empty, only
+// clips from allowed apps are synced.
+type AppFilter struct {
+	allow map[string]bool
+	deny  map[string]bool
+	mu    sync.Mutex
+}
+
+// NewAppFilter creates a filter from allow and deny lists
+func NewAppFilter(allow, deny []string) *AppFilter {
+	return &AppFilter{
+		allow: appSet(allow),
+		deny:  appSet(deny),
+	}
+}
+
+// Allowed returns true if clips copied from bundleID may be synced.
+// With an allow list, clips from an unknown app are not synced.
+func (f *AppFilter) Allowed(bundleID string) bool {
+	if f == nil {
+		return true
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	id := normalizeApp(bundleID)
+	if f.deny[id] {
+		return false
+	}
+	if len(f.allow) > 0 {
+		return f.allow[id]
+	}
+	return true
+}
+
+// Denied returns true if bundleID is on the deny list
+func (f *AppFilter) Denied(bundleID string) bool {
+	if f == nil {
+		return false
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.deny[normalizeApp(bundleID)]
+}
+
+// SetDenied adds bundleID to or removes it from the deny list
+func (f *AppFilter) SetDenied(bundleID string, denied bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	id := normalizeApp(bundleID)
+	if id == "" {
+		return
+	}
+	if denied {
+		f.deny[id] = true
+	} else {
+		delete(f.deny, id)
+	}
+}
+
+// DenyList returns the denied bundle IDs, sorted
+func (f *AppFilter) DenyList() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	list := make([]string, 0, len(f.deny))
+	for id := range f.deny {
+		list = append(list, id)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// normalizeApp compares bundle IDs case-insensitively, as macOS does
+func normalizeApp(bundleID string) string {
+	return strings.ToLower(strings.TrimSpace(bundleID))
+}
+
+func appSet(ids []string) map[string]bool {
+	set := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if id = normalizeApp(id); id != "" {
+			set[id] = true
+		}
+	}
+	return set
+}
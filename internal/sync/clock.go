@@ -0,0 +1,36 @@
+package sync
+
+import (
+	"time"
+
+	"github.com/mindmorass/yippity-clippity/internal/clipboard"
+	"github.com/mindmorass/yippity-clippity/internal/hlc"
+)
+
+// clockOf returns the clock timestamp of a clip. Clips from versions
+// without hybrid clocks fall back to their wall clock time.
+func clockOf(content *clipboard.Content) hlc.Timestamp {
+	if content.Clock.IsZero() {
+		return hlc.FromTime(content.Timestamp)
+	}
+	return content.Clock
+}
+
+// clockTime returns the wall clock time of a clip's clock timestamp
+func clockTime(content *clipboard.Content) time.Time {
+	return time.UnixMilli(clockOf(content).Wall)
+}
+
+// isNewer returns true if clip a was written after clip b. Clips with the
+// same clock timestamp are ordered by the device key that signed them, so
+// every device picks the same winner even if two share a hostname. The
+// source machine only decides between unsigned clips.
+func isNewer(a, b *clipboard.Content) bool {
+	if c := clockOf(a).Compare(clockOf(b)); c != 0 {
+		return c > 0
+	}
+	if a.Signer != b.Signer {
+		return a.Signer > b.Signer
+	}
+	return a.SourceMachine > b.SourceMachine
+}
@@ -0,0 +1,164 @@
+package sync
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mindmorass/yippity-clippity/internal/clipboard"
+)
+
+// Conflict strategy names, as used in the config file
+const (
+	StrategyLastWriteWins = "last-write-wins"
+	StrategyPreferLocal   = "prefer-local"
+	StrategyPreferDevice  = "prefer-device"
+	StrategyKeepBoth      = "keep-both"
+)
+
+// DefaultConflictWindow is how long after a local copy the prefer-local
+// and keep-both strategies treat a remote clip as conflicting
+const DefaultConflictWindow = 10 * time.Second
+
+// Conflict is a remote clip arriving after a local copy
+type Conflict struct {
+	Local  *clipboard.Content
+	Remote *clipboard.Content
+
+	// Now is the time the conflict is judged at. The engine passes the
+	// time the remote clip was copied, by its clock timestamp, rather
+	// than when it arrived, so the outcome depends only on the clips.
+	Now time.Time
+}
+
+// LocalAge returns how long before Now the local clip was copied
+func (c Conflict) LocalAge() time.Duration {
+	return c.Now.Sub(clockTime(c.Local))
+}
+
+// Resolution is what a ConflictResolver decided
+type Resolution struct {
+	// Remote is true if the remote clip replaces the local one
+	Remote bool
+
+	// KeepLoser saves the losing clip in the history and reports the
+	// conflict
+	KeepLoser bool
+}
+
+// Loser returns the clip that lost the conflict
+func (r Resolution) Loser(c Conflict) *clipboard.Content {
+	if r.Remote {
+		return c.Local
+	}
+	return c.Remote
+}
+
+// ConflictResolver decides between the local clip and a remote clip.
+// Resolvers must only depend on the conflict, so every device holding
+// the same clips decides the same way.
+type ConflictResolver interface {
+	// Name returns the strategy name
+	Name() string
+
+	// Resolve picks the winning clip
+	Resolve(c Conflict) Resolution
+}
+
+// NewConflictResolver creates the resolver for a strategy from the config
+// file. The window applies to prefer-local and keep-both, and device to
+// prefer-device.
+func NewConflictResolver(strategy string, window time.Duration, device string) (ConflictResolver, error) {
+	if window <= 0 {
+		window = DefaultConflictWindow
+	}
+
+	switch strings.ToLower(strings.TrimSpace(strategy)) {
+	case StrategyLastWriteWins, "":
+		return LastWriteWins{}, nil
+	case StrategyPreferLocal:
+		return PreferLocal{Window: window}, nil
+	case StrategyPreferDevice:
+		if device == "" {
+			return nil, fmt.Errorf("%s needs a device name", StrategyPreferDevice)
+		}
+		return PreferDevice{Device: device}, nil
+	case StrategyKeepBoth:
+		return KeepBoth{Window: window}, nil
+	default:
+		return nil, fmt.Errorf("unknown conflict strategy: %q", strategy)
+	}
+}
+
+// LastWriteWins applies the remote clip if it was written after the local
+// one
+type LastWriteWins struct{}
+
+// Name returns the strategy name
+func (LastWriteWins) Name() string {
+	return StrategyLastWriteWins
+}
+
+// Resolve picks the clip with the later clock timestamp
+func (LastWriteWins) Resolve(c Conflict) Resolution {
+	return Resolution{Remote: isNewer(c.Remote, c.Local)}
+}
+
+// PreferLocal keeps the local clip for Window after it was copied, then
+// falls back to last-write-wins
+type PreferLocal struct {
+	Window time.Duration
+}
+
+// Name returns the strategy name
+func (PreferLocal) Name() string {
+	return StrategyPreferLocal
+}
+
+// Resolve keeps recent local copies
+func (p PreferLocal) Resolve(c Conflict) Resolution {
+	if c.LocalAge() < p.Window {
+		return Resolution{}
+	}
+	return LastWriteWins{}.Resolve(c)
+}
+
+// PreferDevice lets clips from Device win every conflict. Conflicts
+// between other devices fall back to last-write-wins.
+type PreferDevice struct {
+	Device string
+}
+
+// Name returns the strategy name
+func (PreferDevice) Name() string {
+	return StrategyPreferDevice
+}
+
+// Resolve picks the preferred device's clip
+func (p PreferDevice) Resolve(c Conflict) Resolution {
+	remote := strings.EqualFold(c.Remote.SourceMachine, p.Device)
+	local := strings.EqualFold(c.Local.SourceMachine, p.Device)
+	if remote != local {
+		return Resolution{Remote: remote}
+	}
+	return LastWriteWins{}.Resolve(c)
+}
+
+// KeepBoth picks the winner by last-write-wins. If the clips were copied
+// within Window of each other, or the remote clip is older, the loser is
+// kept in the history instead of being dropped.
+type KeepBoth struct {
+	Window time.Duration
+}
+
+// Name returns the strategy name
+func (KeepBoth) Name() string {
+	return StrategyKeepBoth
+}
+
+// Resolve picks the newer clip and keeps the loser of a close call
+func (k KeepBoth) Resolve(c Conflict) Resolution {
+	r := LastWriteWins{}.Resolve(c)
+	r.KeepLoser = !r.Remote || c.LocalAge() < k.Window
+	return r
+}
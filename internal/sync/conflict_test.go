@@ -0,0 +1,140 @@
+package sync
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mindmorass/yippity-clippity/internal/clipboard"
+	"github.com/mindmorass/yippity-clippity/internal/hlc"
+)
+
+// base is the time the local clip in every test conflict was copied
+var base = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// clipAt returns a clip from device copied offset after base
+func clipAt(device string, offset time.Duration) *clipboard.Content {
+	t := base.Add(offset)
+	return &clipboard.Content{
+		ID:            device,
+		Timestamp:     t,
+		Clock:         hlc.FromTime(t),
+		SourceMachine: device,
+	}
+}
+
+// conflictAt returns a conflict between a local clip from local copied at
+// base and a remote clip from remote copied offset later, judged when the
+// remote clip was copied
+func conflictAt(local, remote string, offset time.Duration) Conflict {
+	r := clipAt(remote, offset)
+	return Conflict{Local: clipAt(local, 0), Remote: r, Now: clockTime(r)}
+}
+
+func TestConflictResolvers(t *testing.T) {
+	const window = 10 * time.Second
+
+	tests := []struct {
+		name     string
+		resolver ConflictResolver
+		conflict Conflict
+		want     Resolution
+	}{
+		// Last write wins
+		{"lww newer remote", LastWriteWins{}, conflictAt("a", "b", time.Second), Resolution{Remote: true}},
+		{"lww older remote", LastWriteWins{}, conflictAt("a", "b", -time.Second), Resolution{}},
+		{"lww tie, higher device name", LastWriteWins{}, conflictAt("a", "b", 0), Resolution{Remote: true}},
+		{"lww tie, lower device name", LastWriteWins{}, conflictAt("b", "a", 0), Resolution{}},
+
+		// Prefer local
+		{"prefer-local inside window", PreferLocal{Window: window}, conflictAt("a", "b", 5*time.Second), Resolution{}},
+		{"prefer-local at window", PreferLocal{Window: window}, conflictAt("a", "b", window), Resolution{Remote: true}},
+		{"prefer-local after window", PreferLocal{Window: window}, conflictAt("a", "b", time.Minute), Resolution{Remote: true}},
+		{"prefer-local older remote", PreferLocal{Window: window}, conflictAt("a", "b", -time.Minute), Resolution{}},
+
+		// Prefer device
+		{"prefer-device remote preferred", PreferDevice{Device: "b"}, conflictAt("a", "b", -time.Minute), Resolution{Remote: true}},
+		{"prefer-device local preferred", PreferDevice{Device: "a"}, conflictAt("a", "b", time.Minute), Resolution{}},
+		{"prefer-device ignores case", PreferDevice{Device: "B"}, conflictAt("a", "b", -time.Second), Resolution{Remote: true}},
+		{"prefer-device neither, newer remote", PreferDevice{Device: "c"}, conflictAt("a", "b", time.Second), Resolution{Remote: true}},
+		{"prefer-device neither, older remote", PreferDevice{Device: "c"}, conflictAt("a", "b", -time.Second), Resolution{}},
+
+		// Keep both
+		{"keep-both close call", KeepBoth{Window: window}, conflictAt("a", "b", time.Second), Resolution{Remote: true, KeepLoser: true}},
+		{"keep-both later remote", KeepBoth{Window: window}, conflictAt("a", "b", time.Minute), Resolution{Remote: true}},
+		{"keep-both older remote", KeepBoth{Window: window}, conflictAt("a", "b", -time.Minute), Resolution{KeepLoser: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.resolver.Resolve(tt.conflict)
+			if got != tt.want {
+				t.Errorf("Resolve = %+v, want %+v", got, tt.want)
+			}
+
+			// The same clips always resolve the same way
+			if again := tt.resolver.Resolve(tt.conflict); again != got {
+				t.Errorf("second Resolve = %+v, want %+v", again, got)
+			}
+		})
+	}
+}
+
+func TestTiesBrokenBySigner(t *testing.T) {
+	// Same hostname and clock, different device keys
+	a, b := clipAt("laptop", 0), clipAt("laptop", 0)
+	a.Signer, b.Signer = "key-a", "key-b"
+
+	if !isNewer(b, a) || isNewer(a, b) {
+		t.Error("tie not broken by signer")
+	}
+
+	// The signer outranks the hostname
+	a.SourceMachine = "zeta"
+	if !isNewer(b, a) {
+		t.Error("hostname decided a tie between signed clips")
+	}
+
+	// Unsigned clips fall back to the hostname
+	a.Signer, b.Signer = "", ""
+	if !isNewer(a, b) {
+		t.Error("tie between unsigned clips not broken by hostname")
+	}
+}
+
+func TestConflictLocalAgeUsesClocks(t *testing.T) {
+	c := conflictAt("a", "b", 3*time.Second)
+
+	// The local clip's wall time doesn't matter, only its clock
+	c.Local.Timestamp = base.Add(-time.Hour)
+	if age := c.LocalAge(); age != 3*time.Second {
+		t.Errorf("LocalAge = %v, want 3s", age)
+	}
+}
+
+func TestNewConflictResolver(t *testing.T) {
+	tests := []struct {
+		strategy string
+		device   string
+		want     ConflictResolver
+		wantErr  bool
+	}{
+		{"", "", LastWriteWins{}, false},
+		{"last-write-wins", "", LastWriteWins{}, false},
+		{" Prefer-Local ", "", PreferLocal{Window: DefaultConflictWindow}, false},
+		{"prefer-device", "laptop", PreferDevice{Device: "laptop"}, false},
+		{"prefer-device", "", nil, true},
+		{"keep-both", "", KeepBoth{Window: DefaultConflictWindow}, false},
+		{"newest", "", nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := NewConflictResolver(tt.strategy, 0, tt.device)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NewConflictResolver(%q) error = %v, wantErr %v", tt.strategy, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("NewConflictResolver(%q) = %#v, want %#v", tt.strategy, got, tt.want)
+		}
+	}
+}
@@ -2,17 +2,26 @@ package sync
 
 import (
 	"context"
-	"log"
+	"errors"
+	"log/slog"
 	"os"
 	"sync"
 	"time"
 
 	"github.com/mindmorass/yippity-clippity/internal/backend"
 	"github.com/mindmorass/yippity-clippity/internal/clipboard"
+	"github.com/mindmorass/yippity-clippity/internal/clock"
+	"github.com/mindmorass/yippity-clippity/internal/hlc"
+	"github.com/mindmorass/yippity-clippity/internal/identity"
+	"github.com/mindmorass/yippity-clippity/internal/imaging"
+	"github.com/mindmorass/yippity-clippity/internal/logging"
+	"github.com/mindmorass/yippity-clippity/internal/metrics"
+	"github.com/mindmorass/yippity-clippity/internal/sensitive"
 )
 
-// StatusHandler is called when sync status changes
-type StatusHandler func(status Status)
+// ConfirmHandler asks the user whether a clip that matched the named
+// sensitive content rules should be uploaded
+type ConfirmHandler func(content *clipboard.Content, rules []string) bool
 
 // Status represents the current sync state
 type Status int
@@ -46,16 +55,44 @@ type Engine struct {
 	remoteWatcher    *Watcher
 
 	lastLocalContent  *clipboard.Content
+	localPublished    hlc.Timestamp
 	lastRemoteContent *clipboard.Content
 	lastWriteChecksum string
+	hlc               *hlc.Clock
 
-	status         Status
-	lastError      error
-	lastSyncTime   time.Time
-	onStatusChange StatusHandler
+	clipboard clipboard.Provider
+	clock     clock.Clock
+	device    string
+	manual    bool
+
+	identity *identity.Identity
+	trust    *identity.TrustStore
+
+	sensitive *sensitive.Pipeline
+	confirm   ConfirmHandler
+	apps      *AppFilter
+	ttl       time.Duration
+	published *publishedClip
+	policy    *Policy
+	pending   *clipboard.Content
+
+	images      *imaging.Options
+	rejectLossy bool
+
+	resolver ConflictResolver
+	history  []*clipboard.Content
+
+	status       Status
+	lastError    error
+	lastSyncTime time.Time
+
+	subscribers    []subscriber
+	nextSubscriber int
+	subMu          sync.Mutex
 
 	paused  bool
 	running bool
+	gcStop  chan struct{}
 	mu      sync.Mutex
 }
 
@@ -67,16 +104,54 @@ func NewEngine(basePath string) *Engine {
 
 // NewEngineWithBackend creates a new sync engine with a custom backend
 func NewEngineWithBackend(b backend.Backend) *Engine {
+	return NewEngineWithOptions(b, EngineOptions{})
+}
+
+// EngineOptions customizes an engine, e.g. to run several in one process.
+// Zero values use the system clipboard, the wall clock and the host name.
+type EngineOptions struct {
+	Clipboard  clipboard.Provider
+	Clock      clock.Clock
+	DeviceName string
+
+	// ManualPolling keeps Start from running background loops. The
+	// caller drives the engine with Poll instead.
+	ManualPolling bool
+}
+
+// NewEngineWithOptions creates a sync engine with a custom backend and
+// options
+func NewEngineWithOptions(b backend.Backend, opts EngineOptions) *Engine {
+	if opts.Clipboard == nil {
+		opts.Clipboard = clipboard.System
+	}
+	if opts.Clock == nil {
+		opts.Clock = clock.System
+	}
+	if opts.DeviceName == "" {
+		opts.DeviceName, _ = os.Hostname()
+	}
+
 	e := &Engine{
 		backend:          b,
-		clipboardMonitor: clipboard.NewMonitor(100 * time.Millisecond),
-		remoteWatcher:    NewWatcher(b, 500*time.Millisecond),
+		clipboardMonitor: clipboard.NewMonitorWithProvider(100*time.Millisecond, opts.Clipboard, opts.Clock),
+		remoteWatcher:    NewWatcherWithClock(b, 500*time.Millisecond, opts.Clock),
+		hlc:              hlc.NewClock(opts.Clock.Now),
+		clipboard:        opts.Clipboard,
+		clock:            opts.Clock,
+		device:           opts.DeviceName,
+		manual:           opts.ManualPolling,
+		resolver:         LastWriteWins{},
 		status:           StatusIdle,
 	}
 
 	// Set up callbacks
 	e.clipboardMonitor.OnChange(e.onLocalClipboardChange)
 	e.remoteWatcher.OnChange(e.onRemoteChange)
+	e.remoteWatcher.DeferPayload(e.defersPayload)
+	e.remoteWatcher.OnError(func(err error) {
+		e.emitError(nil, OpWatch, err)
+	})
 
 	return e
 }
@@ -110,23 +185,84 @@ func (e *Engine) SetSharedLocation(path string) error {
 
 	// Restart watcher with new location
 	if wasRunning && path != "" {
-		e.remoteWatcher.Start()
+		e.startWatcher()
 	}
 
-	log.Printf("Shared location set to: %s", path)
+	e.logger().Info("Shared location set", "location", path)
 	return nil
 }
 
-// GetSharedLocation returns the current sync location
-func (e *Engine) GetSharedLocation() string {
-	return e.backend.GetLocation()
+// Reconnect re-initializes the backend and restarts the remote watcher,
+// e.g. after the backend's credentials have changed
+func (e *Engine) Reconnect() error {
+	e.mu.Lock()
+	wasRunning := e.running
+	e.mu.Unlock()
+
+	if wasRunning {
+		e.remoteWatcher.Stop()
+	}
+
+	ctx := context.Background()
+	if err := e.backend.Init(ctx); err != nil {
+		return err
+	}
+
+	if wasRunning && e.backend.GetLocation() != "" {
+		e.startWatcher()
+	}
+
+	e.logger().Info("Reconnected to backend")
+	return nil
 }
 
-// OnStatusChange sets the status change handler
-func (e *Engine) OnStatusChange(handler StatusHandler) {
+// SetIdentity sets the device key local clips are signed with
+func (e *Engine) SetIdentity(id *identity.Identity) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
-	e.onStatusChange = handler
+	e.identity = id
+}
+
+// SetTrustStore requires remote clips to be signed by a trusted device.
+// Without a trust store, remote clips are applied unchecked.
+func (e *Engine) SetTrustStore(trust *identity.TrustStore) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.trust = trust
+}
+
+// SetSensitivePipeline sets the rules outgoing clips are checked against
+func (e *Engine) SetSensitivePipeline(p *sensitive.Pipeline) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.sensitive = p
+}
+
+// SetConfirmHandler sets the handler asked about clips matching a confirm
+// rule. Without one, such clips are not uploaded.
+func (e *Engine) SetConfirmHandler(handler ConfirmHandler) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.confirm = handler
+}
+
+// SetAppFilter sets which apps' clips may be synced
+func (e *Engine) SetAppFilter(f *AppFilter) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.apps = f
+}
+
+// GetAppFilter returns the app filter, or nil if every app is synced
+func (e *Engine) GetAppFilter() *AppFilter {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.apps
+}
+
+// GetSharedLocation returns the current sync location
+func (e *Engine) GetSharedLocation() string {
+	return e.backend.GetLocation()
 }
 
 // Start begins the sync engine
@@ -138,20 +274,56 @@ func (e *Engine) Start() error {
 	}
 	e.running = true
 	e.paused = false
+	e.gcStop = make(chan struct{})
+	gcStop := e.gcStop
 	e.mu.Unlock()
 
+	e.setStatus(StatusSyncing)
+	if e.manual {
+		return nil
+	}
+
 	// Start clipboard monitoring
 	e.clipboardMonitor.Start()
 
+	// Collect unreferenced blobs and expired clips in the background
+	go e.runGC(gcStop)
+	go e.runJanitor(gcStop)
+
 	// Start remote watcher if location is set
 	if e.backend.GetLocation() != "" {
 		e.remoteWatcher.Start()
 	}
-
-	e.setStatus(StatusSyncing)
 	return nil
 }
 
+// startWatcher restarts the remote watcher unless the engine is polled
+// manually
+func (e *Engine) startWatcher() {
+	if !e.manual {
+		e.remoteWatcher.Start()
+	}
+}
+
+// Poll checks the local clipboard and the shared location once and
+// removes this device's clip if it has expired. Engines created with
+// ManualPolling are driven this way.
+func (e *Engine) Poll() {
+	e.clipboardMonitor.Poll()
+	e.remoteWatcher.Poll()
+	e.removeExpired()
+}
+
+// DeviceName returns the name this engine's clips are published under
+func (e *Engine) DeviceName() string {
+	return e.device
+}
+
+// logger returns the default logger with this engine's device and backend
+func (e *Engine) logger() *slog.Logger {
+	return slog.With(logging.KeyDevice, e.device, logging.KeyBackend, string(e.backend.Type()))
+}
+
 // Stop stops the sync engine
 func (e *Engine) Stop() {
 	e.mu.Lock()
@@ -160,6 +332,7 @@ func (e *Engine) Stop() {
 		return
 	}
 	e.running = false
+	close(e.gcStop)
 	e.mu.Unlock()
 
 	e.clipboardMonitor.Stop()
@@ -221,12 +394,9 @@ func (e *Engine) GetLastError() error {
 func (e *Engine) setStatus(status Status) {
 	e.mu.Lock()
 	e.status = status
-	handler := e.onStatusChange
 	e.mu.Unlock()
 
-	if handler != nil {
-		handler(status)
-	}
+	e.emit(Event{Type: EventStatusChanged, Status: status})
 }
 
 func (e *Engine) onLocalClipboardChange(content *clipboard.Content) {
@@ -242,19 +412,77 @@ func (e *Engine) onLocalClipboardChange(content *clipboard.Content) {
 		return
 	}
 
+	// Order the clip after everything this device has seen
+	stamped := *content
+	stamped.Clock = e.hlc.Now()
+	content = &stamped
+
 	e.lastLocalContent = content
+	e.localPublished = hlc.Timestamp{}
+	id := e.identity
+	pipeline := e.sensitive
+	confirm := e.confirm
+	apps := e.apps
+	ttl := e.ttl
+	policy := e.policy
+	images := e.images
 	e.mu.Unlock()
 
+	logger := e.logger().With(logging.KeyClip, content.ID)
+	e.emit(Event{Type: EventLocalCopied, Clip: content})
+
+	// Never publish clips from excluded apps
+	if !apps.Allowed(content.SourceApp) {
+		logger.Info("Not syncing clipboard copied from excluded app", "app", content.SourceApp)
+		e.emitSkipped(content, SkipExcludedApp, content.SourceApp)
+		return
+	}
+
+	// Keep secrets out of the shared location
+	outgoing, rules, ok := screenSensitive(content, pipeline, confirm, logger)
+	if !ok {
+		e.emitSkipped(content, SkipSensitive, rules)
+		return
+	}
+
+	// Shrink and strip images before the size limits apply
+	outgoing, ok = prepareImage(outgoing, images, logger)
+	if !ok {
+		e.emitSkipped(content, SkipBadImage, "")
+		return
+	}
+
+	// Enforce the content type and size policy
+	outgoing, ok = e.applySendPolicy(outgoing, policy, logger)
+	if !ok {
+		return
+	}
+
+	// Let the shared copy expire
+	outgoing = withExpiry(outgoing, ttl)
+
 	// Write to shared location
-	hostname, _ := os.Hostname()
-	log.Printf("[%s] Local clipboard changed, writing to shared location", hostname)
+	logger.Info("Local clipboard changed, writing to shared location")
+
+	// Sign a copy so peers can tell the clip came from this device
+	if id != nil {
+		signed := *outgoing
+		if err := id.Sign(&signed); err != nil {
+			logger.Error("Failed to sign clipboard", logging.KeyError, err)
+			e.emitError(outgoing, OpSign, err)
+			return
+		}
+		outgoing = &signed
+	}
 
 	ctx := context.Background()
-	if err := e.backend.Write(ctx, content); err != nil {
-		log.Printf("Failed to write clipboard: %v", err)
+	err := e.backend.Write(ctx, outgoing)
+	if err != nil {
+		logger.Error("Failed to write clipboard", logging.KeyError, err)
 		e.mu.Lock()
 		e.lastError = err
 		e.mu.Unlock()
+		e.emitError(outgoing, OpPublish, err)
 		e.setStatus(StatusError)
 		return
 	}
@@ -263,9 +491,18 @@ func (e *Engine) onLocalClipboardChange(content *clipboard.Content) {
 	e.remoteWatcher.NotifyActivity()
 
 	e.mu.Lock()
-	e.lastSyncTime = time.Now()
+	e.lastSyncTime = e.clock.Now()
 	e.lastError = nil
+	if e.lastLocalContent == content {
+		e.localPublished = e.hlc.Now()
+	}
+	e.published = nil
+	if !outgoing.ExpiresAt.IsZero() {
+		e.published = &publishedClip{id: outgoing.ID, expiresAt: outgoing.ExpiresAt}
+	}
 	e.mu.Unlock()
+
+	e.emit(Event{Type: EventPublished, Clip: outgoing})
 }
 
 func (e *Engine) onRemoteChange(content *clipboard.Content) {
@@ -276,43 +513,136 @@ func (e *Engine) onRemoteChange(content *clipboard.Content) {
 	}
 
 	// Skip if content is from this machine
-	hostname, _ := os.Hostname()
+	hostname := e.device
 	if content.SourceMachine == hostname {
 		e.mu.Unlock()
 		return
 	}
+	trust := e.trust
+	e.mu.Unlock()
+
+	logger := e.logger().With(logging.KeyClip, content.ID, "from", content.SourceMachine)
+	e.emit(Event{Type: EventRemoteReceived, Clip: content})
+
+	// Expired clips must not be applied
+	if content.Expired(e.clock.Now()) {
+		e.emitSkipped(content, SkipExpired, "")
+		return
+	}
 
+	// Only apply clips signed by a trusted device
+	if trust != nil {
+		if _, err := trust.Verify(content); err != nil {
+			logger.Warn("Refusing remote clipboard that failed verification", logging.KeyError, err)
+			e.emitSkipped(content, SkipUntrusted, err.Error())
+			return
+		}
+	}
+
+	e.mu.Lock()
 	// Skip if we already have this content
 	if e.lastRemoteContent != nil && e.lastRemoteContent.ID == content.ID {
 		e.mu.Unlock()
 		return
 	}
 
-	// Last-write-wins: only apply if remote is newer
-	if e.lastLocalContent != nil && !content.Timestamp.After(e.lastLocalContent.Timestamp) {
+	// Clips written after this one must be ordered after it
+	e.hlc.Update(clockOf(content))
+
+	// A clip copied after the local clip was published replaces it
+	// without a conflict
+	if e.lastLocalContent != nil && !e.localPublished.IsZero() && clockOf(content).Compare(e.localPublished) > 0 {
+		e.lastLocalContent = nil
+	}
+
+	// Decide between the remote clip and the last local copy
+	if local := e.lastLocalContent; local != nil {
+		conflict := Conflict{Local: local, Remote: content, Now: clockTime(content)}
+		resolution := e.resolver.Resolve(conflict)
+
+		loser := resolution.Loser(conflict)
+		kept := resolution.KeepLoser && e.keepInHistory(loser)
+		if kept {
+			logger.Info("Conflict with the local clipboard, kept the losing clip in history", "kept_from", loser.SourceMachine, "kept_clip_id", loser.ID)
+		}
+
+		if !resolution.Remote {
+			strategy := e.resolver.Name()
+			e.mu.Unlock()
+			if kept {
+				e.emit(Event{Type: EventConflict, Clip: local, Loser: loser})
+			}
+			e.emitSkipped(content, SkipLostConflict, strategy)
+			return
+		}
+		if kept {
+			// Report the conflict once the lock is released
+			defer e.emit(Event{Type: EventConflict, Clip: content, Loser: loser})
+		}
+
+		// The local clip is superseded and takes no part in later
+		// conflicts
+		e.lastLocalContent = nil
+	}
+
+	e.lastRemoteContent = content
+
+	// Content the policy excludes is never applied
+	policy := e.policy
+	if policy.Excluded(content.ContentType) {
 		e.mu.Unlock()
+		logger.Info("Ignoring remote clipboard: content type is excluded", "type", content.ContentType)
+		e.emitSkipped(content, SkipExcludedType, string(content.ContentType))
 		return
 	}
 
-	e.lastRemoteContent = content
+	// Lossy copies of images are only applied if the user accepts them
+	if e.rejectLossy && isLossyCopy(content) {
+		e.mu.Unlock()
+		logger.Info("Ignoring remote image re-encoded in a lossy format", "original_mime_type", content.OriginalMimeType, "mime_type", content.MimeType)
+		e.emitSkipped(content, SkipLossyImage, content.MimeType)
+		return
+	}
+
+	// Large clips wait until the user asks for them
+	if content.Placeholder || policy.TooLarge(content) || payloadMissing(content) {
+		e.pending = content
+		e.mu.Unlock()
+		logger.Info("Large remote clipboard is available on demand", "type", content.ContentType, "size", content.Size)
+		e.emitSkipped(content, SkipDeferred, "")
+		return
+	}
+
+	e.pending = nil
 	e.lastWriteChecksum = content.Checksum
 	e.mu.Unlock()
 
-	log.Printf("[%s] Remote clipboard changed from %s, applying locally", hostname, content.SourceMachine)
+	logger.Info("Remote clipboard changed, applying locally")
 
-	// Apply to local clipboard
-	if !clipboard.Write(content) {
-		log.Printf("Failed to apply remote clipboard")
-		return
+	if err := e.applyRemote(content); err != nil {
+		logger.Error("Failed to apply remote clipboard", logging.KeyError, err)
+	}
+}
+
+// applyRemote writes a remote clip to the local clipboard
+func (e *Engine) applyRemote(content *clipboard.Content) error {
+	if !e.clipboard.Write(content) {
+		err := errors.New("failed to apply remote clipboard")
+		e.emitError(content, OpApply, err)
+		return err
 	}
 
 	// Update monitor's checksum to prevent echo
 	e.clipboardMonitor.SetLastChecksum(content.Checksum)
+	metrics.ObserveApplyLatency(e.clock.Now().Sub(content.Timestamp))
 
 	// Notify watcher of activity for adaptive polling
 	e.remoteWatcher.NotifyActivity()
 
 	e.mu.Lock()
-	e.lastSyncTime = time.Now()
+	e.lastSyncTime = e.clock.Now()
 	e.mu.Unlock()
+
+	e.emit(Event{Type: EventApplied, Clip: content})
+	return nil
 }
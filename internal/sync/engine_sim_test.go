@@ -0,0 +1,110 @@
+package sync_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mindmorass/yippity-clippity/internal/clipboard"
+	"github.com/mindmorass/yippity-clippity/internal/sim"
+	"github.com/mindmorass/yippity-clippity/internal/sync"
+)
+
+func TestPublishedLocalClipTakesNoPartInLaterConflicts(t *testing.T) {
+	s := sim.New("a", "b")
+	s.Device("a").Engine.SetConflictResolver(sync.PreferDevice{Device: "a"})
+
+	s.Device("a").Clipboard.Copy("mine")
+	if err := s.Settle(20); err != nil {
+		t.Fatalf("first clip: %v", err)
+	}
+
+	// b copies after a's clip was synced, so it isn't a conflict
+	s.Run(5)
+	s.Device("b").Clipboard.Copy("theirs")
+	if err := s.Settle(20); err != nil {
+		t.Fatalf("later clip: %v", err)
+	}
+	if got := s.Device("a").Clipboard.Text(); got != "theirs" {
+		t.Errorf("a has %q, want theirs", got)
+	}
+}
+
+func TestUnpublishedLocalClipContends(t *testing.T) {
+	s := sim.New("a", "b")
+	s.Device("a").Engine.SetConflictResolver(sync.PreferDevice{Device: "a"})
+	s.Backend.SetError(errOutage)
+
+	// Both copy while the shared location is down; a's clip never
+	// reaches it, so b's is a conflict a wins
+	s.Device("a").Clipboard.Copy("mine")
+	s.Run(2)
+	s.Backend.SetError(nil)
+	s.Device("b").Clipboard.Copy("theirs")
+	s.Run(20)
+
+	if got := s.Device("a").Clipboard.Text(); got != "mine" {
+		t.Errorf("a has %q, want mine", got)
+	}
+}
+
+func TestLargeClipPayloadWaitsForFetch(t *testing.T) {
+	s := sim.New("a", "b")
+	s.Device("b").Engine.SetPolicy(&sync.Policy{
+		MaxSize: map[clipboard.ContentType]int64{clipboard.ContentTypeText: 4},
+	})
+
+	s.Device("a").Clipboard.Copy("too large for b")
+	s.Run(10)
+
+	b := s.Device("b")
+	pending := b.Engine.PendingItem()
+	if pending == nil {
+		t.Fatal("no pending clip on b")
+	}
+	if pending.Data != nil {
+		t.Error("pending clip holds its payload before it was fetched")
+	}
+	if n := s.Backend.Reads(); n != 0 {
+		t.Errorf("payload read %d times before it was fetched", n)
+	}
+	if got := b.Clipboard.Text(); got != "" {
+		t.Errorf("b has %q before fetching", got)
+	}
+
+	if err := b.Engine.FetchPendingItem(); err != nil {
+		t.Fatalf("FetchPendingItem: %v", err)
+	}
+	if got := b.Clipboard.Text(); got != "too large for b" {
+		t.Errorf("b has %q after fetching", got)
+	}
+	if n := s.Backend.Reads(); n != 1 {
+		t.Errorf("payload read %d times, want 1", n)
+	}
+}
+
+func TestReplacedPendingClipIsNotFetched(t *testing.T) {
+	s := sim.New("a", "b")
+	b := s.Device("b")
+	b.Engine.SetPolicy(&sync.Policy{
+		MaxSize: map[clipboard.ContentType]int64{clipboard.ContentTypeText: 4},
+	})
+
+	s.Device("a").Clipboard.Copy("too large for b")
+	s.Run(10)
+	if b.Engine.PendingItem() == nil {
+		t.Fatal("no pending clip on b")
+	}
+
+	// Replace the clip without letting b poll
+	s.Device("a").Clipboard.Copy("another large clip")
+	s.Device("a").Engine.Poll()
+
+	if err := b.Engine.FetchPendingItem(); err == nil {
+		t.Error("fetched a pending clip that was replaced")
+	}
+	if got := b.Clipboard.Text(); got != "" {
+		t.Errorf("b has %q", got)
+	}
+}
+
+var errOutage = errors.New("shared location unavailable")
@@ -0,0 +1,173 @@
+package sync
+
+import (
+	"time"
+
+	"github.com/mindmorass/yippity-clippity/internal/clipboard"
+)
+
+// EventType identifies what happened in the engine
+type EventType int
+
+const (
+	// EventLocalCopied is a new clip on the local clipboard
+	EventLocalCopied EventType = iota
+
+	// EventPublished is a local clip written to the shared location
+	EventPublished
+
+	// EventRemoteReceived is a clip from another device found in the
+	// shared location
+	EventRemoteReceived
+
+	// EventApplied is a remote clip written to the local clipboard
+	EventApplied
+
+	// EventSkipped is a clip that wasn't published or applied. Reason
+	// says why.
+	EventSkipped
+
+	// EventConflict is a conflict whose losing clip was kept in the
+	// history
+	EventConflict
+
+	// EventError is a failed operation. Op says which.
+	EventError
+
+	// EventStatusChanged is a change of the engine status
+	EventStatusChanged
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventLocalCopied:
+		return "local_copied"
+	case EventPublished:
+		return "published"
+	case EventRemoteReceived:
+		return "remote_received"
+	case EventApplied:
+		return "applied"
+	case EventSkipped:
+		return "skipped"
+	case EventConflict:
+		return "conflict"
+	case EventError:
+		return "error"
+	case EventStatusChanged:
+		return "status_changed"
+	default:
+		return "unknown"
+	}
+}
+
+// SkipReason says why a clip was skipped
+type SkipReason string
+
+const (
+	// Outgoing clips
+	SkipExcludedApp SkipReason = "excluded_app"
+	SkipSensitive   SkipReason = "sensitive"
+	SkipTooLarge    SkipReason = "too_large"
+	SkipBadImage    SkipReason = "bad_image"
+
+	// Incoming clips
+	SkipExpired      SkipReason = "expired"
+	SkipUntrusted    SkipReason = "untrusted"
+	SkipLostConflict SkipReason = "lost_conflict"
+	SkipLossyImage   SkipReason = "lossy_image"
+	SkipDeferred     SkipReason = "deferred"
+
+	// Both directions
+	SkipExcludedType SkipReason = "excluded_type"
+)
+
+// Operations reported by error events
+const (
+	OpSign    = "sign"
+	OpPublish = "publish"
+	OpFetch   = "fetch"
+	OpApply   = "apply"
+	OpWatch   = "watch"
+)
+
+// Event is something that happened in the engine
+type Event struct {
+	Type EventType
+	Time time.Time
+
+	// Clip is the clip the event is about, if any. For conflicts it is
+	// the winning clip.
+	Clip *clipboard.Content
+
+	// Loser is the clip kept in the history after a conflict
+	Loser *clipboard.Content
+
+	// Reason and Detail say why a clip was skipped. Detail is e.g. the
+	// excluded app or the matched sensitive content rules.
+	Reason SkipReason
+	Detail string
+
+	// Op and Err describe a failed operation
+	Op  string
+	Err error
+
+	// Status is the new status of a status change
+	Status Status
+}
+
+// EventHandler receives engine events
+type EventHandler func(event Event)
+
+// subscriber is a registered event handler
+type subscriber struct {
+	id      int
+	handler EventHandler
+}
+
+// Subscribe calls handler with every event until the returned function
+// is called. Handlers run in the order they subscribed, on the goroutine
+// that caused the event and without the engine lock held. They must
+// return quickly.
+func (e *Engine) Subscribe(handler EventHandler) (unsubscribe func()) {
+	e.subMu.Lock()
+	defer e.subMu.Unlock()
+
+	e.nextSubscriber++
+	id := e.nextSubscriber
+	e.subscribers = append(e.subscribers, subscriber{id: id, handler: handler})
+
+	return func() {
+		e.subMu.Lock()
+		defer e.subMu.Unlock()
+		for i, s := range e.subscribers {
+			if s.id == id {
+				e.subscribers = append(e.subscribers[:i:i], e.subscribers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// emit sends an event to every subscriber. The caller must not hold e.mu.
+func (e *Engine) emit(event Event) {
+	event.Time = e.clock.Now()
+
+	e.subMu.Lock()
+	subscribers := e.subscribers
+	e.subMu.Unlock()
+
+	for _, s := range subscribers {
+		s.handler(event)
+	}
+}
+
+// emitSkipped reports a clip that wasn't published or applied
+func (e *Engine) emitSkipped(content *clipboard.Content, reason SkipReason, detail string) {
+	e.emit(Event{Type: EventSkipped, Clip: content, Reason: reason, Detail: detail})
+}
+
+// emitError reports a failed operation
+func (e *Engine) emitError(content *clipboard.Content, op string, err error) {
+	e.emit(Event{Type: EventError, Clip: content, Op: op, Err: err})
+}
@@ -0,0 +1,88 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/mindmorass/yippity-clippity/internal/backend"
+	"github.com/mindmorass/yippity-clippity/internal/clipboard"
+	"github.com/mindmorass/yippity-clippity/internal/logging"
+)
+
+// ExpiryCheckInterval is how often the janitor looks for an expired clip
+// this device published
+const ExpiryCheckInterval = 30 * time.Second
+
+// publishedClip is the last clip this device wrote that carries an expiry
+type publishedClip struct {
+	id        string
+	expiresAt time.Time
+}
+
+// SetClipTTL sets how long published clips stay valid. Zero disables
+// expiry.
+func (e *Engine) SetClipTTL(ttl time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.ttl = ttl
+}
+
+// withExpiry returns a copy of content that expires ttl after it was
+// copied
+func withExpiry(content *clipboard.Content, ttl time.Duration) *clipboard.Content {
+	if ttl <= 0 {
+		return content
+	}
+	expiring := *content
+	expiring.ExpiresAt = content.Timestamp.Add(ttl)
+	return &expiring
+}
+
+// runJanitor periodically removes the clip this device published once it
+// has expired
+func (e *Engine) runJanitor(stop <-chan struct{}) {
+	ticker := e.clock.NewTicker(ExpiryCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+			e.removeExpired()
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (e *Engine) removeExpired() {
+	e.mu.Lock()
+	published := e.published
+	e.mu.Unlock()
+
+	if published == nil || e.clock.Now().Before(published.expiresAt) {
+		return
+	}
+
+	remover, ok := e.backend.(backend.Remover)
+	if !ok || e.backend.GetLocation() == "" {
+		return
+	}
+
+	err := remover.RemoveClip(context.Background(), published.id)
+	switch {
+	case err == nil:
+		e.logger().Info("Removed expired clip from shared location", logging.KeyClip, published.id)
+	case errors.Is(err, backend.ErrConflict):
+		// Another clip has replaced ours, nothing left to remove
+	default:
+		e.logger().Warn("Failed to remove expired clip", logging.KeyClip, published.id, logging.KeyError, err)
+		return
+	}
+
+	e.mu.Lock()
+	if e.published == published {
+		e.published = nil
+	}
+	e.mu.Unlock()
+}
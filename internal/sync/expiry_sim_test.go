@@ -0,0 +1,81 @@
+package sync_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mindmorass/yippity-clippity/internal/sim"
+)
+
+// sharedClipID returns the ID of the clip in the shared location, or ""
+func sharedClipID(t *testing.T, s *sim.Sim) string {
+	t.Helper()
+	clip, err := s.Backend.Read(context.Background())
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if clip == nil {
+		return ""
+	}
+	return clip.ID
+}
+
+func TestJanitorRemovesOwnExpiredClip(t *testing.T) {
+	s := sim.New("a", "b")
+	s.Device("a").Engine.SetClipTTL(time.Second)
+
+	s.Device("a").Clipboard.Copy("short-lived")
+	s.Run(5)
+	if sharedClipID(t, s) == "" {
+		t.Fatal("clip wasn't published")
+	}
+
+	s.Run(10)
+	if id := sharedClipID(t, s); id != "" {
+		t.Errorf("expired clip %s is still in the shared location", id)
+	}
+}
+
+func TestJanitorLeavesNewerClip(t *testing.T) {
+	s := sim.New("a", "b")
+	s.Device("a").Engine.SetClipTTL(time.Second)
+
+	s.Device("a").Clipboard.Copy("short-lived")
+	s.Run(3)
+
+	// b replaces the clip before a's expires
+	s.Device("b").Clipboard.Copy("lasting")
+	s.Run(20)
+
+	if id := sharedClipID(t, s); !strings.HasPrefix(id, "b-") {
+		t.Errorf("shared location holds %q, want b's clip", id)
+	}
+	if got := s.Device("a").Clipboard.Text(); got != "lasting" {
+		t.Errorf("a has %q, want lasting", got)
+	}
+}
+
+func TestReaderIgnoresExpiredClip(t *testing.T) {
+	s := sim.New("a", "b")
+	a, b := s.Device("a"), s.Device("b")
+	a.Engine.SetClipTTL(time.Second)
+
+	a.Clipboard.Copy("short-lived")
+	a.Engine.Poll()
+	if sharedClipID(t, s) == "" {
+		t.Fatal("clip wasn't published")
+	}
+
+	// b only looks after the clip expired, before a removed it
+	s.Clock.Advance(2 * time.Second)
+	b.Engine.Poll()
+
+	if got := b.Clipboard.Text(); got != "" {
+		t.Errorf("b applied the expired clip %q", got)
+	}
+	if sharedClipID(t, s) == "" {
+		t.Error("b removed a clip it didn't publish")
+	}
+}
@@ -0,0 +1,43 @@
+package sync
+
+import (
+	"context"
+	"time"
+
+	"github.com/mindmorass/yippity-clippity/internal/backend"
+	"github.com/mindmorass/yippity-clippity/internal/logging"
+)
+
+// BlobGCInterval is how often unreferenced blobs are collected
+const BlobGCInterval = time.Hour
+
+// runGC periodically removes blobs the current clip no longer references
+func (e *Engine) runGC(stop <-chan struct{}) {
+	ticker := e.clock.NewTicker(BlobGCInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+			e.collectGarbage()
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (e *Engine) collectGarbage() {
+	collector, ok := e.backend.(backend.Collector)
+	if !ok || e.IsPaused() || e.backend.GetLocation() == "" {
+		return
+	}
+
+	removed, err := collector.CollectGarbage(context.Background())
+	if err != nil {
+		e.logger().Warn("Blob garbage collection failed", logging.KeyError, err)
+		return
+	}
+	if removed > 0 {
+		e.logger().Info("Removed unreferenced blobs", "count", removed)
+	}
+}
@@ -0,0 +1,73 @@
+package sync
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mindmorass/yippity-clippity/internal/clipboard"
+)
+
+// HistorySize is how many clips that lost a conflict are kept
+const HistorySize = 20
+
+// SetConflictResolver sets the strategy used when a remote clip arrives
+// after a local copy. Nil uses last-write-wins.
+func (e *Engine) SetConflictResolver(r ConflictResolver) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if r == nil {
+		r = LastWriteWins{}
+	}
+	e.resolver = r
+}
+
+// History returns the clips kept from conflicts, newest first
+func (e *Engine) History() []*clipboard.Content {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	history := make([]*clipboard.Content, len(e.history))
+	for i, content := range e.history {
+		history[len(e.history)-1-i] = content
+	}
+	return history
+}
+
+// RestoreClip copies a clip from the history to the local clipboard.
+// It is then synced like any other copy.
+func (e *Engine) RestoreClip(id string) error {
+	e.mu.Lock()
+	var content *clipboard.Content
+	for i, c := range e.history {
+		if c.ID == id {
+			content = c
+			e.history = append(e.history[:i], e.history[i+1:]...)
+			break
+		}
+	}
+	e.mu.Unlock()
+
+	if content == nil {
+		return fmt.Errorf("clip %s is not in the history", id)
+	}
+	if !e.clipboard.Write(content) {
+		return errors.New("failed to restore clip")
+	}
+	return nil
+}
+
+// keepInHistory adds a clip to the history, dropping the oldest once it
+// is full. The caller must hold e.mu.
+func (e *Engine) keepInHistory(content *clipboard.Content) bool {
+	for _, c := range e.history {
+		if c.ID == content.ID {
+			return false
+		}
+	}
+
+	e.history = append(e.history, content)
+	if len(e.history) > HistorySize {
+		e.history = e.history[len(e.history)-HistorySize:]
+	}
+	return true
+}
@@ -0,0 +1,58 @@
+package sync
+
+import (
+	"log/slog"
+
+	"github.com/mindmorass/yippity-clippity/internal/clipboard"
+	"github.com/mindmorass/yippity-clippity/internal/imaging"
+)
+
+// SetImageOptions sets how images are prepared before sending. Nil sends
+// images as they were copied.
+func (e *Engine) SetImageOptions(opts *imaging.Options) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.images = opts
+}
+
+// SetAcceptLossyImages sets whether images a peer re-encoded in a lossy
+// format are applied
+func (e *Engine) SetAcceptLossyImages(accept bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.rejectLossy = !accept
+}
+
+// prepareImage runs PNG images through the image pipeline. If that
+// fails, the image is sent as copied minus its metadata, and if even the
+// metadata can't be removed, it returns false and the clip isn't sent.
+func prepareImage(content *clipboard.Content, opts *imaging.Options, logger *slog.Logger) (*clipboard.Content, bool) {
+	if opts == nil || !content.IsImage() || content.MimeType != imaging.MimePNG {
+		return content, true
+	}
+
+	data, mimeType, err := imaging.Process(content.Data, *opts)
+	if err != nil {
+		stripped, stripErr := imaging.StripPNGMetadata(content.Data)
+		if stripErr != nil {
+			logger.Warn("Not syncing image: failed to remove its metadata", "error", stripErr)
+			return nil, false
+		}
+		logger.Warn("Failed to process image, sending it without metadata", "error", err)
+		return withPayload(content, stripped), true
+	}
+
+	out := withPayload(content, data)
+	if mimeType != content.MimeType {
+		logger.Info("Re-encoded image", "size", content.Size, "mime_type", mimeType, "encoded_size", len(data))
+		out.MimeType = mimeType
+		out.OriginalMimeType = content.MimeType
+	}
+	return out, true
+}
+
+// isLossyCopy returns true if content is an image a peer re-encoded in a
+// lossy format
+func isLossyCopy(content *clipboard.Content) bool {
+	return content.IsImage() && content.OriginalMimeType != "" && imaging.IsLossy(content.MimeType)
+}
@@ -0,0 +1,89 @@
+package sync
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/mindmorass/yippity-clippity/internal/clipboard"
+	"github.com/mindmorass/yippity-clippity/internal/imaging"
+)
+
+// pngChunk returns a PNG chunk with a valid CRC
+func pngChunk(name string, data []byte) []byte {
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	chunk = append(chunk, name...)
+	chunk = append(chunk, data...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+// pngWithLocation returns a PNG carrying a text chunk with a location.
+// If corrupt is set, the image data can't be decoded.
+func pngWithLocation(t *testing.T, corrupt bool) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, 40, 40))
+	img.Set(3, 3, color.NRGBA{R: 255, A: 255})
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	// Signature and IHDR, then the text chunk, then the rest
+	const ihdrEnd = 8 + 12 + 13
+	out := append([]byte{}, data[:ihdrEnd]...)
+	out = append(out, pngChunk("tEXt", []byte("Location\x0052.37,4.89"))...)
+	rest := append([]byte{}, data[ihdrEnd:]...)
+	if corrupt {
+		// Break the IDAT checksum
+		rest[8] ^= 0xff
+	}
+	return append(out, rest...)
+}
+
+func imageClip(data []byte) *clipboard.Content {
+	return withPayload(&clipboard.Content{
+		ID:          "img",
+		ContentType: clipboard.ContentTypeImage,
+		MimeType:    imaging.MimePNG,
+	}, data)
+}
+
+func TestPrepareImage(t *testing.T) {
+	opts := &imaging.Options{MaxDimension: 16, Format: imaging.FormatPNG}
+
+	tests := []struct {
+		name     string
+		data     []byte
+		wantSent bool
+	}{
+		{"valid", pngWithLocation(t, false), true},
+		{"undecodable", pngWithLocation(t, true), true},
+		{"not a PNG", []byte("GIF89a not really a png"), false},
+		{"truncated", pngWithLocation(t, false)[:40], false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, ok := prepareImage(imageClip(tt.data), opts, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			if ok != tt.wantSent {
+				t.Fatalf("prepareImage sent = %v, want %v", ok, tt.wantSent)
+			}
+			if !ok {
+				return
+			}
+			if bytes.Contains(out.Data, []byte("Location")) {
+				t.Error("image sent with its metadata")
+			}
+			if out.Size != int64(len(out.Data)) {
+				t.Errorf("size = %d, want %d", out.Size, len(out.Data))
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package sync
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/mindmorass/yippity-clippity/internal/backend"
+	"github.com/mindmorass/yippity-clippity/internal/clipboard"
+	"github.com/mindmorass/yippity-clippity/internal/imaging"
+)
+
+// errPendingReplaced means the pending clip was replaced before its
+// payload was fetched
+var errPendingReplaced = errors.New("pending clip was replaced before it was fetched")
+
+// LargeItemAction is what happens to a clip over its size limit
+type LargeItemAction string
+
+const (
+	// LargeItemSkip doesn't sync the clip
+	LargeItemSkip LargeItemAction = "skip"
+
+	// LargeItemDownscale shrinks images to fit the limit. Other content
+	// is skipped.
+	LargeItemDownscale LargeItemAction = "downscale"
+
+	// LargeItemPlaceholder publishes only the clip's metadata. Receivers
+	// fetch the payload on demand.
+	LargeItemPlaceholder LargeItemAction = "placeholder"
+)
+
+// ParseLargeItemAction parses a large item action from the config file
+func ParseLargeItemAction(s string) (LargeItemAction, error) {
+	switch a := LargeItemAction(strings.ToLower(strings.TrimSpace(s))); a {
+	case LargeItemSkip, LargeItemDownscale, LargeItemPlaceholder:
+		return a, nil
+	case "":
+		return LargeItemSkip, nil
+	default:
+		return "", fmt.Errorf("unknown large item action: %q", s)
+	}
+}
+
+// Policy limits which clips are synced, by content type and size.
+// It is enforced on both sending and receiving.
+type Policy struct {
+	// MaxSize is the largest payload synced per content type. Types
+	// without an entry are only limited by the clip format.
+	MaxSize map[clipboard.ContentType]int64
+
+	// Exclude lists content types that are never synced
+	Exclude map[clipboard.ContentType]bool
+
+	// LargeItem is what happens to clips over their size limit
+	LargeItem LargeItemAction
+}
+
+// Excluded returns true if clips of type t are never synced
+func (p *Policy) Excluded(t clipboard.ContentType) bool {
+	return p != nil && p.Exclude[t]
+}
+
+// TooLarge returns true if content is over the limit for its type
+func (p *Policy) TooLarge(content *clipboard.Content) bool {
+	if p == nil {
+		return false
+	}
+	limit, ok := p.MaxSize[content.ContentType]
+	return ok && limit > 0 && content.Size > limit
+}
+
+// defersPayload returns true if a remote clip won't be applied when it
+// arrives, so the watcher needn't download its payload. That is the case
+// for this device's own clips and those the policy excludes or defers.
+func (e *Engine) defersPayload(content *clipboard.Content) bool {
+	e.mu.Lock()
+	policy := e.policy
+	own := content.SourceMachine == e.device
+	e.mu.Unlock()
+	return own || policy.Excluded(content.ContentType) || policy.TooLarge(content)
+}
+
+// payloadMissing returns true if content was received without its payload
+func payloadMissing(content *clipboard.Content) bool {
+	return len(content.Data) == 0 && content.Size > 0
+}
+
+// SetPolicy sets the content type and size policy
+func (e *Engine) SetPolicy(p *Policy) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.policy = p
+}
+
+// applySendPolicy returns the clip to publish, or false if it must not be
+// synced. Oversized clips are skipped, downscaled or turned into a
+// placeholder depending on the policy.
+func (e *Engine) applySendPolicy(content *clipboard.Content, policy *Policy, logger *slog.Logger) (*clipboard.Content, bool) {
+	if policy.Excluded(content.ContentType) {
+		logger.Info("Not syncing clipboard: content type is excluded", "type", content.ContentType)
+		e.emitSkipped(content, SkipExcludedType, string(content.ContentType))
+		return nil, false
+	}
+	if !policy.TooLarge(content) {
+		return content, true
+	}
+
+	limit := policy.MaxSize[content.ContentType]
+
+	switch policy.LargeItem {
+	case LargeItemDownscale:
+		if content.IsImage() {
+			data, err := imaging.Downscale(content.Data, content.MimeType, limit)
+			if err == nil {
+				logger.Info("Downscaled image to fit size limit", "size", content.Size, "downscaled_size", len(data))
+				return withPayload(content, data), true
+			}
+			logger.Warn("Failed to downscale image", "error", err)
+		}
+
+	case LargeItemPlaceholder:
+		if fetcher, ok := e.backend.(backend.Fetcher); ok && fetcher.CanFetch() {
+			logger.Info("Publishing clip as a placeholder", "type", content.ContentType, "size", content.Size)
+			placeholder := *content
+			placeholder.Placeholder = true
+			return &placeholder, true
+		}
+		logger.Warn("Placeholders need clip format version 3 or later")
+	}
+
+	logger.Info("Not syncing clipboard: clip is over the size limit", "type", content.ContentType, "size", content.Size, "limit", limit)
+	e.emitSkipped(content, SkipTooLarge, fmt.Sprintf("over the %d byte limit", limit))
+	return nil, false
+}
+
+// withPayload returns a copy of content carrying data
+func withPayload(content *clipboard.Content, data []byte) *clipboard.Content {
+	out := *content
+	out.Data = data
+	out.Size = int64(len(data))
+	checksum := sha256.Sum256(data)
+	out.Checksum = hex.EncodeToString(checksum[:])
+	return &out
+}
+
+// PendingItem returns the large remote clip waiting to be fetched, if any
+func (e *Engine) PendingItem() *clipboard.Content {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.pending
+}
+
+// FetchPendingItem downloads the pending large clip, if it is still
+// current, and applies it to the local clipboard
+func (e *Engine) FetchPendingItem() error {
+	e.mu.Lock()
+	content := e.pending
+	trust := e.trust
+	e.mu.Unlock()
+
+	if content == nil {
+		return nil
+	}
+
+	if content.Placeholder && len(content.Data) == 0 {
+		fetcher, ok := e.backend.(backend.Fetcher)
+		if !ok {
+			return fmt.Errorf("%s backend can't fetch placeholders", e.backend.Type())
+		}
+
+		fetched, err := fetcher.Fetch(context.Background(), content)
+		if err != nil {
+			err = fmt.Errorf("fetch failed: %w", err)
+			e.emitError(content, OpFetch, err)
+			return err
+		}
+		if trust != nil {
+			// The clip was signed as the placeholder it was published as
+			published := *fetched
+			published.Placeholder = true
+			if _, err := trust.Verify(&published); err != nil {
+				e.emitSkipped(fetched, SkipUntrusted, err.Error())
+				return fmt.Errorf("fetched clip failed verification: %w", err)
+			}
+		}
+		content = fetched
+	} else if payloadMissing(content) {
+		// Only the header was read when the clip arrived
+		fetched, err := e.backend.Read(context.Background())
+		if err == nil && (fetched == nil || fetched.ID != content.ID) {
+			err = errPendingReplaced
+		}
+		if err != nil {
+			err = fmt.Errorf("fetch failed: %w", err)
+			e.emitError(content, OpFetch, err)
+			return err
+		}
+		if trust != nil {
+			if _, err := trust.Verify(fetched); err != nil {
+				e.emitSkipped(fetched, SkipUntrusted, err.Error())
+				return fmt.Errorf("fetched clip failed verification: %w", err)
+			}
+		}
+		content = fetched
+	}
+
+	e.mu.Lock()
+	if e.pending != nil && e.pending.ID == content.ID {
+		e.pending = nil
+	}
+	e.lastWriteChecksum = content.Checksum
+	e.mu.Unlock()
+
+	return e.applyRemote(content)
+}
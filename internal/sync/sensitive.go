@@ -0,0 +1,41 @@
+package sync
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/mindmorass/yippity-clippity/internal/clipboard"
+	"github.com/mindmorass/yippity-clippity/internal/sensitive"
+)
+
+// screenSensitive checks an outgoing clip against the sensitive content
+// rules. It returns the clip to upload, which is redacted if a redact rule
+// matched, or false and the matched rules if the clip must not be
+// uploaded.
+func screenSensitive(content *clipboard.Content, pipeline *sensitive.Pipeline, confirm ConfirmHandler, logger *slog.Logger) (*clipboard.Content, string, bool) {
+	result := pipeline.Check(content)
+	if len(result.Findings) == 0 {
+		return content, "", true
+	}
+
+	rules := strings.Join(result.Names(), ", ")
+
+	switch result.Action {
+	case sensitive.ActionBlock:
+		logger.Info("Not syncing clipboard: matched sensitive content rules", "rules", rules)
+		return nil, rules, false
+
+	case sensitive.ActionConfirm:
+		if confirm == nil || !confirm(content, result.Names()) {
+			logger.Info("Not syncing clipboard: upload of sensitive content not confirmed", "rules", rules)
+			return nil, rules, false
+		}
+		logger.Info("Syncing clipboard matching sensitive content rules after confirmation", "rules", rules)
+	}
+
+	if result.Redacted != nil {
+		logger.Info("Redacted sensitive content before syncing", "rules", rules)
+		return result.Redacted, "", true
+	}
+	return content, "", true
+}
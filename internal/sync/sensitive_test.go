@@ -0,0 +1,111 @@
+package sync
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/mindmorass/yippity-clippity/internal/clipboard"
+	"github.com/mindmorass/yippity-clippity/internal/sensitive"
+)
+
+func sensitiveClip(text string) *clipboard.Content {
+	checksum := sha256.Sum256([]byte(text))
+	return &clipboard.Content{
+		ID:          "clip",
+		ContentType: clipboard.ContentTypeText,
+		MimeType:    "text/plain",
+		Checksum:    hex.EncodeToString(checksum[:]),
+		Size:        int64(len(text)),
+		Data:        []byte(text),
+	}
+}
+
+func sensitivePipeline(t *testing.T, action sensitive.Action) *sensitive.Pipeline {
+	t.Helper()
+	secret, err := sensitive.NewRegexDetector("secret", `hunter2`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sensitive.NewPipeline(
+		sensitive.Rule{Detector: secret, Action: action},
+		sensitive.Rule{Detector: sensitive.Builtin(sensitive.RuleCreditCard), Action: sensitive.ActionRedact},
+	)
+}
+
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func TestScreenSensitiveBlocks(t *testing.T) {
+	confirmed := false
+	confirm := func(*clipboard.Content, []string) bool {
+		confirmed = true
+		return true
+	}
+
+	out, rules, ok := screenSensitive(sensitiveClip("password hunter2"), sensitivePipeline(t, sensitive.ActionBlock), confirm, discardLogger)
+	if ok || out != nil {
+		t.Errorf("blocked clip was let through: %v", out)
+	}
+	if rules != "secret" {
+		t.Errorf("rules = %q, want secret", rules)
+	}
+	if confirmed {
+		t.Error("user was asked to confirm a blocked clip")
+	}
+}
+
+func TestScreenSensitiveConfirm(t *testing.T) {
+	text := "password hunter2, card 4111 1111 1111 1111"
+
+	tests := []struct {
+		name    string
+		confirm ConfirmHandler
+		want    bool
+	}{
+		{"accepted", func(*clipboard.Content, []string) bool { return true }, true},
+		{"declined", func(*clipboard.Content, []string) bool { return false }, false},
+		{"no handler", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var asked []string
+			confirm := tt.confirm
+			if confirm != nil {
+				confirm = func(c *clipboard.Content, rules []string) bool {
+					asked = rules
+					return tt.confirm(c, rules)
+				}
+			}
+
+			out, rules, ok := screenSensitive(sensitiveClip(text), sensitivePipeline(t, sensitive.ActionConfirm), confirm, discardLogger)
+			if ok != tt.want {
+				t.Fatalf("ok = %v, want %v", ok, tt.want)
+			}
+			if tt.confirm != nil && len(asked) != 2 {
+				t.Errorf("confirmation asked about %v, want both rules", asked)
+			}
+			if !ok {
+				if out != nil || rules == "" {
+					t.Errorf("declined clip = %v, rules %q", out, rules)
+				}
+				return
+			}
+
+			// An accepted clip is still redacted
+			if want := "password hunter2, card [REDACTED credit_card]"; string(out.Data) != want {
+				t.Errorf("uploaded %q, want %q", out.Data, want)
+			}
+		})
+	}
+}
+
+func TestScreenSensitivePassesCleanClips(t *testing.T) {
+	clip := sensitiveClip("nothing to see")
+	out, rules, ok := screenSensitive(clip, sensitivePipeline(t, sensitive.ActionBlock), nil, discardLogger)
+	if !ok || out != clip || rules != "" {
+		t.Errorf("clean clip = %v, %q, %v", out, rules, ok)
+	}
+}
@@ -2,12 +2,15 @@ package sync
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"sync"
 	"time"
 
 	"github.com/mindmorass/yippity-clippity/internal/backend"
 	"github.com/mindmorass/yippity-clippity/internal/clipboard"
+	"github.com/mindmorass/yippity-clippity/internal/clock"
+	"github.com/mindmorass/yippity-clippity/internal/logging"
+	"github.com/mindmorass/yippity-clippity/internal/metrics"
 )
 
 // Adaptive polling constants
@@ -20,15 +23,27 @@ const (
 // RemoteChangeHandler is called when remote clipboard changes
 type RemoteChangeHandler func(*clipboard.Content)
 
+// PayloadFilter decides from a clip read without its payload whether the
+// payload should stay on the backend until it is asked for
+type PayloadFilter func(*clipboard.Content) bool
+
+// WatchErrorHandler is called when watching stops working and the
+// watcher falls back to another method
+type WatchErrorHandler func(error)
+
 // Watcher monitors the shared location for changes
-// Uses polling because fsnotify doesn't work on network filesystems
+// Uses fsnotify for local filesystems and falls back to polling on
+// network filesystems, where fsnotify doesn't see remote writes
 // Implements adaptive polling: faster during active use, slower when idle
 type Watcher struct {
 	backend      backend.Backend
+	clock        clock.Clock
 	interval     time.Duration
 	lastModTime  time.Time
 	lastChecksum string
 	onChange     RemoteChangeHandler
+	onError      WatchErrorHandler
+	deferPayload PayloadFilter
 	stopChan     chan struct{}
 	running      bool
 
@@ -41,8 +56,14 @@ type Watcher struct {
 
 // NewWatcher creates a new remote watcher
 func NewWatcher(b backend.Backend, interval time.Duration) *Watcher {
+	return NewWatcherWithClock(b, interval, clock.System)
+}
+
+// NewWatcherWithClock creates a remote watcher timed by clk
+func NewWatcherWithClock(b backend.Backend, interval time.Duration, clk clock.Clock) *Watcher {
 	return &Watcher{
 		backend:         b,
+		clock:           clk,
 		interval:        interval,
 		currentInterval: interval,
 		stopChan:        make(chan struct{}),
@@ -63,6 +84,33 @@ func (w *Watcher) OnChange(handler RemoteChangeHandler) {
 	w.onChange = handler
 }
 
+// OnError sets the handler for watch failures
+func (w *Watcher) OnError(handler WatchErrorHandler) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onError = handler
+}
+
+// DeferPayload sets the filter for clips whose payload isn't downloaded
+// when they arrive. The handler receives such clips without their data.
+// It only applies to backends that can read a clip's header alone.
+func (w *Watcher) DeferPayload(filter PayloadFilter) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.deferPayload = filter
+}
+
+// reportError passes a watch failure to the error handler
+func (w *Watcher) reportError(err error) {
+	w.mu.Lock()
+	handler := w.onError
+	w.mu.Unlock()
+
+	if handler != nil {
+		handler(err)
+	}
+}
+
 // Start begins watching for remote changes
 func (w *Watcher) Start() {
 	w.mu.Lock()
@@ -100,7 +148,7 @@ func (w *Watcher) IsRunning() bool {
 func (w *Watcher) NotifyActivity() {
 	w.mu.Lock()
 	defer w.mu.Unlock()
-	w.lastActivity = time.Now()
+	w.lastActivity = w.clock.Now()
 }
 
 // getAdaptiveInterval calculates the current polling interval based on activity
@@ -108,7 +156,7 @@ func (w *Watcher) getAdaptiveInterval() time.Duration {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
-	timeSinceActivity := time.Since(w.lastActivity)
+	timeSinceActivity := w.clock.Now().Sub(w.lastActivity)
 
 	if timeSinceActivity < ActivityWindow {
 		// Active: use fast polling
@@ -125,16 +173,27 @@ func (w *Watcher) getAdaptiveInterval() time.Duration {
 		}
 	}
 
+	metrics.SetPollInterval(w.currentInterval)
 	return w.currentInterval
 }
 
 func (w *Watcher) run() {
+	// Prefer filesystem notifications when the backend supports them,
+	// and poll if they aren't available or stop working
+	if w.runNotify() {
+		return
+	}
+	w.runPolling()
+}
+
+func (w *Watcher) runPolling() {
 	// Start with the configured interval
 	w.mu.Lock()
 	w.currentInterval = w.interval
 	w.mu.Unlock()
+	metrics.SetPollInterval(w.interval)
 
-	ticker := time.NewTicker(w.interval)
+	ticker := w.clock.NewTicker(w.interval)
 	defer ticker.Stop()
 
 	// Initial check
@@ -142,7 +201,7 @@ func (w *Watcher) run() {
 
 	for {
 		select {
-		case <-ticker.C:
+		case <-ticker.C():
 			w.checkForChanges()
 
 			// Adjust ticker interval based on activity
@@ -154,10 +213,17 @@ func (w *Watcher) run() {
 	}
 }
 
+// Poll checks the shared location once, calling the change handler if
+// it changed. Watchers that aren't started can be driven this way.
+func (w *Watcher) Poll() {
+	w.checkForChanges()
+}
+
 func (w *Watcher) checkForChanges() {
 	w.mu.Lock()
 	b := w.backend
 	handler := w.onChange
+	filter := w.deferPayload
 	w.mu.Unlock()
 
 	if b == nil || b.GetLocation() == "" {
@@ -165,6 +231,7 @@ func (w *Watcher) checkForChanges() {
 	}
 
 	ctx := context.Background()
+	metrics.ObservePoll()
 
 	// Check if file exists and has been modified
 	modTime, err := b.GetModTime(ctx)
@@ -180,10 +247,11 @@ func (w *Watcher) checkForChanges() {
 	w.lastModTime = modTime
 	w.mu.Unlock()
 
-	// Read the content
-	content, err := b.Read(ctx)
+	// Read the header first if the payload may not be wanted, and the
+	// whole clip otherwise
+	content, err := w.read(ctx, b, filter)
 	if err != nil {
-		log.Printf("Failed to read remote clipboard: %v", err)
+		slog.Warn("Failed to read remote clipboard", logging.KeyBackend, string(b.Type()), logging.KeyError, err)
 		return
 	}
 	if content == nil {
@@ -199,12 +267,39 @@ func (w *Watcher) checkForChanges() {
 	w.lastChecksum = content.Checksum
 	w.mu.Unlock()
 
+	// Ignore clips that have outlived their TTL
+	if content.Expired(w.clock.Now()) {
+		return
+	}
+
 	// Notify handler
 	if handler != nil {
 		handler(content)
 	}
 }
 
+// read returns the current clip, leaving its payload on the backend if
+// filter defers it
+func (w *Watcher) read(ctx context.Context, b backend.Backend, filter PayloadFilter) (*clipboard.Content, error) {
+	reader, ok := b.(backend.HeaderReader)
+	if !ok || filter == nil {
+		return b.Read(ctx)
+	}
+
+	header, err := reader.ReadHeader(ctx)
+	if header == nil || err != nil {
+		return nil, err
+	}
+
+	w.mu.Lock()
+	unchanged := header.Checksum == w.lastChecksum
+	w.mu.Unlock()
+	if unchanged || header.Placeholder || filter(header) {
+		return header, nil
+	}
+	return b.Read(ctx)
+}
+
 // SetLastChecksum sets the last known checksum (used to prevent initial echo)
 func (w *Watcher) SetLastChecksum(checksum string) {
 	w.mu.Lock()
@@ -0,0 +1,101 @@
+package sync
+
+import (
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/mindmorass/yippity-clippity/internal/backend"
+	"github.com/mindmorass/yippity-clippity/internal/logging"
+)
+
+// SafetyPollInterval is how often the clipboard file is polled while
+// fsnotify is active, in case an event is missed
+const SafetyPollInterval = 5 * time.Second
+
+// errNotifyClosed is reported when fsnotify stops delivering events
+var errNotifyClosed = errors.New("fsnotify watcher closed, falling back to polling")
+
+// runNotify watches the sync directory with fsnotify until stopped.
+// It returns false if the backend isn't on a local filesystem or
+// notifications can't be set up or stop working, so the caller can poll.
+func (w *Watcher) runNotify() bool {
+	w.mu.Lock()
+	b := w.backend
+	w.mu.Unlock()
+
+	watchable, ok := b.(backend.Watchable)
+	if !ok {
+		return false
+	}
+	dir, reliable := watchable.WatchDir()
+	logger := slog.With(logging.KeyBackend, string(b.Type()), "dir", dir)
+	if !reliable {
+		logger.Info("Polling network filesystem")
+		return false
+	}
+
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		logger.Warn("fsnotify unavailable, falling back to polling", logging.KeyError, err)
+		return false
+	}
+	defer fsw.Close()
+
+	if err := fsw.Add(dir); err != nil {
+		logger.Warn("Failed to watch directory, falling back to polling", logging.KeyError, err)
+		return false
+	}
+	logger.Info("Watching directory with fsnotify")
+
+	w.mu.Lock()
+	w.currentInterval = SafetyPollInterval
+	w.mu.Unlock()
+
+	ticker := w.clock.NewTicker(SafetyPollInterval)
+	defer ticker.Stop()
+
+	// Initial check
+	w.checkForChanges()
+
+	for {
+		select {
+		case event, ok := <-fsw.Events:
+			if !ok {
+				return w.notifyClosed(logger)
+			}
+			if filepath.Base(event.Name) != backend.CurrentFile {
+				continue
+			}
+			if event.Has(fsnotify.Create) || event.Has(fsnotify.Write) || event.Has(fsnotify.Rename) {
+				w.checkForChanges()
+			}
+		case err, ok := <-fsw.Errors:
+			if !ok {
+				return w.notifyClosed(logger)
+			}
+			logger.Warn("fsnotify error", logging.KeyError, err)
+		case <-ticker.C():
+			w.checkForChanges()
+		case <-w.stopChan:
+			return true
+		}
+	}
+}
+
+// notifyClosed handles fsnotify closing its channels unexpectedly. It
+// reports the failure and returns false so the caller polls instead,
+// unless the watcher is being stopped anyway.
+func (w *Watcher) notifyClosed(logger *slog.Logger) bool {
+	select {
+	case <-w.stopChan:
+		return true
+	default:
+	}
+
+	logger.Warn("fsnotify stopped, falling back to polling")
+	w.reportError(errNotifyClosed)
+	return false
+}
@@ -0,0 +1,41 @@
+package sync
+
+import (
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestNotifyClosedFallsBackToPolling(t *testing.T) {
+	w := NewWatcher(nil, time.Second)
+
+	var reported []error
+	w.OnError(func(err error) {
+		reported = append(reported, err)
+	})
+
+	if w.notifyClosed(slog.Default()) {
+		t.Error("notifyClosed returned true while running, want a fallback to polling")
+	}
+	if len(reported) != 1 || !errors.Is(reported[0], errNotifyClosed) {
+		t.Errorf("reported errors = %v, want errNotifyClosed", reported)
+	}
+}
+
+func TestNotifyClosedWhileStopping(t *testing.T) {
+	w := NewWatcher(nil, time.Second)
+
+	var reported []error
+	w.OnError(func(err error) {
+		reported = append(reported, err)
+	})
+	close(w.stopChan)
+
+	if !w.notifyClosed(slog.Default()) {
+		t.Error("notifyClosed returned false while stopping")
+	}
+	if len(reported) != 0 {
+		t.Errorf("reported errors = %v while stopping, want none", reported)
+	}
+}
@@ -0,0 +1,64 @@
+//go:build darwin
+
+package ui
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework Cocoa
+
+#import <Cocoa/Cocoa.h>
+
+int showConfirmAlert(const char* title, const char* message, const char* confirm, const char* cancel) {
+    __block int result = 0;
+
+    NSString* titleStr = [NSString stringWithUTF8String:title];
+    NSString* messageStr = [NSString stringWithUTF8String:message];
+    NSString* confirmStr = [NSString stringWithUTF8String:confirm];
+    NSString* cancelStr = [NSString stringWithUTF8String:cancel];
+
+    // Use dispatch_async with a semaphore to avoid deadlock with systray
+    dispatch_semaphore_t sem = dispatch_semaphore_create(0);
+
+    dispatch_async(dispatch_get_main_queue(), ^{
+        @autoreleasepool {
+            [NSApp activateIgnoringOtherApps:YES];
+
+            NSAlert* alert = [[NSAlert alloc] init];
+            [alert setMessageText:titleStr];
+            [alert setInformativeText:messageStr];
+            [alert setAlertStyle:NSAlertStyleWarning];
+            // The first button is the default; make it the safe choice
+            [alert addButtonWithTitle:cancelStr];
+            [alert addButtonWithTitle:confirmStr];
+            [[alert window] setLevel:NSFloatingWindowLevel];
+
+            if ([alert runModal] == NSAlertSecondButtonReturn) {
+                result = 1;
+            }
+        }
+        dispatch_semaphore_signal(sem);
+    });
+
+    dispatch_semaphore_wait(sem, DISPATCH_TIME_FOREVER);
+
+    return result;
+}
+*/
+import "C"
+
+import "unsafe"
+
+// ShowConfirmDialog displays a native alert with confirm and cancel buttons.
+// Returns true if the user chose confirm.
+func ShowConfirmDialog(title, message, confirm, cancel string) bool {
+	cTitle := C.CString(title)
+	defer C.free(unsafe.Pointer(cTitle))
+	cMessage := C.CString(message)
+	defer C.free(unsafe.Pointer(cMessage))
+	cConfirm := C.CString(confirm)
+	defer C.free(unsafe.Pointer(cConfirm))
+	cCancel := C.CString(cancel)
+	defer C.free(unsafe.Pointer(cCancel))
+
+	return C.showConfirmAlert(cTitle, cMessage, cConfirm, cCancel) == 1
+}
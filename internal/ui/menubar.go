@@ -6,12 +6,16 @@ import (
 	"image"
 	"image/color"
 	"image/png"
-	"log"
+	"log/slog"
 	"os/exec"
 	"runtime"
+	"sync/atomic"
 	"time"
 
 	"fyne.io/systray"
+	"github.com/mindmorass/yippity-clippity/internal/clipboard"
+	"github.com/mindmorass/yippity-clippity/internal/logging"
+	"github.com/mindmorass/yippity-clippity/internal/notify"
 	"github.com/mindmorass/yippity-clippity/internal/sync"
 	"github.com/mindmorass/yippity-clippity/internal/update"
 )
@@ -23,8 +27,14 @@ type App interface {
 	GetSharedLocation() string
 	GetBackendType() string
 	SetBackendType(backendType string) error
+	ConnectDropbox() error
+	PairDevice(onCode func(code, uri string)) (string, error)
+	SetAppExcluded(bundleID string, excluded bool) error
+	IsAppExcluded(bundleID string) bool
 	GetVersion() string
 	GetUpdateChecker() *update.Checker
+	GetLogDir() string
+	GetNotifier() *notify.Service
 	Quit()
 }
 
@@ -41,6 +51,15 @@ type Menubar struct {
 	mBackendLocal  *systray.MenuItem
 	mBackendS3     *systray.MenuItem
 	mBackendDropbox *systray.MenuItem
+	mConnectDropbox *systray.MenuItem
+	mPair          *systray.MenuItem
+	mPairCode      *systray.MenuItem
+	mPairQR        *systray.MenuItem
+	pairURI        string
+	mExcludeApp    *systray.MenuItem
+	mFetchPending  *systray.MenuItem
+	mRestoreClip   *systray.MenuItem
+	frontApp       atomic.Pointer[frontApp]
 	mUpdate        *systray.MenuItem
 	mCheckUpdate   *systray.MenuItem
 	mVersion       *systray.MenuItem
@@ -134,6 +153,14 @@ func (m *Menubar) onReady() {
 	m.mLastSync = systray.AddMenuItem("Last sync: Never", "")
 	m.mLastSync.Disable()
 
+	// Shown while a large remote clip waits to be fetched
+	m.mFetchPending = systray.AddMenuItem("Fetch Large Clip", "Download the large clip copied on another device")
+	m.mFetchPending.Hide()
+
+	// Shown while a clip that lost a conflict is kept in the history
+	m.mRestoreClip = systray.AddMenuItem("Restore Clip", "Copy the clip that lost a conflict")
+	m.mRestoreClip.Hide()
+
 	systray.AddSeparator()
 
 	// Location submenu
@@ -148,14 +175,25 @@ func (m *Menubar) onReady() {
 	m.mBackendLocal = m.mBackend.AddSubMenuItem("Local (File System)", "Use local folder for sync")
 	m.mBackendS3 = m.mBackend.AddSubMenuItem("Amazon S3", "Use S3 bucket for sync")
 	m.mBackendDropbox = m.mBackend.AddSubMenuItem("Dropbox", "Use Dropbox API for sync")
+	m.mConnectDropbox = m.mBackend.AddSubMenuItem("Connect Dropbox...", "Sign in to Dropbox in your browser")
 	m.updateBackendSelection()
 
+	// Device pairing; the code and QR items are shown while a session runs
+	m.mPair = systray.AddMenuItem("Pair New Device...", "Show a one-time code to enter on another device")
+	m.mPairCode = systray.AddMenuItem("", "")
+	m.mPairCode.Disable()
+	m.mPairCode.Hide()
+	m.mPairQR = systray.AddMenuItem("Show QR Code", "")
+	m.mPairQR.Hide()
+
 	systray.AddSeparator()
 
 	// Sync controls
 	m.mPause = systray.AddMenuItem("Pause Sync", "")
 	m.mResume = systray.AddMenuItem("Resume Sync", "")
 	m.mResume.Hide()
+	m.mExcludeApp = systray.AddMenuItem("Never Sync from Current App", "Never sync clips copied from this app")
+	m.mExcludeApp.Hide()
 
 	systray.AddSeparator()
 
@@ -168,7 +206,8 @@ func (m *Menubar) onReady() {
 
 	systray.AddSeparator()
 
-	// About and Quit
+	// Logs, About and Quit
+	mOpenLogs := systray.AddMenuItem("Open Logs", "Show the log files")
 	mAbout := systray.AddMenuItem("About Yippity-Clippity", "")
 	mQuit := systray.AddMenuItem("Quit", "")
 
@@ -176,14 +215,19 @@ func (m *Menubar) onReady() {
 	m.updateLocation()
 	m.updateStatus(sync.StatusSyncing)
 
-	// Set up status change handler
-	m.app.GetSyncEngine().OnStatusChange(func(status sync.Status) {
-		m.updateStatus(status)
+	// Follow status changes
+	m.app.GetSyncEngine().Subscribe(func(event sync.Event) {
+		if event.Type == sync.EventStatusChanged {
+			m.updateStatus(event.Status)
+		}
 	})
 
 	// Start last sync time updater
 	go m.updateLastSyncLoop()
 
+	// Keep the exclusion item pointing at the app in front
+	go m.updateFrontAppLoop()
+
 	// Check for updates on startup and periodically
 	go m.checkForUpdates()
 	go m.updateCheckLoop()
@@ -196,7 +240,8 @@ func (m *Menubar) onReady() {
 				path := ShowFolderPicker()
 				if path != "" {
 					if err := m.app.SetSharedLocation(path); err != nil {
-						// TODO: Show error notification
+						slog.Error("Failed to set shared location", "location", path, "error", err)
+						m.app.GetNotifier().Error("Couldn't use that folder", err)
 						continue
 					}
 					m.updateLocation()
@@ -227,15 +272,68 @@ func (m *Menubar) onReady() {
 					m.updateBackendSelection()
 				}
 
+			case <-m.mConnectDropbox.ClickedCh:
+				// Login waits for the browser redirect, so don't block the menu
+				go func() {
+					if err := m.app.ConnectDropbox(); err != nil {
+						slog.Error("Dropbox login failed", "error", err)
+						return
+					}
+					m.updateLocation()
+				}()
+
+			case <-m.mPair.ClickedCh:
+				// Pairing waits for the other device, so don't block the menu
+				m.mPair.Disable()
+				go m.pairDevice()
+
+			case <-m.mPairQR.ClickedCh:
+				if err := ShowQRCode(m.pairURI); err != nil {
+					slog.Warn("Failed to show QR code", "error", err)
+				}
+
+			case <-m.mFetchPending.ClickedCh:
+				// Large downloads shouldn't block the menu
+				m.mFetchPending.Disable()
+				go func() {
+					defer m.mFetchPending.Enable()
+					if err := m.app.GetSyncEngine().FetchPendingItem(); err != nil {
+						slog.Error("Failed to fetch large clip", "error", err)
+						return
+					}
+					m.updatePendingItem()
+				}()
+
+			case <-m.mRestoreClip.ClickedCh:
+				engine := m.app.GetSyncEngine()
+				if history := engine.History(); len(history) > 0 {
+					if err := engine.RestoreClip(history[0].ID); err != nil {
+						slog.Error("Failed to restore clip", logging.KeyClip, history[0].ID, logging.KeyError, err)
+					}
+				}
+				m.updateHistoryItem()
+
+			case <-m.mExcludeApp.ClickedCh:
+				if app := m.frontApp.Load(); app != nil {
+					excluded := m.app.IsAppExcluded(app.bundleID)
+					if err := m.app.SetAppExcluded(app.bundleID, !excluded); err != nil {
+						continue
+					}
+					m.updateExcludeApp(app)
+				}
+
 			case <-m.mCheckUpdate.ClickedCh:
 				m.checkForUpdates()
 
 			case <-m.mUpdate.ClickedCh:
 				// Open release page in browser
 				if m.updateInfo != nil && m.updateInfo.ReleaseURL != "" {
-					openBrowser(m.updateInfo.ReleaseURL)
+					OpenBrowser(m.updateInfo.ReleaseURL)
 				}
 
+			case <-mOpenLogs.ClickedCh:
+				OpenPath(m.app.GetLogDir())
+
 			case <-mAbout.ClickedCh:
 				// TODO: Show about dialog
 				continue
@@ -300,6 +398,76 @@ func (m *Menubar) updateBackendSelection() {
 	}
 }
 
+// pairDevice runs a pairing session, showing its code in the menu
+func (m *Menubar) pairDevice() {
+	defer func() {
+		m.mPairCode.Hide()
+		m.mPairQR.Hide()
+		m.mPair.Enable()
+		RemoveQRCode()
+	}()
+
+	name, err := m.app.PairDevice(func(code, uri string) {
+		m.pairURI = uri
+		m.mPairCode.SetTitle("Pairing code: " + code)
+		m.mPairCode.Show()
+		m.mPairQR.Show()
+	})
+	if err != nil {
+		slog.Error("Pairing failed", "error", err)
+		return
+	}
+
+	slog.Info("Now trusting clips from paired device", "peer", name)
+}
+
+// frontApp is the app the exclusion menu item applies to
+type frontApp struct {
+	bundleID string
+	name     string
+}
+
+// updateFrontAppLoop tracks the frontmost app so the menu can offer to
+// exclude it. Status bar menus don't take focus, so the app in front when
+// the menu opens is the one the user was working in.
+func (m *Menubar) updateFrontAppLoop() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			bundleID, name := clipboard.FrontmostApp()
+			if current := m.frontApp.Load(); current != nil && current.bundleID == bundleID {
+				continue
+			}
+			if bundleID == "" {
+				m.frontApp.Store(nil)
+				m.mExcludeApp.Hide()
+				continue
+			}
+			if name == "" {
+				name = bundleID
+			}
+			app := &frontApp{bundleID: bundleID, name: name}
+			m.frontApp.Store(app)
+			m.updateExcludeApp(app)
+			m.mExcludeApp.Show()
+		case <-m.quitChan:
+			return
+		}
+	}
+}
+
+// updateExcludeApp titles the exclusion item for app
+func (m *Menubar) updateExcludeApp(app *frontApp) {
+	if m.app.IsAppExcluded(app.bundleID) {
+		m.mExcludeApp.SetTitle("✓ Never Sync from " + app.name)
+	} else {
+		m.mExcludeApp.SetTitle("Never Sync from " + app.name)
+	}
+}
+
 func (m *Menubar) updateLastSyncLoop() {
 	ticker := time.NewTicker(5 * time.Second)
 	defer ticker.Stop()
@@ -314,12 +482,48 @@ func (m *Menubar) updateLastSyncLoop() {
 				ago := time.Since(lastSync)
 				m.mLastSync.SetTitle(fmt.Sprintf("Last sync: %s ago", formatDuration(ago)))
 			}
+			m.updatePendingItem()
+			m.updateHistoryItem()
 		case <-m.quitChan:
 			return
 		}
 	}
 }
 
+// updatePendingItem shows the fetch item while a large clip is waiting
+func (m *Menubar) updatePendingItem() {
+	pending := m.app.GetSyncEngine().PendingItem()
+	if pending == nil {
+		m.mFetchPending.Hide()
+		return
+	}
+	m.mFetchPending.SetTitle(fmt.Sprintf("Fetch %s from %s (%s)", pending.ContentType, pending.SourceMachine, formatSize(pending.Size)))
+	m.mFetchPending.Show()
+}
+
+// updateHistoryItem offers the newest clip kept from a conflict
+func (m *Menubar) updateHistoryItem() {
+	history := m.app.GetSyncEngine().History()
+	if len(history) == 0 {
+		m.mRestoreClip.Hide()
+		return
+	}
+	m.mRestoreClip.SetTitle(fmt.Sprintf("Restore %s from %s", history[0].ContentType, history[0].SourceMachine))
+	m.mRestoreClip.Show()
+}
+
+// formatSize formats a byte count for display
+func formatSize(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.0f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d bytes", n)
+	}
+}
+
 func formatDuration(d time.Duration) string {
 	if d < time.Minute {
 		return fmt.Sprintf("%d seconds", int(d.Seconds()))
@@ -338,7 +542,7 @@ func (m *Menubar) checkForUpdates() {
 
 	info, err := checker.Check()
 	if err != nil {
-		log.Printf("Update check failed: %v", err)
+		slog.Warn("Update check failed", "error", err)
 		m.mUpdate.SetTitle("Update check failed")
 		m.mUpdate.Show()
 		// Hide the error after a few seconds
@@ -356,7 +560,8 @@ func (m *Menubar) checkForUpdates() {
 	if info.Available {
 		m.mUpdate.SetTitle(fmt.Sprintf("Update Available: %s", info.LatestVersion))
 		m.mUpdate.Show()
-		log.Printf("Update available: %s -> %s", info.CurrentVersion, info.LatestVersion)
+		slog.Info("Update available", "current", info.CurrentVersion, "latest", info.LatestVersion)
+		m.app.GetNotifier().UpdateAvailable(info.LatestVersion)
 	} else {
 		// Show "up to date" briefly so user knows the check completed
 		m.mUpdate.SetTitle("Up to date ✓")
@@ -388,20 +593,33 @@ func (m *Menubar) updateCheckLoop() {
 	}
 }
 
-func openBrowser(url string) {
+// OpenBrowser opens a URL in the default browser
+func OpenBrowser(url string) {
+	if err := openDefault(url); err != nil {
+		slog.Warn("Failed to open browser", "error", err)
+	}
+}
+
+// OpenPath opens a file or folder in the default app, e.g. Finder for
+// folders
+func OpenPath(path string) {
+	if err := openDefault(path); err != nil {
+		slog.Warn("Failed to open path", "path", path, "error", err)
+	}
+}
+
+// openDefault hands target to the platform's default opener
+func openDefault(target string) error {
 	var cmd *exec.Cmd
 	switch runtime.GOOS {
 	case "darwin":
-		cmd = exec.Command("open", url)
+		cmd = exec.Command("open", target)
 	case "linux":
-		cmd = exec.Command("xdg-open", url)
+		cmd = exec.Command("xdg-open", target)
 	case "windows":
-		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", target)
 	default:
-		log.Printf("Unsupported platform for opening browser")
-		return
-	}
-	if err := cmd.Start(); err != nil {
-		log.Printf("Failed to open browser: %v", err)
+		return fmt.Errorf("unsupported platform %s", runtime.GOOS)
 	}
+	return cmd.Start()
 }
@@ -0,0 +1,30 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// qrCodeFile is where the pairing QR code image is written
+var qrCodeFile = filepath.Join(os.TempDir(), "yippity-clippity-pairing.png")
+
+// ShowQRCode renders text as a QR code and opens it in the default
+// image viewer
+func ShowQRCode(text string) error {
+	image, err := qrcode.Encode(text, qrcode.Medium, 512)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(qrCodeFile, image, 0600); err != nil {
+		return err
+	}
+	OpenBrowser(qrCodeFile)
+	return nil
+}
+
+// RemoveQRCode deletes the QR code image once it is no longer valid
+func RemoveQRCode() {
+	os.Remove(qrCodeFile)
+}